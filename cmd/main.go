@@ -1,46 +1,231 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/joho/godotenv"
 	"github.com/kefir4iick/crud/internal/api"
+	"github.com/kefir4iick/crud/internal/auth"
+	"github.com/kefir4iick/crud/internal/blob"
+	"github.com/kefir4iick/crud/internal/domain"
 	"github.com/kefir4iick/crud/internal/handler"
+	"github.com/kefir4iick/crud/internal/idempotency"
+	"github.com/kefir4iick/crud/internal/logging"
+	"github.com/kefir4iick/crud/internal/metrics"
+	"github.com/kefir4iick/crud/internal/ratelimit"
+	"github.com/kefir4iick/crud/internal/repository"
+	"github.com/kefir4iick/crud/internal/repository/cache"
 	"github.com/kefir4iick/crud/internal/repository/postgres"
 	"github.com/kefir4iick/crud/internal/service"
+	"github.com/kefir4iick/crud/internal/tracing"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found")
+	envErr := godotenv.Load()
+
+	logger, err := logging.New(os.Stdout, getEnv("LOG_FORMAT", "text"), getEnv("LOG_LEVEL", "info"))
+	if err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
 	}
+	slog.SetDefault(logger)
 
-	connStr := buildConnectionString()
-	db, err := postgres.NewDB(connStr)
+	if envErr != nil {
+		logger.Info("No .env file found")
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    getEnv("OTEL_TRACES_EXPORTER", "none"),
+		ServiceName: getEnv("OTEL_SERVICE_NAME", "crud"),
+		SampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+	})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to configure tracing", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	connStr := buildConnectionString()
+	dbConfig := buildDBConfig()
+	dbConfig.Logger = logger
+
+	var (
+		db        *sql.DB
+		repo      repository.CarRepository
+		uow       repository.UnitOfWork
+		idemStore idempotency.Store
+		catalog   repository.CatalogRepository
+		dealers   repository.DealerRepository
+		expired   repository.ExpiredReservationRepository
+	)
+	switch driver := getEnv("DB_DRIVER", "pq"); driver {
+	case "pq":
+		db, err = postgres.NewDB(context.Background(), connStr, dbConfig)
+		if err != nil {
+			fatal("Failed to connect to database", err)
+		}
+		repo = postgres.NewPostgresCarRepository(db, dbConfig)
+		uow = postgres.NewUnitOfWork(db, dbConfig)
+		idemStore = postgres.NewIdempotencyStore(db, dbConfig)
+		catalog = postgres.NewCatalogRepository(db, dbConfig)
+		dealers = postgres.NewDealerRepository(db, dbConfig)
+		expired = postgres.NewExpiredReservationRepository(db, dbConfig)
+		if err := metrics.RegisterDBStats(db, getEnv("DB_NAME", "postgres")); err != nil {
+			fatal("Failed to register database metrics", err)
+		}
+	case "pgx":
+		pool, err := postgres.NewPool(context.Background(), connStr, dbConfig)
+		if err != nil {
+			fatal("Failed to connect to database", err)
+		}
+		defer pool.Close()
+		db = stdlib.OpenDBFromPool(pool)
+		repo = postgres.NewPgxCarRepository(pool, dbConfig)
+		uow = postgres.NewPgxUnitOfWork(pool, dbConfig)
+		idemStore = postgres.NewPgxIdempotencyStore(pool, dbConfig)
+		catalog = postgres.NewPgxCatalogRepository(pool, dbConfig)
+		dealers = postgres.NewPgxDealerRepository(pool, dbConfig)
+		expired = postgres.NewPgxExpiredReservationRepository(pool, dbConfig)
+		if err := metrics.RegisterPoolStats(pool, getEnv("DB_NAME", "postgres")); err != nil {
+			fatal("Failed to register database metrics", err)
+		}
+	default:
+		fatal("Unknown DB_DRIVER, expected pq or pgx", fmt.Errorf("got %q", driver))
 	}
 	defer db.Close()
 
-	repo := postgres.NewPostgresCarRepository(db)
+	if err := postgres.CheckSchema(context.Background(), db); err != nil {
+		fatal("Database schema check failed", err)
+	}
+
+	// The car cache is per process and only invalidated by this process's
+	// writes, so enable it only when a single instance serves the database.
+	if ttl := getEnvDuration("CAR_CACHE_TTL", 0); ttl > 0 {
+		carCache := cache.New(ttl, getEnvInt("CAR_CACHE_SIZE", 10000))
+		repo = cache.NewCarRepository(repo, carCache)
+		uow = cache.NewUnitOfWork(uow, carCache)
+	}
+
+	switch kind := getEnv("IDEMPOTENCY_STORE", "postgres"); kind {
+	case "postgres":
+	case "memory":
+		idemStore = idempotency.NewMemoryStore()
+	default:
+		fatal("Unknown IDEMPOTENCY_STORE, expected postgres or memory", fmt.Errorf("got %q", kind))
+	}
+	janitorCtx, stopJanitor := context.WithCancel(context.Background())
+	defer stopJanitor()
+	go idempotency.RunJanitor(janitorCtx, idemStore, getEnvDuration("IDEMPOTENCY_CLEANUP_INTERVAL", 10*time.Minute), logger)
+
+	authEnabled := getEnv("AUTH_ENABLED", "true") == "true"
 
-	carService := service.NewCarService(repo)
+	serviceOpts := []service.Option{service.WithLogger(logger)}
+	if getEnv("VIN_PREFILL", "false") == "true" {
+		serviceOpts = append(serviceOpts, service.WithVINPrefill())
+	}
+	defaultCurrency := getEnv("DEFAULT_CURRENCY", "USD")
+	if !domain.ValidCurrency(defaultCurrency) {
+		fatal("Invalid DEFAULT_CURRENCY", fmt.Errorf("%q is not an ISO 4217 code", defaultCurrency))
+	}
+	serviceOpts = append(serviceOpts, service.WithDefaultCurrency(defaultCurrency))
+	if path := getEnv("CURRENCY_RATES_FILE", ""); path != "" {
+		table, err := loadConversionTable(path)
+		if err != nil {
+			fatal("Failed to load currency rates", err)
+		}
+		serviceOpts = append(serviceOpts, service.WithConversionTable(table))
+	}
+	if getEnv("CATALOG_MATCH", "true") == "true" {
+		serviceOpts = append(serviceOpts, service.WithCatalog(catalog, getEnv("CATALOG_STRICT", "false") == "true"))
+	}
+	blobs, err := blob.NewLocalStore(getEnv("ATTACHMENT_DIR", "data/attachments"))
+	if err != nil {
+		fatal("Failed to configure attachment storage", err)
+	}
+	serviceOpts = append(serviceOpts, service.WithAttachmentContent(blobs))
+	var authz service.Authorizer
+	if authEnabled {
+		policy, err := buildPolicy()
+		if err != nil {
+			fatal("Failed to load authorization policy", err)
+		}
+		authz = policy
+		serviceOpts = append(serviceOpts, service.WithAuthorizer(policy))
+	}
+	carService := service.WithTracing(service.NewCarService(repo, uow, serviceOpts...))
+	catalogService := service.WithCatalogTracing(service.NewCatalogService(catalog, authz, logger))
+	dealerService := service.WithDealerTracing(service.NewDealerService(dealers, authz, logger))
+	maxAttachmentBytes := int64(getEnvInt("ATTACHMENT_MAX_BYTES", service.DefaultMaxAttachmentSize))
+	attachmentService := service.WithAttachmentTracing(service.NewAttachmentService(uow, blobs, maxAttachmentBytes, authz, logger))
+	reservationService := service.WithReservationTracing(service.NewReservationService(uow, expired, authz, logger))
+	go service.RunReservationSweeper(janitorCtx, reservationService, getEnvDuration("RESERVATION_SWEEP_INTERVAL", time.Minute), logger)
 
-	carHandler := handler.NewCarHandler(carService)
+	maxBodyBytes := int64(getEnvInt("HTTP_MAX_BODY_BYTES", 1<<20))
+	carHandler := handler.NewCarHandler(carService, logger, handler.WithMaxBodyBytes(maxBodyBytes))
+	catalogHandler := handler.NewCatalogHandler(catalogService, logger, maxBodyBytes)
+	dealerHandler := handler.NewDealerHandler(dealerService, logger, maxBodyBytes)
+	attachmentHandler := handler.NewAttachmentHandler(attachmentService, logger, maxAttachmentBytes)
+	reservationHandler := handler.NewReservationHandler(reservationService, logger, maxBodyBytes)
 
 	r := chi.NewRouter()
-	r.Mount("/cars", api.NewCarRouter(carHandler))
+	r.Use(api.RequestID, api.Tracing, api.AccessLog(logger), api.Metrics)
+	r.Handle("/metrics", promhttp.Handler())
+	r.Group(func(r chi.Router) {
+		limits := ratelimit.NewMemoryStore()
+		// Throttle by address first, so that requests failing authentication
+		// are limited too.
+		r.Use(api.ThrottleByIP(limits,
+			ratelimit.Limit{Rate: getEnvFloat("RATE_LIMIT_IP_RPS", 50), Burst: getEnvInt("RATE_LIMIT_IP_BURST", 100)},
+			logger,
+		))
+		if authEnabled {
+			authn, err := buildAuthenticator()
+			if err != nil {
+				fatal("Failed to configure authentication", err)
+			}
+			r.Use(api.Authenticate(authn, logger))
+		} else {
+			logger.Warn("Authentication is disabled")
+		}
+		r.Use(api.ResolveTenant(getEnv("TENANT_HEADER", "X-Tenant-ID"), getEnv("DEFAULT_TENANT", "default")))
+		r.Use(api.RateLimit(limits,
+			ratelimit.Limit{Rate: getEnvFloat("RATE_LIMIT_READ_RPS", 20), Burst: getEnvInt("RATE_LIMIT_READ_BURST", 40)},
+			ratelimit.Limit{Rate: getEnvFloat("RATE_LIMIT_WRITE_RPS", 5), Burst: getEnvInt("RATE_LIMIT_WRITE_BURST", 10)},
+			logger,
+		))
+		r.Mount("/cars", api.NewCarRouter(carHandler, api.CarRoutes{
+			Idempotency:      api.Idempotency(idemStore, getEnvDuration("IDEMPOTENCY_LEASE", time.Minute), getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour), maxBodyBytes, logger),
+			ListCacheControl: getEnv("CACHE_CONTROL_CARS_LIST", "private, no-cache"),
+			ItemCacheControl: getEnv("CACHE_CONTROL_CARS_ITEM", "private, no-cache"),
+			Attachments:      attachmentHandler,
+			Reservations:     reservationHandler,
+		}))
+		r.Mount("/dealers", api.NewDealerRouter(dealerHandler, carHandler, getEnv("CACHE_CONTROL_DEALERS", "private, no-cache")))
+		r.Mount("/makes", api.NewCatalogRouter(catalogHandler, getEnv("CACHE_CONTROL_CATALOG", "private, no-cache")))
+	})
 
 	port := getEnv("PORT", "8080")
-	log.Printf("Starting server on :%s", port)
+	logger.Info("Starting server", slog.String("addr", ":"+port))
 	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+		fatal("Failed to start server", err)
 	}
 }
 
+func fatal(msg string, err error) {
+	slog.Error(msg, slog.Any("error", err))
+	os.Exit(1)
+}
+
 func buildConnectionString() string {
 	return "user=" + getEnv("DB_USER", "postgres") +
 		" dbname=" + getEnv("DB_NAME", "postgres") +
@@ -50,9 +235,129 @@ func buildConnectionString() string {
 		" sslmode=" + getEnv("DB_SSLMODE", "disable")
 }
 
+func buildDBConfig() postgres.Config {
+	cfg := postgres.DefaultConfig()
+	cfg.MaxOpenConns = getEnvInt("DB_MAX_OPEN_CONNS", cfg.MaxOpenConns)
+	cfg.MaxIdleConns = getEnvInt("DB_MAX_IDLE_CONNS", cfg.MaxIdleConns)
+	cfg.ConnMaxLifetime = getEnvDuration("DB_CONN_MAX_LIFETIME", cfg.ConnMaxLifetime)
+	cfg.ConnMaxIdleTime = getEnvDuration("DB_CONN_MAX_IDLE_TIME", cfg.ConnMaxIdleTime)
+	cfg.ConnectAttempts = getEnvInt("DB_CONNECT_ATTEMPTS", cfg.ConnectAttempts)
+	cfg.ConnectBackoff = getEnvDuration("DB_CONNECT_BACKOFF", cfg.ConnectBackoff)
+	cfg.ConnectMaxBackoff = getEnvDuration("DB_CONNECT_MAX_BACKOFF", cfg.ConnectMaxBackoff)
+	cfg.QueryTimeout = getEnvDuration("DB_QUERY_TIMEOUT", cfg.QueryTimeout)
+	cfg.ReadRetries = getEnvInt("DB_READ_RETRIES", cfg.ReadRetries)
+	cfg.TxRetries = getEnvInt("DB_TX_RETRIES", cfg.TxRetries)
+	cfg.RetryBackoff = getEnvDuration("DB_RETRY_BACKOFF", cfg.RetryBackoff)
+	cfg.TenantRLS = getEnv("DB_TENANT_RLS", "false") == "true"
+	return cfg
+}
+
+func buildAuthenticator() (*auth.Authenticator, error) {
+	cfg := auth.Config{
+		HS256Secret: []byte(getEnv("AUTH_JWT_HS256_SECRET", "")),
+		Issuer:      getEnv("AUTH_JWT_ISSUER", ""),
+		Audience:    getEnv("AUTH_JWT_AUDIENCE", ""),
+		Leeway:      getEnvDuration("AUTH_JWT_LEEWAY", 30*time.Second),
+	}
+
+	if path := getEnv("AUTH_JWKS_FILE", ""); path != "" {
+		keys, err := auth.LoadJWKS(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RSAKeys = keys
+	} else if path := getEnv("AUTH_JWT_RSA_PUBLIC_KEY_FILE", ""); path != "" {
+		keys, err := auth.LoadRSAPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RSAKeys = keys
+	}
+
+	if path := getEnv("AUTH_API_KEYS_FILE", ""); path != "" {
+		keys, err := auth.LoadAPIKeys(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg.APIKeys = keys
+	}
+
+	return auth.NewAuthenticator(cfg)
+}
+
+func buildPolicy() (auth.Policy, error) {
+	if path := getEnv("AUTH_POLICY_FILE", ""); path != "" {
+		return auth.LoadPolicy(path)
+	}
+	return auth.DefaultPolicy(), nil
+}
+
+// loadConversionTable reads {"base": "USD", "rates": {"EUR": 1.08, ...}}
+// where each rate is the value of one unit of the currency in the base
+// currency.
+func loadConversionTable(path string) (*domain.ConversionTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read currency rates: %w", err)
+	}
+
+	var table domain.ConversionTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse currency rates: %w", err)
+	}
+	if !domain.ValidCurrency(table.Base) {
+		return nil, fmt.Errorf("base currency %q is not an ISO 4217 code", table.Base)
+	}
+	for currency, rate := range table.Rates {
+		if !domain.ValidCurrency(currency) || rate <= 0 {
+			return nil, fmt.Errorf("invalid rate %v for currency %q", rate, currency)
+		}
+	}
+	return &table, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Invalid integer in environment, using default", slog.String("key", key), slog.String("value", value), slog.Int("default", defaultValue))
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("Invalid duration in environment, using default", slog.String("key", key), slog.String("value", value), slog.Duration("default", defaultValue))
+		return defaultValue
+	}
+	return d
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("Invalid number in environment, using default", slog.String("key", key), slog.String("value", value), slog.Float64("default", defaultValue))
+		return defaultValue
+	}
+	return f
+}
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS cars_price_idx;
+DROP INDEX IF EXISTS cars_year_idx;
+DROP INDEX IF EXISTS cars_model_idx;
+DROP INDEX IF EXISTS cars_make_model_idx;
+
+ALTER TABLE cars
+    DROP CONSTRAINT IF EXISTS cars_price_check,
+    DROP CONSTRAINT IF EXISTS cars_year_check,
+    DROP CONSTRAINT IF EXISTS cars_model_check,
+    DROP CONSTRAINT IF EXISTS cars_make_check;
@@ -0,0 +1,10 @@
+ALTER TABLE cars
+    ADD CONSTRAINT cars_make_check CHECK (make <> ''),
+    ADD CONSTRAINT cars_model_check CHECK (model <> ''),
+    ADD CONSTRAINT cars_year_check CHECK (year >= 1900),
+    ADD CONSTRAINT cars_price_check CHECK (price > 0);
+
+CREATE INDEX cars_make_model_idx ON cars (make, model);
+CREATE INDEX cars_model_idx ON cars (model);
+CREATE INDEX cars_year_idx ON cars (year);
+CREATE INDEX cars_price_idx ON cars (price);
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS cars_price_idx;
+DROP INDEX IF EXISTS cars_year_idx;
+DROP INDEX IF EXISTS cars_model_idx;
+DROP INDEX IF EXISTS cars_make_model_idx;
+
+CREATE INDEX cars_make_model_idx ON cars (make, model);
+CREATE INDEX cars_model_idx ON cars (model);
+CREATE INDEX cars_year_idx ON cars (year);
+CREATE INDEX cars_price_idx ON cars (price);
+
+ALTER TABLE cars DROP CONSTRAINT cars_pkey;
+ALTER TABLE cars ADD CONSTRAINT cars_pkey PRIMARY KEY (id);
+
+ALTER TABLE cars DROP COLUMN tenant_id;
@@ -0,0 +1,15 @@
+ALTER TABLE cars ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE cars ALTER COLUMN tenant_id DROP DEFAULT;
+
+ALTER TABLE cars DROP CONSTRAINT cars_pkey;
+ALTER TABLE cars ADD CONSTRAINT cars_pkey PRIMARY KEY (tenant_id, id);
+
+DROP INDEX IF EXISTS cars_make_model_idx;
+DROP INDEX IF EXISTS cars_model_idx;
+DROP INDEX IF EXISTS cars_year_idx;
+DROP INDEX IF EXISTS cars_price_idx;
+
+CREATE INDEX cars_make_model_idx ON cars (tenant_id, make, model);
+CREATE INDEX cars_model_idx ON cars (tenant_id, model);
+CREATE INDEX cars_year_idx ON cars (tenant_id, year);
+CREATE INDEX cars_price_idx ON cars (tenant_id, price);
@@ -0,0 +1,4 @@
+DROP POLICY IF EXISTS cars_tenant_isolation ON cars;
+
+ALTER TABLE cars NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE cars DISABLE ROW LEVEL SECURITY;
@@ -0,0 +1,10 @@
+-- Second line of defence for multi-tenancy. The policy hides every row when
+-- app.tenant_id is not set, so run the service with DB_TENANT_RLS=true unless
+-- it connects as a role that bypasses row-level security. The same holds for
+-- the other enable_*_rls migrations.
+ALTER TABLE cars ENABLE ROW LEVEL SECURITY;
+ALTER TABLE cars FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY cars_tenant_isolation ON cars
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
@@ -0,0 +1,3 @@
+ALTER TABLE cars
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS created_at;
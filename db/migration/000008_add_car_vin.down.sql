@@ -0,0 +1,2 @@
+ALTER TABLE cars DROP CONSTRAINT IF EXISTS cars_tenant_vin_key;
+ALTER TABLE cars DROP COLUMN IF EXISTS vin;
@@ -0,0 +1,4 @@
+ALTER TABLE cars ADD COLUMN vin CHAR(17);
+
+-- NULLs are distinct, so cars without a VIN do not collide.
+ALTER TABLE cars ADD CONSTRAINT cars_tenant_vin_key UNIQUE (tenant_id, vin);
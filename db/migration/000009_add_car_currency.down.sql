@@ -0,0 +1,21 @@
+DROP INDEX IF EXISTS cars_price_idx;
+CREATE INDEX cars_price_idx ON cars (tenant_id, price);
+
+-- Back to whole units, rounding up so that no price drops to zero. The
+-- tenant policy is lifted as in the up migration.
+DO $$
+DECLARE
+    forced BOOLEAN;
+BEGIN
+    SELECT relforcerowsecurity INTO forced FROM pg_class WHERE oid = 'cars'::regclass;
+    ALTER TABLE cars NO FORCE ROW LEVEL SECURITY;
+    UPDATE cars SET price = (price + 99) / 100;
+    IF forced THEN
+        ALTER TABLE cars FORCE ROW LEVEL SECURITY;
+    END IF;
+END $$;
+
+ALTER TABLE cars DROP CONSTRAINT IF EXISTS cars_currency_check;
+ALTER TABLE cars DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE cars ALTER COLUMN price TYPE INTEGER;
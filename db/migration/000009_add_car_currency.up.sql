@@ -0,0 +1,24 @@
+ALTER TABLE cars ALTER COLUMN price TYPE BIGINT;
+
+-- Existing prices were entered in whole dollars without a currency; they are
+-- all USD and are stored in cents from now on.
+ALTER TABLE cars ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE cars ALTER COLUMN currency DROP DEFAULT;
+ALTER TABLE cars ADD CONSTRAINT cars_currency_check CHECK (currency ~ '^[A-Z]{3}$');
+
+-- The tenant policy of 000005_enable_car_rls would hide every row from the
+-- update, so it is lifted while prices are rescaled.
+DO $$
+DECLARE
+    forced BOOLEAN;
+BEGIN
+    SELECT relforcerowsecurity INTO forced FROM pg_class WHERE oid = 'cars'::regclass;
+    ALTER TABLE cars NO FORCE ROW LEVEL SECURITY;
+    UPDATE cars SET price = price * 100;
+    IF forced THEN
+        ALTER TABLE cars FORCE ROW LEVEL SECURITY;
+    END IF;
+END $$;
+
+DROP INDEX IF EXISTS cars_price_idx;
+CREATE INDEX cars_price_idx ON cars (tenant_id, currency, price);
@@ -0,0 +1,15 @@
+CREATE TABLE car_price_history (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    car_id VARCHAR(36) NOT NULL,
+    old_price BIGINT NOT NULL,
+    old_currency CHAR(3) NOT NULL,
+    new_price BIGINT NOT NULL,
+    new_currency CHAR(3) NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    FOREIGN KEY (tenant_id, car_id) REFERENCES cars (tenant_id, id) ON DELETE CASCADE
+);
+
+-- Serves both the history of one car and the first entry, which holds the
+-- original price.
+CREATE INDEX car_price_history_car_idx ON car_price_history (tenant_id, car_id, id);
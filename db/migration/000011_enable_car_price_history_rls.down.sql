@@ -0,0 +1,4 @@
+DROP POLICY IF EXISTS car_price_history_tenant_isolation ON car_price_history;
+
+ALTER TABLE car_price_history NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE car_price_history DISABLE ROW LEVEL SECURITY;
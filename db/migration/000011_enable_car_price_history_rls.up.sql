@@ -0,0 +1,8 @@
+-- Like the policy of 000005_enable_car_rls, this hides every row when
+-- app.tenant_id is not set; see there.
+ALTER TABLE car_price_history ENABLE ROW LEVEL SECURITY;
+ALTER TABLE car_price_history FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY car_price_history_tenant_isolation ON car_price_history
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
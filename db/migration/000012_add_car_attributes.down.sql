@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS cars_body_type_idx;
+DROP INDEX IF EXISTS cars_mileage_idx;
+
+ALTER TABLE cars
+    DROP COLUMN IF EXISTS mileage,
+    DROP COLUMN IF EXISTS condition,
+    DROP COLUMN IF EXISTS fuel_type,
+    DROP COLUMN IF EXISTS transmission,
+    DROP COLUMN IF EXISTS body_type,
+    DROP COLUMN IF EXISTS doors,
+    DROP COLUMN IF EXISTS engine_displacement;
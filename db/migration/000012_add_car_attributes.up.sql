@@ -0,0 +1,21 @@
+ALTER TABLE cars
+    ADD COLUMN mileage INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN condition VARCHAR(16),
+    ADD COLUMN fuel_type VARCHAR(16),
+    ADD COLUMN transmission VARCHAR(16),
+    ADD COLUMN body_type VARCHAR(16),
+    ADD COLUMN doors SMALLINT,
+    ADD COLUMN engine_displacement INTEGER;
+
+-- Keep in sync with the enums in internal/domain/attributes.go.
+ALTER TABLE cars
+    ADD CONSTRAINT cars_mileage_check CHECK (mileage >= 0),
+    ADD CONSTRAINT cars_condition_check CHECK (condition IN ('new', 'used', 'certified')),
+    ADD CONSTRAINT cars_fuel_type_check CHECK (fuel_type IN ('petrol', 'diesel', 'hybrid', 'plug_in_hybrid', 'electric', 'lpg', 'hydrogen')),
+    ADD CONSTRAINT cars_transmission_check CHECK (transmission IN ('manual', 'automatic', 'cvt', 'dual_clutch')),
+    ADD CONSTRAINT cars_body_type_check CHECK (body_type IN ('sedan', 'hatchback', 'wagon', 'coupe', 'convertible', 'suv', 'pickup', 'van', 'minivan')),
+    ADD CONSTRAINT cars_doors_check CHECK (doors BETWEEN 1 AND 9),
+    ADD CONSTRAINT cars_engine_displacement_check CHECK (engine_displacement > 0);
+
+CREATE INDEX cars_body_type_idx ON cars (tenant_id, body_type);
+CREATE INDEX cars_mileage_idx ON cars (tenant_id, mileage);
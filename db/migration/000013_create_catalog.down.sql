@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS model_names;
+DROP TABLE IF EXISTS models;
+DROP TABLE IF EXISTS make_names;
+DROP TABLE IF EXISTS makes;
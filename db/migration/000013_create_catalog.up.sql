@@ -0,0 +1,38 @@
+-- The make and model reference catalog is shared by all tenants. The *_names
+-- tables hold the normalized key of every name and alias so that a single
+-- primary key keeps both unique.
+CREATE TABLE makes (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE make_names (
+    key VARCHAR(255) PRIMARY KEY,
+    make_id VARCHAR(64) NOT NULL REFERENCES makes (id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    alias BOOLEAN NOT NULL
+);
+
+CREATE INDEX make_names_make_idx ON make_names (make_id);
+
+CREATE TABLE models (
+    make_id VARCHAR(64) NOT NULL REFERENCES makes (id) ON DELETE CASCADE,
+    id VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    year_from INTEGER,
+    year_to INTEGER,
+    PRIMARY KEY (make_id, id),
+    CONSTRAINT models_years_check CHECK (year_to >= year_from)
+);
+
+CREATE TABLE model_names (
+    make_id VARCHAR(64) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    model_id VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    alias BOOLEAN NOT NULL,
+    PRIMARY KEY (make_id, key),
+    FOREIGN KEY (make_id, model_id) REFERENCES models (make_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX model_names_model_idx ON model_names (make_id, model_id);
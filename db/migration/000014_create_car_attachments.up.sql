@@ -0,0 +1,17 @@
+CREATE TABLE car_attachments (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    car_id VARCHAR(36) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL CONSTRAINT car_attachments_size_check CHECK (size >= 0),
+    checksum CHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    FOREIGN KEY (tenant_id, car_id) REFERENCES cars (tenant_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX car_attachments_car_idx ON car_attachments (tenant_id, car_id, id);
+
+-- Content is stored once per tenant and checksum; this finds the attachments
+-- still referring to it.
+CREATE INDEX car_attachments_checksum_idx ON car_attachments (tenant_id, checksum);
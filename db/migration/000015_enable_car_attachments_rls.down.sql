@@ -0,0 +1,4 @@
+DROP POLICY IF EXISTS car_attachments_tenant_isolation ON car_attachments;
+
+ALTER TABLE car_attachments NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE car_attachments DISABLE ROW LEVEL SECURITY;
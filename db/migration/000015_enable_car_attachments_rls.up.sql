@@ -0,0 +1,6 @@
+ALTER TABLE car_attachments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE car_attachments FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY car_attachments_tenant_isolation ON car_attachments
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS cars_dealer_idx;
+
+ALTER TABLE cars
+    DROP CONSTRAINT IF EXISTS cars_tenant_id_dealer_id_fkey,
+    DROP COLUMN IF EXISTS dealer_id;
+
+DROP TABLE IF EXISTS dealers;
@@ -0,0 +1,21 @@
+CREATE TABLE dealers (
+    tenant_id VARCHAR(64) NOT NULL,
+    id BIGSERIAL NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    address VARCHAR(1000),
+    latitude DOUBLE PRECISION CONSTRAINT dealers_latitude_check CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION CONSTRAINT dealers_longitude_check CHECK (longitude BETWEEN -180 AND 180),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id),
+    CONSTRAINT dealers_location_check CHECK ((latitude IS NULL) = (longitude IS NULL))
+);
+
+-- A dealer holding cars cannot be deleted; the cars have to be transferred
+-- first.
+ALTER TABLE cars
+    ADD COLUMN dealer_id BIGINT,
+    ADD CONSTRAINT cars_tenant_id_dealer_id_fkey
+        FOREIGN KEY (tenant_id, dealer_id) REFERENCES dealers (tenant_id, id) ON DELETE RESTRICT;
+
+CREATE INDEX cars_dealer_idx ON cars (tenant_id, dealer_id) WHERE dealer_id IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP POLICY IF EXISTS dealers_tenant_isolation ON dealers;
+
+ALTER TABLE dealers NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE dealers DISABLE ROW LEVEL SECURITY;
@@ -0,0 +1,6 @@
+ALTER TABLE dealers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE dealers FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY dealers_tenant_isolation ON dealers
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS car_status_history;
+
+DROP INDEX IF EXISTS cars_status_idx;
+
+ALTER TABLE cars DROP COLUMN IF EXISTS status;
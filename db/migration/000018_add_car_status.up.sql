@@ -0,0 +1,20 @@
+ALTER TABLE cars ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'available';
+
+-- Keep in sync with CarStatus in internal/domain/status.go.
+ALTER TABLE cars
+    ADD CONSTRAINT cars_status_check CHECK (status IN ('available', 'reserved', 'sold', 'withdrawn'));
+
+CREATE INDEX cars_status_idx ON cars (tenant_id, status);
+
+CREATE TABLE car_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    car_id VARCHAR(36) NOT NULL,
+    from_status VARCHAR(16) NOT NULL,
+    to_status VARCHAR(16) NOT NULL,
+    reason VARCHAR(500),
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    FOREIGN KEY (tenant_id, car_id) REFERENCES cars (tenant_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX car_status_history_car_idx ON car_status_history (tenant_id, car_id, id);
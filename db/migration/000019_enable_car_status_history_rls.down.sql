@@ -0,0 +1,4 @@
+DROP POLICY IF EXISTS car_status_history_tenant_isolation ON car_status_history;
+
+ALTER TABLE car_status_history NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE car_status_history DISABLE ROW LEVEL SECURITY;
@@ -0,0 +1,6 @@
+ALTER TABLE car_status_history ENABLE ROW LEVEL SECURITY;
+ALTER TABLE car_status_history FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY car_status_history_tenant_isolation ON car_status_history
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
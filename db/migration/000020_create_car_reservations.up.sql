@@ -0,0 +1,22 @@
+CREATE TABLE car_reservations (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    car_id VARCHAR(36) NOT NULL,
+    holder VARCHAR(255) NOT NULL,
+    note VARCHAR(1000),
+    status VARCHAR(16) NOT NULL DEFAULT 'active',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- Keep in sync with ReservationStatus in internal/domain/reservation.go.
+    CONSTRAINT car_reservations_status_check CHECK (status IN ('active', 'cancelled', 'expired', 'completed')),
+    FOREIGN KEY (tenant_id, car_id) REFERENCES cars (tenant_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX car_reservations_car_idx ON car_reservations (tenant_id, car_id, id);
+
+-- A car has at most one active reservation, however the requests race.
+CREATE UNIQUE INDEX car_reservations_active_idx ON car_reservations (tenant_id, car_id) WHERE status = 'active';
+
+-- The sweeper looks for active reservations past their expiry.
+CREATE INDEX car_reservations_expiry_idx ON car_reservations (expires_at) WHERE status = 'active';
@@ -0,0 +1,5 @@
+DROP POLICY IF EXISTS car_reservations_sweeper ON car_reservations;
+DROP POLICY IF EXISTS car_reservations_tenant_isolation ON car_reservations;
+
+ALTER TABLE car_reservations NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE car_reservations DISABLE ROW LEVEL SECURITY;
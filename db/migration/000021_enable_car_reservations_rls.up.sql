@@ -0,0 +1,14 @@
+ALTER TABLE car_reservations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE car_reservations FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY car_reservations_tenant_isolation ON car_reservations
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+-- The sweeper finds expired reservations before it knows their tenants, so a
+-- session without a tenant may read, but not change, those rows. Ending them
+-- happens per tenant.
+CREATE POLICY car_reservations_sweeper ON car_reservations
+    FOR SELECT
+    USING (coalesce(current_setting('app.tenant_id', true), '') = ''
+        AND status = 'active' AND expires_at <= now());
@@ -0,0 +1,2 @@
+-- Response headers replayed with the stored response, such as Location.
+ALTER TABLE idempotency_keys ADD COLUMN headers JSONB;
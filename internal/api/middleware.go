@@ -0,0 +1,486 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/kefir4iick/crud/internal/auth"
+	"github.com/kefir4iick/crud/internal/idempotency"
+	"github.com/kefir4iick/crud/internal/logging"
+	"github.com/kefir4iick/crud/internal/metrics"
+	"github.com/kefir4iick/crud/internal/ratelimit"
+	"github.com/kefir4iick/crud/internal/tenant"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// RequestID reuses the caller's X-Request-ID when it looks sane, otherwise
+// generates one, stores it in the request context and echoes it back.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessLog writes one record per request once the response is complete.
+func AccessLog(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			logger.LogAttrs(r.Context(), level, "request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", routePattern(r)),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Duration("latency", time.Since(start)),
+				slog.String("remote_addr", r.RemoteAddr),
+			)
+		})
+	}
+}
+
+// Metrics records request count and latency per route pattern. Requests that
+// matched no route share a single "unmatched" label.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		route := routePattern(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHTTP(r.Method, route, status, time.Since(start))
+	})
+}
+
+var tracer = otel.Tracer("github.com/kefir4iick/crud/internal/api")
+
+// Tracing starts a server span per request, continuing the caller's trace
+// when a W3C traceparent header is present. The span is renamed to the route
+// pattern once routing has happened.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		if route := routePattern(r); route != "" {
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
+
+// Authenticate rejects requests without valid credentials with 401 and puts
+// the principal into the context of the others.
+func Authenticate(authn *auth.Authenticator, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authn.Authenticate(r)
+			if err != nil {
+				logger.DebugContext(r.Context(), "authentication failed", slog.Any("error", err))
+
+				challenge := `Bearer realm="crud"`
+				if errors.Is(err, auth.ErrInvalidToken) {
+					challenge += `, error="invalid_token"`
+				}
+				w.Header().Set("WWW-Authenticate", challenge)
+				writeError(w, http.StatusUnauthorized, authErrorMessage(err))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// ResolveTenant decides which tenant the request acts for and stores it in the
+// context. A tenant bound to the principal always wins and the header may only
+// repeat it; the header selects the tenant for service keys without one and
+// when authentication is disabled. Otherwise defaultTenant is used, and with
+// no default the request is rejected.
+func ResolveTenant(header, defaultTenant string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested := r.Header.Get(header)
+			if requested != "" && !tenant.ValidID(requested) {
+				writeError(w, http.StatusBadRequest, "invalid tenant ID")
+				return
+			}
+
+			id := defaultTenant
+			principal, authenticated := auth.PrincipalFromContext(r.Context())
+			switch {
+			case authenticated && principal.TenantID != "":
+				if requested != "" && requested != principal.TenantID {
+					writeError(w, http.StatusForbidden, "tenant not allowed for this credential")
+					return
+				}
+				id = principal.TenantID
+			case requested != "" && (!authenticated || principal.Kind == auth.KindService):
+				id = requested
+			case requested != "":
+				writeError(w, http.StatusForbidden, "tenant not allowed for this credential")
+				return
+			}
+
+			if id == "" {
+				writeError(w, http.StatusBadRequest, "tenant is required")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(tenant.WithID(r.Context(), id)))
+		})
+	}
+}
+
+// RateLimit charges every request to a token bucket per client and rejects it
+// with 429 once the bucket is empty. Safe methods use the read budget, all
+// others the write budget. Clients are identified by their principal (the API
+// key name for service keys), or by remote address when unauthenticated. If
+// the store fails the request is let through.
+func RateLimit(store ratelimit.Store, read, write ratelimit.Limit, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			budget, limit := "write", write
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				budget, limit = "read", read
+			}
+			if allow(w, r, store, clientKey(r), budget, limit, logger) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// ThrottleByIP charges every request to a token bucket per remote address
+// before it is authenticated, so that API keys and tokens cannot be guessed
+// faster than limit allows: a request that fails authentication never
+// reaches RateLimit. Clients behind one address share the bucket, so limit
+// should be well above the per-client budgets.
+func ThrottleByIP(store ratelimit.Store, limit ratelimit.Limit, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if allow(w, r, store, ipKey(r), "ip", limit, logger) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// allow charges r to the bucket of key for budget and sets the RateLimit
+// headers. It writes the 429 response and returns false once the bucket is
+// empty.
+func allow(w http.ResponseWriter, r *http.Request, store ratelimit.Store, key, budget string, limit ratelimit.Limit, logger *slog.Logger) bool {
+	if limit.Unlimited() {
+		return true
+	}
+
+	res, err := store.Allow(r.Context(), key+":"+budget, limit)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "rate limit store failed", slog.Any("error", err))
+		return true
+	}
+
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+	if !res.Allowed {
+		metrics.RateLimited(budget)
+		h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+		writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+		return false
+	}
+	return true
+}
+
+func clientKey(r *http.Request) string {
+	if p, ok := auth.PrincipalFromContext(r.Context()); ok {
+		return p.Kind + ":" + p.Subject
+	}
+	return ipKey(r)
+}
+
+func ipKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+)
+
+// replayedHeaders are the response headers stored with an idempotent
+// response and sent again when it is replayed. Per-request headers such as
+// X-Request-ID are deliberately left out.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag", "Last-Modified"}
+
+// Idempotency makes requests carrying an Idempotency-Key header safe to
+// retry. The first request with a key runs normally and its response is
+// stored for ttl; retries get that response replayed, a retry that arrives
+// while the first is still running gets 409, and reusing a key for a different
+// request gets 422. Server errors are not stored so the request can be
+// retried. Keys are scoped to the tenant and principal.
+//
+// While the first request runs its key is only held for lease, so a key whose
+// request was cut short by a crash frees up quickly. lease should exceed the
+// longest a request may take, or a slow request can be executed twice.
+func Idempotency(store idempotency.Store, lease, ttl time.Duration, maxBodyBytes int64, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				writeError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "failed to read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			if int64(len(body)) > maxBodyBytes {
+				// Let the handler reject the oversized body.
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			scope := idempotencyScope(r)
+			hash := requestHash(r, body)
+
+			rec, err := store.Begin(ctx, scope, key, hash, lease)
+			if err != nil {
+				logger.ErrorContext(ctx, "idempotency store failed", slog.Any("error", err))
+				writeError(w, http.StatusInternalServerError, "failed to check Idempotency-Key")
+				return
+			}
+			if rec != nil {
+				switch {
+				case rec.RequestHash != hash:
+					writeError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+				case !rec.Completed():
+					w.Header().Set("Retry-After", "1")
+					writeError(w, http.StatusConflict, "a request with this Idempotency-Key is still being processed")
+				default:
+					for name, value := range rec.Header {
+						w.Header().Set(name, value)
+					}
+					w.Header().Set("Idempotent-Replayed", "true")
+					w.WriteHeader(rec.Status)
+					w.Write(rec.Body)
+				}
+				return
+			}
+
+			completed := false
+			defer func() {
+				if !completed {
+					if err := store.Release(context.WithoutCancel(ctx), scope, key); err != nil {
+						logger.ErrorContext(ctx, "failed to release idempotency key", slog.Any("error", err))
+					}
+				}
+			}()
+
+			var buf bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&buf)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError {
+				return
+			}
+			resp := idempotency.Response{Status: status, Header: make(map[string]string), Body: buf.Bytes()}
+			for _, name := range replayedHeaders {
+				if value := ww.Header().Get(name); value != "" {
+					resp.Header[name] = value
+				}
+			}
+			if err := store.Complete(context.WithoutCancel(ctx), scope, key, resp, ttl); err != nil {
+				logger.ErrorContext(ctx, "failed to store idempotent response", slog.Any("error", err))
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+func idempotencyScope(r *http.Request) string {
+	id, _ := tenant.FromContext(r.Context())
+	if p, ok := auth.PrincipalFromContext(r.Context()); ok {
+		return id + "/" + p.Kind + ":" + p.Subject
+	}
+	return id + "/anonymous"
+}
+
+// requestHash fingerprints what a retry must repeat: the method, the path
+// with its query and the body.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CacheControl sets the Cache-Control header on successful and 304
+// responses. Errors are left uncached.
+func CacheControl(value string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if value == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, value: value}, r)
+		})
+	}
+}
+
+type cacheControlWriter struct {
+	http.ResponseWriter
+	value       string
+	wroteHeader bool
+}
+
+func (w *cacheControlWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status < http.StatusMultipleChoices || status == http.StatusNotModified {
+			w.Header().Set("Cache-Control", w.value)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *cacheControlWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// authErrorMessage keeps token validation details out of the response.
+func authErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, auth.ErrMissingCredentials):
+		return auth.ErrMissingCredentials.Error()
+	case errors.Is(err, auth.ErrInvalidAPIKey):
+		return auth.ErrInvalidAPIKey.Error()
+	}
+	return auth.ErrInvalidToken.Error()
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,66 @@
+package api_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kefir4iick/crud/internal/api"
+	"github.com/kefir4iick/crud/internal/auth"
+	"github.com/kefir4iick/crud/internal/idempotency"
+	"github.com/kefir4iick/crud/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestThrottleByIP_FailedAuthentication(t *testing.T) {
+	authn, err := auth.NewAuthenticator(auth.Config{HS256Secret: []byte("test-secret")})
+	require.NoError(t, err)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	h := api.ThrottleByIP(ratelimit.NewMemoryStore(), ratelimit.Limit{Rate: 0.001, Burst: 3}, discard)(api.Authenticate(authn, discard)(ok))
+
+	guess := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/cars", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer guessed")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, guess("192.0.2.1:1234"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, guess("192.0.2.1:5678"), "same address, another port")
+	assert.Equal(t, http.StatusUnauthorized, guess("192.0.2.2:1234"), "another address")
+}
+
+func TestIdempotency_RequestMismatch(t *testing.T) {
+	calls := 0
+	created := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	})
+	h := api.Idempotency(idempotency.NewMemoryStore(), time.Minute, time.Hour, 1<<20, discard)(created)
+
+	post := func(target, body string) int {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "k1")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, post("/cars?dry_run=true", `{"make":"Toyota"}`))
+	assert.Equal(t, http.StatusCreated, post("/cars?dry_run=true", `{"make":"Toyota"}`), "replayed")
+	assert.Equal(t, http.StatusUnprocessableEntity, post("/cars?dry_run=false", `{"make":"Toyota"}`), "another query")
+	assert.Equal(t, http.StatusUnprocessableEntity, post("/cars?dry_run=true", `{"make":"Honda"}`), "another body")
+	assert.Equal(t, 1, calls)
+}
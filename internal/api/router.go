@@ -1,19 +1,106 @@
 package api
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
+	"github.com/kefir4iick/crud/internal/domain"
 	"github.com/kefir4iick/crud/internal/handler"
 )
 
-func NewCarRouter(h *handler.CarHandler) chi.Router {
+// CarRoutes configures the per-route middleware of the car router.
+type CarRoutes struct {
+	// Idempotency wraps the create endpoints, which are the only ones that
+	// are not naturally idempotent. Nil leaves them unwrapped.
+	Idempotency func(http.Handler) http.Handler
+	// ListCacheControl and ItemCacheControl are sent as Cache-Control with
+	// successful GET /cars and single-car GET responses. Empty values send
+	// nothing.
+	ListCacheControl string
+	ItemCacheControl string
+	// Attachments serves /cars/{id}/attachments. Nil leaves the routes out.
+	Attachments *handler.AttachmentHandler
+	// Reservations serves /cars/{id}/reservations. Nil leaves the routes
+	// out.
+	Reservations *handler.ReservationHandler
+}
+
+func NewCarRouter(h *handler.CarHandler, routes CarRoutes) chi.Router {
 	r := chi.NewRouter()
 
-	r.Post("/", h.Create)
-	r.Get("/", h.GetAll)
-	r.Get("/{id}", h.GetByID)
+	create := r.With()
+	if routes.Idempotency != nil {
+		create = r.With(routes.Idempotency)
+	}
+
+	create.Post("/", h.Create)
+	create.Post("/batch", h.CreateBatch)
+	r.With(CacheControl(routes.ListCacheControl)).Get("/", h.GetAll)
+	r.With(CacheControl(routes.ListCacheControl)).Get("/stats", h.Stats)
+	r.With(CacheControl(routes.ItemCacheControl)).Get("/{id}", h.GetByID)
+	r.With(CacheControl(routes.ItemCacheControl)).Get("/{id}/prices", h.GetPriceHistory)
+	r.With(CacheControl(routes.ItemCacheControl)).Get("/{id}/status-history", h.GetStatusHistory)
+	r.With(CacheControl(routes.ItemCacheControl)).Get("/by-vin/{vin}", h.GetByVIN)
 	r.Put("/{id}", h.Update)
 	r.Patch("/{id}", h.Update) 
 	r.Delete("/{id}", h.Delete)
+	r.Post("/{id}/transfer", h.Transfer)
+	r.Post("/{id}/sell", h.Transition(domain.TransitionSell))
+	r.Post("/{id}/release", h.Transition(domain.TransitionRelease))
+	r.Post("/{id}/withdraw", h.Transition(domain.TransitionWithdraw))
+
+	if a := routes.Attachments; a != nil {
+		r.Post("/{id}/attachments", a.Upload)
+		r.With(CacheControl(routes.ItemCacheControl)).Get("/{id}/attachments", a.List)
+		r.With(CacheControl(routes.ItemCacheControl)).Get("/{id}/attachments/{attachmentID}", a.Download)
+		r.Delete("/{id}/attachments/{attachmentID}", a.Delete)
+	}
+
+	if h := routes.Reservations; h != nil {
+		r.Post("/{id}/reservations", h.Create)
+		// Reserving is creating a reservation, so that every hold expires.
+		r.Post("/{id}/reserve", h.Create)
+		r.With(CacheControl(routes.ItemCacheControl)).Get("/{id}/reservations", h.List)
+		r.Post("/{id}/reservations/{reservationID}/extend", h.Extend)
+		r.Post("/{id}/reservations/{reservationID}/cancel", h.Cancel)
+	}
+
+	return r
+}
+
+// NewCatalogRouter serves the make and model catalog, to be mounted at
+// /makes.
+func NewCatalogRouter(h *handler.CatalogHandler, cacheControl string) chi.Router {
+	r := chi.NewRouter()
+	cached := r.With(CacheControl(cacheControl))
+
+	cached.Get("/", h.ListMakes)
+	r.Post("/", h.CreateMake)
+	cached.Get("/{makeID}", h.GetMake)
+	r.Put("/{makeID}", h.UpdateMake)
+	r.Delete("/{makeID}", h.DeleteMake)
+
+	cached.Get("/{makeID}/models", h.ListModels)
+	r.Post("/{makeID}/models", h.CreateModel)
+	cached.Get("/{makeID}/models/{modelID}", h.GetModel)
+	r.Put("/{makeID}/models/{modelID}", h.UpdateModel)
+	r.Delete("/{makeID}/models/{modelID}", h.DeleteModel)
+
+	return r
+}
+
+// NewDealerRouter serves the dealers, to be mounted at /dealers. cars lists
+// the cars kept at a dealer.
+func NewDealerRouter(h *handler.DealerHandler, cars *handler.CarHandler, cacheControl string) chi.Router {
+	r := chi.NewRouter()
+	cached := r.With(CacheControl(cacheControl))
+
+	cached.Get("/", h.GetAll)
+	r.Post("/", h.Create)
+	cached.Get("/{id}", h.GetByID)
+	r.Put("/{id}", h.Update)
+	r.Delete("/{id}", h.Delete)
+	cached.Get("/{id}/cars", cars.GetByDealer)
 
 	return r
 }
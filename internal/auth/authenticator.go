@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrInvalidAPIKey      = errors.New("invalid API key")
+)
+
+const apiKeyHeader = "X-API-Key"
+
+type Config struct {
+	// HS256Secret enables HMAC-signed tokens.
+	HS256Secret []byte
+	// RSAKeys enables RS256 tokens, keyed by the "kid" header. A key stored
+	// under "" is used for tokens without a kid.
+	RSAKeys  map[string]*rsa.PublicKey
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+
+	APIKeys []APIKey
+}
+
+// APIKey is a service credential. Only the SHA-256 of the key is configured,
+// hex encoded, so the config file does not contain usable secrets.
+type APIKey struct {
+	Name   string   `json:"name"`
+	Hash   string   `json:"hash"`
+	Roles  []string `json:"roles"`
+	Scopes []string `json:"scopes"`
+	// TenantID pins the key to one tenant; leave it empty for keys that pick
+	// the tenant per request.
+	TenantID string `json:"tenant_id"`
+}
+
+type Authenticator struct {
+	cfg     Config
+	parser  *jwt.Parser
+	methods []string
+	apiKeys []apiKey
+}
+
+type apiKey struct {
+	APIKey
+	hash []byte
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Roles    []string `json:"roles"`
+	Scope    string   `json:"scope"`
+	TenantID string   `json:"tenant_id"`
+}
+
+func NewAuthenticator(cfg Config) (*Authenticator, error) {
+	a := &Authenticator{cfg: cfg}
+
+	if len(cfg.HS256Secret) > 0 {
+		a.methods = append(a.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if len(cfg.RSAKeys) > 0 {
+		a.methods = append(a.methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	for _, k := range cfg.APIKeys {
+		hash, err := hex.DecodeString(k.Hash)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("API key %q: hash must be a hex encoded SHA-256", k.Name)
+		}
+		a.apiKeys = append(a.apiKeys, apiKey{APIKey: k, hash: hash})
+	}
+
+	if len(a.methods) == 0 && len(a.apiKeys) == 0 {
+		return nil, errors.New("no JWT keys or API keys configured")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(a.methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	a.parser = jwt.NewParser(opts...)
+
+	return a, nil
+}
+
+// Authenticate reads a bearer token from Authorization or an API key from
+// X-API-Key.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return a.authenticateAPIKey(key)
+	}
+
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, ErrInvalidToken
+	}
+	return a.authenticateToken(strings.TrimSpace(token))
+}
+
+func (a *Authenticator) authenticateToken(raw string) (*Principal, error) {
+	if len(a.methods) == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	var c claims
+	if _, err := a.parser.ParseWithClaims(raw, &c, a.key); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	return &Principal{
+		Subject:  c.Subject,
+		Kind:     KindUser,
+		Roles:    c.Roles,
+		Scopes:   strings.Fields(c.Scope),
+		TenantID: c.TenantID,
+	}, nil
+}
+
+func (a *Authenticator) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return a.cfg.HS256Secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := a.cfg.RSAKeys[kid]; ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+}
+
+func (a *Authenticator) authenticateAPIKey(key string) (*Principal, error) {
+	sum := sha256.Sum256([]byte(key))
+
+	var match *apiKey
+	for i := range a.apiKeys {
+		// Compare against every key so timing does not reveal which one matched.
+		if subtle.ConstantTimeCompare(sum[:], a.apiKeys[i].hash) == 1 {
+			match = &a.apiKeys[i]
+		}
+	}
+	if match == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	return &Principal{
+		Subject:  match.Name,
+		Kind:     KindService,
+		Roles:    match.Roles,
+		Scopes:   match.Scopes,
+		TenantID: match.TenantID,
+	}, nil
+}
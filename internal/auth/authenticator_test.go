@@ -0,0 +1,173 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kefir4iick/crud/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var secret = []byte("test-secret")
+
+func signHS256(t *testing.T, c jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(secret)
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthenticate_JWT(t *testing.T) {
+	authn, err := auth.NewAuthenticator(auth.Config{HS256Secret: secret, Issuer: "issuer"})
+	require.NoError(t, err)
+
+	valid := jwt.MapClaims{
+		"sub":   "alice",
+		"iss":   "issuer",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"editor"},
+		"scope": "cars:read cars:write",
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		wantErr error
+	}{
+		{name: "Valid", header: "Bearer " + signHS256(t, valid)},
+		{name: "Missing", header: "", wantErr: auth.ErrMissingCredentials},
+		{name: "Wrong scheme", header: "Basic abc", wantErr: auth.ErrInvalidToken},
+		{name: "Garbage", header: "Bearer abc", wantErr: auth.ErrInvalidToken},
+		{
+			name:    "Expired",
+			header:  "Bearer " + signHS256(t, jwt.MapClaims{"sub": "alice", "iss": "issuer", "exp": time.Now().Add(-time.Hour).Unix()}),
+			wantErr: auth.ErrInvalidToken,
+		},
+		{
+			name:    "No expiry",
+			header:  "Bearer " + signHS256(t, jwt.MapClaims{"sub": "alice", "iss": "issuer"}),
+			wantErr: auth.ErrInvalidToken,
+		},
+		{
+			name:    "Wrong issuer",
+			header:  "Bearer " + signHS256(t, jwt.MapClaims{"sub": "alice", "iss": "other", "exp": time.Now().Add(time.Hour).Unix()}),
+			wantErr: auth.ErrInvalidToken,
+		},
+		{
+			name:    "Unsigned",
+			header:  "Bearer " + unsigned(t, valid),
+			wantErr: auth.ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/cars", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+
+			p, err := authn.Authenticate(r)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, p)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "alice", p.Subject)
+			assert.Equal(t, auth.KindUser, p.Kind)
+			assert.True(t, p.HasRole("editor"))
+			assert.True(t, p.HasScope("cars:write"))
+		})
+	}
+}
+
+func unsigned(t *testing.T, c jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, c).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthenticate_RS256WithJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"k1","use":"sig","n":%q,"e":%q}]}`,
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	)
+	require.NoError(t, os.WriteFile(jwksPath, []byte(jwks), 0o600))
+
+	keys, err := auth.LoadJWKS(jwksPath)
+	require.NoError(t, err)
+
+	authn, err := auth.NewAuthenticator(auth.Config{RSAKeys: keys})
+	require.NoError(t, err)
+
+	sign := func(kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"sub": "svc",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = kid
+		s, err := token.SignedString(key)
+		require.NoError(t, err)
+		return s
+	}
+
+	r := httptest.NewRequest("GET", "/cars", nil)
+	r.Header.Set("Authorization", "Bearer "+sign("k1"))
+	p, err := authn.Authenticate(r)
+	require.NoError(t, err)
+	assert.Equal(t, "svc", p.Subject)
+
+	r.Header.Set("Authorization", "Bearer "+sign("unknown"))
+	_, err = authn.Authenticate(r)
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+
+	// An HS256 token must not be accepted when only RSA keys are configured.
+	r.Header.Set("Authorization", "Bearer "+signHS256(t, jwt.MapClaims{"sub": "x", "exp": time.Now().Add(time.Hour).Unix()}))
+	_, err = authn.Authenticate(r)
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestAuthenticate_APIKey(t *testing.T) {
+	sum := sha256.Sum256([]byte("importer-key"))
+	authn, err := auth.NewAuthenticator(auth.Config{APIKeys: []auth.APIKey{
+		{Name: "importer", Hash: hex.EncodeToString(sum[:]), Roles: []string{"editor"}},
+	}})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/cars", nil)
+	r.Header.Set("X-API-Key", "importer-key")
+	p, err := authn.Authenticate(r)
+	require.NoError(t, err)
+	assert.Equal(t, "importer", p.Subject)
+	assert.Equal(t, auth.KindService, p.Kind)
+	assert.True(t, p.HasRole("editor"))
+
+	r.Header.Set("X-API-Key", "wrong")
+	_, err = authn.Authenticate(r)
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+}
+
+func TestNewAuthenticator_RequiresCredentials(t *testing.T) {
+	_, err := auth.NewAuthenticator(auth.Config{})
+	assert.Error(t, err)
+
+	_, err = auth.NewAuthenticator(auth.Config{APIKeys: []auth.APIKey{{Name: "bad", Hash: "xyz"}}})
+	assert.Error(t, err)
+}
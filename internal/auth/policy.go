@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/kefir4iick/crud/internal/domain"
+)
+
+type Action string
+
+const (
+	ActionReadCars     Action = "cars:read"
+	ActionCreateCars   Action = "cars:create"
+	ActionUpdateCars   Action = "cars:update"
+	ActionDeleteCars   Action = "cars:delete"
+	ActionTransferCars Action = "cars:transfer"
+	ActionReserveCars  Action = "cars:reserve"
+
+	ActionReadDealers  Action = "dealers:read"
+	ActionWriteDealers Action = "dealers:write"
+
+	ActionReadCatalog  Action = "catalog:read"
+	ActionWriteCatalog Action = "catalog:write"
+)
+
+// platformActions change data shared by every tenant. Whatever the policy
+// says, they are denied to principals pinned to a tenant, so that one tenant's
+// admin cannot change the catalog for all others; they need a platform
+// credential such as a service key without a tenant.
+var platformActions = map[Action]bool{
+	ActionWriteCatalog: true,
+}
+
+// Policy lists, per action, the roles allowed to perform it. A principal
+// holding a scope named after the action is allowed as well, which is how
+// narrowly scoped tokens are granted single operations.
+type Policy map[Action][]string
+
+func DefaultPolicy() Policy {
+	return Policy{
+		ActionReadCars:     {"viewer", "editor", "admin"},
+		ActionCreateCars:   {"editor", "admin"},
+		ActionUpdateCars:   {"editor", "admin"},
+		ActionDeleteCars:   {"admin"},
+		ActionTransferCars: {"editor", "admin"},
+		ActionReserveCars:  {"editor", "admin"},
+
+		ActionReadDealers:  {"viewer", "editor", "admin"},
+		ActionWriteDealers: {"admin"},
+
+		ActionReadCatalog:  {"viewer", "editor", "admin"},
+		ActionWriteCatalog: {"platform-admin"},
+	}
+}
+
+// LoadPolicy reads a policy from a JSON object such as
+// {"cars:read": ["viewer", "editor", "admin"], "cars:delete": ["admin"]}.
+// Actions missing from the file are denied to everyone.
+func LoadPolicy(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	return p, nil
+}
+
+func (p Policy) Authorize(ctx context.Context, action Action) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return domain.ErrUnauthenticated
+	}
+	if platformActions[action] && principal.TenantID != "" {
+		return fmt.Errorf("%w: %s belongs to a tenant and may not %s", domain.ErrForbidden, principal.Subject, action)
+	}
+
+	if principal.HasScope(string(action)) {
+		return nil
+	}
+	for _, role := range p[action] {
+		if principal.HasRole(role) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s may not %s", domain.ErrForbidden, principal.Subject, action)
+}
@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_PlatformActions(t *testing.T) {
+	policy := DefaultPolicy()
+	authorize := func(p *Principal) error {
+		return policy.Authorize(WithPrincipal(context.Background(), p), ActionWriteCatalog)
+	}
+
+	assert.NoError(t, authorize(&Principal{Subject: "ops", Kind: KindService, Roles: []string{"platform-admin"}}))
+	assert.ErrorIs(t, authorize(&Principal{Subject: "a", Roles: []string{"admin"}}), domain.ErrForbidden)
+	assert.ErrorIs(t, authorize(&Principal{Subject: "a", Roles: []string{"platform-admin"}, TenantID: "t1"}), domain.ErrForbidden,
+		"a tenant's principal is refused whatever its roles")
+	assert.ErrorIs(t, authorize(&Principal{Subject: "a", Scopes: []string{"catalog:write"}, TenantID: "t1"}), domain.ErrForbidden,
+		"and whatever its scopes")
+	assert.NoError(t, policy.Authorize(WithPrincipal(context.Background(), &Principal{Subject: "a", Roles: []string{"admin"}, TenantID: "t1"}), ActionWriteDealers))
+}
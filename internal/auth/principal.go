@@ -0,0 +1,50 @@
+// Package auth authenticates callers with JWT bearer tokens or static API keys
+// and carries the resulting principal in the request context.
+package auth
+
+import "context"
+
+const (
+	KindUser    = "user"
+	KindService = "service"
+)
+
+// Principal is the authenticated caller.
+type Principal struct {
+	Subject string
+	Kind    string
+	Roles   []string
+	Scopes  []string
+	// TenantID is the tenant the caller belongs to. It is empty for service
+	// keys that may act for any tenant.
+	TenantID string
+}
+
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type ctxKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,22 @@
+// Package blob stores the content of car attachments. Metadata lives in the
+// database; a Store only maps keys to bytes.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs under slash-separated keys such as "tenant/ab/abcd...".
+type Store interface {
+	// Put stores the content of r under key, replacing what was there. A
+	// failed Put leaves the previous content, if any, in place.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns the content stored under key, or ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
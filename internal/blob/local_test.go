@@ -0,0 +1,45 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = store.Open(ctx, "acme/ab/abc")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Put(ctx, "acme/ab/abc", strings.NewReader("first")))
+	require.NoError(t, store.Put(ctx, "acme/ab/abc", strings.NewReader("second")))
+
+	rc, err := store.Open(ctx, "acme/ab/abc")
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, "second", string(data))
+
+	require.NoError(t, store.Delete(ctx, "acme/ab/abc"))
+	require.NoError(t, store.Delete(ctx, "acme/ab/abc"), "deleting twice is fine")
+	_, err = store.Open(ctx, "acme/ab/abc")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	for _, key := range []string{"", "../escape", "/abs"} {
+		assert.Error(t, store.Put(ctx, key, strings.NewReader("x")), key)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, store.Put(cancelled, "acme/ab/abd", strings.NewReader("x")), context.Canceled)
+	_, err = store.Open(ctx, "acme/ab/abd")
+	assert.ErrorIs(t, err, ErrNotFound, "a failed put leaves nothing behind")
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// Attachment is a photo or document of a car. Its content is kept in a blob
+// store under the tenant and Checksum, so identical files attached to several
+// cars of a tenant are stored once.
+type Attachment struct {
+	ID          int64  `json:"id"`
+	CarID       string `json:"car_id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// Checksum is the hex encoded SHA-256 of the content.
+	Checksum  string    `json:"checksum"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,79 @@
+package domain
+
+// The attribute types below are closed sets. The empty value means "not
+// specified" and is always accepted.
+
+type Condition string
+
+const (
+	ConditionNew       Condition = "new"
+	ConditionUsed      Condition = "used"
+	ConditionCertified Condition = "certified"
+)
+
+func (c Condition) Valid() bool {
+	switch c {
+	case "", ConditionNew, ConditionUsed, ConditionCertified:
+		return true
+	}
+	return false
+}
+
+type FuelType string
+
+const (
+	FuelPetrol       FuelType = "petrol"
+	FuelDiesel       FuelType = "diesel"
+	FuelHybrid       FuelType = "hybrid"
+	FuelPlugInHybrid FuelType = "plug_in_hybrid"
+	FuelElectric     FuelType = "electric"
+	FuelLPG          FuelType = "lpg"
+	FuelHydrogen     FuelType = "hydrogen"
+)
+
+func (f FuelType) Valid() bool {
+	switch f {
+	case "", FuelPetrol, FuelDiesel, FuelHybrid, FuelPlugInHybrid, FuelElectric, FuelLPG, FuelHydrogen:
+		return true
+	}
+	return false
+}
+
+type Transmission string
+
+const (
+	TransmissionManual     Transmission = "manual"
+	TransmissionAutomatic  Transmission = "automatic"
+	TransmissionCVT        Transmission = "cvt"
+	TransmissionDualClutch Transmission = "dual_clutch"
+)
+
+func (t Transmission) Valid() bool {
+	switch t {
+	case "", TransmissionManual, TransmissionAutomatic, TransmissionCVT, TransmissionDualClutch:
+		return true
+	}
+	return false
+}
+
+type BodyType string
+
+const (
+	BodySedan       BodyType = "sedan"
+	BodyHatchback   BodyType = "hatchback"
+	BodyWagon       BodyType = "wagon"
+	BodyCoupe       BodyType = "coupe"
+	BodyConvertible BodyType = "convertible"
+	BodySUV         BodyType = "suv"
+	BodyPickup      BodyType = "pickup"
+	BodyVan         BodyType = "van"
+	BodyMinivan     BodyType = "minivan"
+)
+
+func (b BodyType) Valid() bool {
+	switch b {
+	case "", BodySedan, BodyHatchback, BodyWagon, BodyCoupe, BodyConvertible, BodySUV, BodyPickup, BodyVan, BodyMinivan:
+		return true
+	}
+	return false
+}
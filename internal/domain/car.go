@@ -1,16 +1,64 @@
 package domain
 
+import "time"
+
 type Car struct {
 	ID    string `json:"id"`
 	Make  string `json:"make" validate:"required"`
 	Model string `json:"model" validate:"required"`
 	Year  int    `json:"year" validate:"gte=1900"`
-	Price int    `json:"price" validate:"gt=0"`
+	Price Money  `json:"price"`
+	// VIN is optional but unique per tenant when set.
+	VIN string `json:"vin,omitempty"`
+
+	// Mileage is in kilometres. The other attributes are optional; zero
+	// values mean unknown. EngineDisplacement is in cubic centimetres and
+	// Color is free text, stored in lower case.
+	Mileage            int          `json:"mileage"`
+	Condition          Condition    `json:"condition,omitempty"`
+	FuelType           FuelType     `json:"fuel_type,omitempty"`
+	Transmission       Transmission `json:"transmission,omitempty"`
+	BodyType           BodyType     `json:"body_type,omitempty"`
+	Doors              int          `json:"doors,omitempty"`
+	EngineDisplacement int          `json:"engine_displacement,omitempty"`
+	Color              string       `json:"color,omitempty"`
+
+	// Status only changes through CarStatus.Apply. New cars are available.
+	Status CarStatus `json:"status"`
+	// ReservedUntil is the expiry of the car's active reservation, if any.
+	// It is read only.
+	ReservedUntil *time.Time `json:"reserved_until,omitempty"`
+
+	// DealerID is the dealer the car is kept at, zero if none. It is set on
+	// creation and changed only by a transfer.
+	DealerID int64 `json:"dealer_id,omitempty"`
+
+	// OriginalPrice and DiscountPercent are computed from the price history
+	// and only set once the price has changed. A negative discount is a
+	// price increase.
+	OriginalPrice   *Money   `json:"original_price,omitempty"`
+	DiscountPercent *float64 `json:"discount_percent,omitempty"`
+
+	// CreatedAt and UpdatedAt are maintained by the repository; values sent
+	// by clients are ignored.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type UpdateCarInput struct {
 	Make  *string `json:"make"`
 	Model *string `json:"model"`
 	Year  *int    `json:"year"`
-	Price *int    `json:"price"`
+	Price *Money  `json:"price"`
+	VIN   *string `json:"vin"`
+
+	// Sending "" or 0 clears an optional attribute.
+	Mileage            *int          `json:"mileage"`
+	Condition          *Condition    `json:"condition"`
+	FuelType           *FuelType     `json:"fuel_type"`
+	Transmission       *Transmission `json:"transmission"`
+	BodyType           *BodyType     `json:"body_type"`
+	Doors              *int          `json:"doors"`
+	EngineDisplacement *int          `json:"engine_displacement"`
+	Color              *string       `json:"color"`
 }
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Make is an entry of the reference catalog. Cars whose make matches its name
+// or one of its aliases are stored under Name.
+type Make struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+// Model belongs to a make. YearFrom and YearTo bound the model years it was
+// built in; zero leaves that side open.
+type Model struct {
+	ID       string   `json:"id"`
+	MakeID   string   `json:"make_id"`
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases"`
+	YearFrom int      `json:"year_from,omitempty"`
+	YearTo   int      `json:"year_to,omitempty"`
+}
+
+// BuiltIn reports whether year lies within the model's production years.
+func (m Model) BuiltIn(year int) bool {
+	return (m.YearFrom == 0 || year >= m.YearFrom) && (m.YearTo == 0 || year <= m.YearTo)
+}
+
+// NameKey is what catalog names are matched on: letters and digits only, in
+// lower case. "Mercedes-Benz", "mercedes benz" and "MERCEDES BENZ " all have
+// the key "mercedesbenz".
+func NameKey(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// Slug derives a catalog ID from a name: runs of anything but ASCII letters
+// and digits become a single dash.
+func Slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
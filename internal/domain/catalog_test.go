@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameKey(t *testing.T) {
+	assert.Equal(t, "mercedesbenz", NameKey("Mercedes-Benz"))
+	assert.Equal(t, "mercedesbenz", NameKey("  MERCEDES benz "))
+	assert.Equal(t, "škoda", NameKey("Škoda"))
+	assert.Equal(t, "", NameKey(" - "))
+}
+
+func TestSlug(t *testing.T) {
+	assert.Equal(t, "mercedes-benz", Slug("Mercedes-Benz"))
+	assert.Equal(t, "model-3", Slug("  Model 3 "))
+	assert.Equal(t, "koda", Slug("Škoda"))
+	assert.Equal(t, "", Slug("!!"))
+}
+
+func TestModelBuiltIn(t *testing.T) {
+	m := Model{YearFrom: 2012, YearTo: 2019}
+	assert.True(t, m.BuiltIn(2012))
+	assert.True(t, m.BuiltIn(2019))
+	assert.False(t, m.BuiltIn(2011))
+	assert.False(t, m.BuiltIn(2020))
+	assert.True(t, Model{YearFrom: 2012}.BuiltIn(2030), "still in production")
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// Dealer is a lot of a tenant where cars are kept.
+type Dealer struct {
+	ID       int64        `json:"id"`
+	Name     string       `json:"name"`
+	Address  string       `json:"address,omitempty"`
+	Location *Coordinates `json:"location,omitempty"`
+
+	// CreatedAt and UpdatedAt are maintained by the repository; values sent
+	// by clients are ignored.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Coordinates are a WGS 84 position in decimal degrees.
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+func (c Coordinates) Valid() bool {
+	return c.Latitude >= -90 && c.Latitude <= 90 && c.Longitude >= -180 && c.Longitude <= 180
+}
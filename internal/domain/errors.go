@@ -1,11 +1,75 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrCarNotFound      = errors.New("car not found")
-	ErrDuplicateCarID   = errors.New("car with this ID already exists")
+	ErrCarNotFound    = errors.New("car not found")
+	ErrDuplicateCarID = errors.New("car with this ID already exists")
+	ErrDuplicateVIN   = errors.New("car with this VIN already exists")
+	ErrInvalidVIN     = errors.New("invalid VIN")
+
+	ErrIllegalTransition = errors.New("illegal status transition")
+	ErrCarSold           = errors.New("car is sold and can no longer be changed")
+
+	ErrReservationNotFound  = errors.New("reservation not found")
+	ErrReservationNotActive = errors.New("reservation has already ended")
+	ErrCarReserved          = errors.New("car already has an active reservation")
+
+	ErrDealerNotFound     = errors.New("dealer not found")
+	ErrDealerHasInventory = errors.New("dealer still holds cars")
+	ErrSameDealer         = errors.New("car is already at this dealer")
+
+	ErrMakeNotFound      = errors.New("make not found")
+	ErrModelNotFound     = errors.New("model not found")
+	ErrDuplicateMake     = errors.New("make with this ID, name or alias already exists")
+	ErrDuplicateModel    = errors.New("model with this ID, name or alias already exists")
+	ErrUnknownMakeModel  = errors.New("make or model is not in the catalog")
+	ErrModelYearMismatch = errors.New("model was not built in that year")
+
+	ErrAttachmentNotFound   = errors.New("attachment not found")
+	ErrAttachmentTooLarge   = errors.New("attachment is too large")
+	ErrUnsupportedMediaType = errors.New("attachment type is not supported")
+
+	ErrInvalidCurrency  = errors.New("currency must be an ISO 4217 code")
+	ErrCurrencyMismatch = errors.New("prices in different currencies cannot be compared")
 	ErrInvalidInput     = errors.New("invalid input")
 	ErrInvalidLimit     = errors.New("invalid limit value")
 	ErrInvalidOffset    = errors.New("invalid offset value")
+
+	ErrUnauthenticated = errors.New("authentication required")
+	ErrForbidden       = errors.New("permission denied")
+	ErrTenantRequired  = errors.New("tenant is required")
+
+	ErrUniqueViolation     = errors.New("value already exists")
+	ErrNotNullViolation    = errors.New("required value is missing")
+	ErrForeignKeyViolation = errors.New("referenced value does not exist or is still referenced")
+	ErrCheckViolation      = errors.New("value is out of the allowed range")
+	ErrValueTooLong        = errors.New("value is too long")
+	ErrConcurrentUpdate    = errors.New("concurrent update, please retry")
 )
+
+// ConstraintError is returned when the storage rejects a write. Kind is one of
+// the Err*Violation sentinels above, so callers can use errors.Is on it.
+type ConstraintError struct {
+	Kind       error
+	Column     string
+	Constraint string
+	Err        error
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Column != "" {
+		return fmt.Sprintf("%s: %s", e.Column, e.Kind)
+	}
+	return e.Kind.Error()
+}
+
+func (e *ConstraintError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
@@ -0,0 +1,82 @@
+package domain
+
+import "time"
+
+// CarFilter selects, orders and pages the cars returned by a list query. Zero
+// values mean "no restriction".
+type CarFilter struct {
+	Make  string `json:"make,omitempty"`
+	Model string `json:"model,omitempty"`
+
+	YearMin int `json:"year_min,omitempty"`
+	YearMax int `json:"year_max,omitempty"`
+
+	// Currency restricts the result to cars priced in it; PriceMin and
+	// PriceMax are then in its minor unit. Without a currency they are in the
+	// base currency of Conversion, which must be set.
+	Currency string `json:"currency,omitempty"`
+	PriceMin int64  `json:"price_min,omitempty"`
+	PriceMax int64  `json:"price_max,omitempty"`
+
+	Condition    Condition    `json:"condition,omitempty"`
+	FuelType     FuelType     `json:"fuel_type,omitempty"`
+	Transmission Transmission `json:"transmission,omitempty"`
+	BodyType     BodyType     `json:"body_type,omitempty"`
+	Doors        int          `json:"doors,omitempty"`
+	MileageMin   int          `json:"mileage_min,omitempty"`
+	MileageMax   int          `json:"mileage_max,omitempty"`
+
+	DealerID int64     `json:"dealer_id,omitempty"`
+	Status   CarStatus `json:"status,omitempty"`
+
+	// PriceDroppedSince keeps cars whose price was lowered at or after it.
+	PriceDroppedSince *time.Time `json:"price_dropped_since,omitempty"`
+
+	// Sort is one of the CarSort* values, optionally prefixed with "-" for
+	// descending order. Ties are broken by ID.
+	Sort string `json:"sort,omitempty"`
+
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+
+	// Conversion is set by the service when prices in different currencies
+	// have to be compared; cars in currencies it has no rate for are left out
+	// of price filters and sorted last.
+	Conversion *ConversionTable `json:"conversion,omitempty"`
+}
+
+const (
+	CarSortID        = "id"
+	CarSortPrice     = "price"
+	CarSortYear      = "year"
+	CarSortCreatedAt = "created_at"
+	CarSortMileage   = "mileage"
+)
+
+// ComparesPrices reports whether applying f orders or filters by price.
+func (f CarFilter) ComparesPrices() bool {
+	return f.PriceMin != 0 || f.PriceMax != 0 || f.SortField() == CarSortPrice
+}
+
+// SortField returns the field Sort orders by, without the direction.
+func (f CarFilter) SortField() string {
+	field, _ := f.sort()
+	return field
+}
+
+// SortDescending reports whether Sort asks for descending order.
+func (f CarFilter) SortDescending() bool {
+	_, desc := f.sort()
+	return desc
+}
+
+func (f CarFilter) sort() (field string, desc bool) {
+	field = f.Sort
+	if len(field) > 0 && field[0] == '-' {
+		field, desc = field[1:], true
+	}
+	if field == "" {
+		field = CarSortID
+	}
+	return field, desc
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in the currency's minor unit (cents for USD, yen for
+// JPY) together with its ISO 4217 code.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// UnmarshalJSON accepts {"amount": ..., "currency": ...} and, for clients
+// written against the old API, a bare integer amount. The old API priced cars
+// in whole units, so a bare amount is left with an empty currency for the
+// service to fill in and scale to minor units; the object form must name its
+// currency.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) > 0 && (trimmed[0] == '-' || trimmed[0] >= '0' && trimmed[0] <= '9') {
+		*m = Money{}
+		return json.Unmarshal(trimmed, &m.Amount)
+	}
+
+	type plain Money
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode((*plain)(m)); err != nil {
+		return err
+	}
+	if m.Currency == "" {
+		return errors.New("price currency is required")
+	}
+	return nil
+}
+
+// Major returns the amount in major units as a decimal without trailing
+// zeros: "25000" for 2500000 USD, "19.99" for 1999 USD.
+func (m Money) Major() string {
+	exp := CurrencyExponent(m.Currency)
+	if exp == 0 {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+	s := m.String()
+	s = s[:len(s)-len(m.Currency)-1]
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+func (m Money) String() string {
+	exp := CurrencyExponent(m.Currency)
+	if exp == 0 {
+		return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+	}
+	unit := int64(math.Pow10(exp))
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	return fmt.Sprintf("%s%d.%0*d %s", sign, amount/unit, exp, amount%unit, m.Currency)
+}
+
+// ConversionTable converts amounts into a base currency so that prices in
+// different currencies can be compared. Rates are in major units: a rate of
+// 1.08 for EUR with base USD means one euro is worth 1.08 dollars.
+type ConversionTable struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// Factor returns what one minor unit of currency is worth in minor units of
+// the base currency. ok is false for currencies without a rate.
+func (t *ConversionTable) Factor(currency string) (factor float64, ok bool) {
+	rate, ok := t.Rates[currency]
+	if currency == t.Base {
+		rate, ok = 1, true
+	}
+	if !ok {
+		return 0, false
+	}
+	return rate * math.Pow10(CurrencyExponent(t.Base)-CurrencyExponent(currency)), true
+}
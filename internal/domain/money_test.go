@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoneyJSON(t *testing.T) {
+	var car Car
+	require.NoError(t, json.Unmarshal([]byte(`{"price":{"amount":1999,"currency":"EUR"}}`), &car))
+	assert.Equal(t, Money{Amount: 1999, Currency: "EUR"}, car.Price)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"price":25000}`), &car))
+	assert.Equal(t, Money{Amount: 25000}, car.Price, "a bare integer is the legacy format")
+
+	assert.Error(t, json.Unmarshal([]byte(`{"price":{"amount":1,"cents":2}}`), &car))
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"price":{"amount":1999}}`), &car), "currency is required")
+
+	out, err := json.Marshal(Money{Amount: 1999, Currency: "EUR"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":1999,"currency":"EUR"}`, string(out))
+}
+
+func TestMoneyString(t *testing.T) {
+	assert.Equal(t, "19.99 EUR", Money{Amount: 1999, Currency: "EUR"}.String())
+	assert.Equal(t, "-0.05 USD", Money{Amount: -5, Currency: "USD"}.String())
+	assert.Equal(t, "1500 JPY", Money{Amount: 1500, Currency: "JPY"}.String())
+}
+
+func TestMoneyMajor(t *testing.T) {
+	assert.Equal(t, "25000", Money{Amount: 2500000, Currency: "USD"}.Major())
+	assert.Equal(t, "19.99", Money{Amount: 1999, Currency: "EUR"}.Major())
+	assert.Equal(t, "19.5", Money{Amount: 1950, Currency: "EUR"}.Major())
+	assert.Equal(t, "1.25", Money{Amount: 1250, Currency: "KWD"}.Major())
+	assert.Equal(t, "1500", Money{Amount: 1500, Currency: "JPY"}.Major())
+}
+
+func TestConversionTableFactor(t *testing.T) {
+	table := &ConversionTable{Base: "USD", Rates: map[string]float64{"EUR": 1.08, "JPY": 0.0067}}
+
+	factor, ok := table.Factor("USD")
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, factor)
+
+	factor, ok = table.Factor("JPY")
+	assert.True(t, ok)
+	assert.InDelta(t, 0.67, factor, 1e-9, "one yen is 0.67 cents")
+
+	_, ok = table.Factor("GBP")
+	assert.False(t, ok)
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// PriceChange is one entry of a car's price history.
+type PriceChange struct {
+	OldPrice  Money     `json:"old_price"`
+	NewPrice  Money     `json:"new_price"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// SetOriginalPrice records the price the car was first listed at and derives
+// DiscountPercent from it. The discount is left unset when the currency has
+// changed since.
+func (c *Car) SetOriginalPrice(original Money) {
+	c.OriginalPrice = &original
+	c.DiscountPercent = nil
+	if original.Currency != c.Price.Currency || original.Amount <= 0 {
+		return
+	}
+	percent := float64(original.Amount-c.Price.Amount) * 100 / float64(original.Amount)
+	percent = math.Round(percent*100) / 100
+	c.DiscountPercent = &percent
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCarSetOriginalPrice(t *testing.T) {
+	car := Car{Price: Money{Amount: 20000, Currency: "USD"}}
+	car.SetOriginalPrice(Money{Amount: 30000, Currency: "USD"})
+	require.NotNil(t, car.DiscountPercent)
+	assert.Equal(t, 33.33, *car.DiscountPercent)
+	assert.Equal(t, Money{Amount: 30000, Currency: "USD"}, *car.OriginalPrice)
+
+	car.Price = Money{Amount: 33000, Currency: "USD"}
+	car.SetOriginalPrice(Money{Amount: 30000, Currency: "USD"})
+	require.NotNil(t, car.DiscountPercent)
+	assert.Equal(t, -10.0, *car.DiscountPercent, "a price increase is a negative discount")
+
+	car.SetOriginalPrice(Money{Amount: 30000, Currency: "EUR"})
+	assert.NotNil(t, car.OriginalPrice)
+	assert.Nil(t, car.DiscountPercent, "no discount across currencies")
+}
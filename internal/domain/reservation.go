@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// ReservationStatus is where a reservation is in its life. Only active
+// reservations hold a car; the others are kept as a record.
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"
+	ReservationCancelled ReservationStatus = "cancelled"
+	ReservationExpired   ReservationStatus = "expired"
+	// ReservationCompleted ends a reservation whose car was sold.
+	ReservationCompleted ReservationStatus = "completed"
+)
+
+// Reservation holds a car for a customer until ExpiresAt. A car has at most
+// one active reservation, and it is reserved for as long as it has one.
+type Reservation struct {
+	ID        int64             `json:"id"`
+	CarID     string            `json:"car_id"`
+	Holder    string            `json:"holder"`
+	Note      string            `json:"note,omitempty"`
+	Status    ReservationStatus `json:"status"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// ExpiredReservation identifies an active reservation past its expiry across
+// tenants, for the sweeper to end.
+type ExpiredReservation struct {
+	TenantID string
+	CarID    string
+	ID       int64
+}
@@ -0,0 +1,52 @@
+package domain
+
+// StatsGroupBy is the attribute car statistics are grouped by. The zero value
+// summarises all matching cars in one group.
+type StatsGroupBy string
+
+const (
+	StatsByMake  StatsGroupBy = "make"
+	StatsByModel StatsGroupBy = "model"
+	StatsByYear  StatsGroupBy = "year"
+	StatsByColor StatsGroupBy = "color"
+)
+
+func (g StatsGroupBy) Valid() bool {
+	switch g {
+	case "", StatsByMake, StatsByModel, StatsByYear, StatsByColor:
+		return true
+	}
+	return false
+}
+
+// Aggregate summarises a numeric attribute over a set of cars. Median is
+// interpolated between the middle values of an even set.
+type Aggregate struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Avg    float64 `json:"avg"`
+	Median float64 `json:"median"`
+}
+
+// PriceStats summarises the prices of the cars of a group in one currency,
+// in its minor unit. Prices in different currencies are never combined.
+type PriceStats struct {
+	Currency string `json:"currency"`
+	Count    int64  `json:"count"`
+	Aggregate
+}
+
+// StatsGroup summarises the cars sharing Key. Key is empty when the stats
+// are not grouped, and for cars whose grouping attribute is unknown.
+type StatsGroup struct {
+	Key    string       `json:"key,omitempty"`
+	Count  int64        `json:"count"`
+	Year   Aggregate    `json:"year"`
+	Prices []PriceStats `json:"prices"`
+}
+
+// CarStats is the result of a statistics query, its groups ordered by key.
+type CarStats struct {
+	GroupBy StatsGroupBy `json:"group_by,omitempty"`
+	Groups  []StatsGroup `json:"groups"`
+}
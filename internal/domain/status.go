@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// CarStatus is where a car is in the inventory lifecycle. Sold is final.
+type CarStatus string
+
+const (
+	StatusAvailable CarStatus = "available"
+	StatusReserved  CarStatus = "reserved"
+	StatusSold      CarStatus = "sold"
+	StatusWithdrawn CarStatus = "withdrawn"
+)
+
+func (s CarStatus) Valid() bool {
+	switch s {
+	case StatusAvailable, StatusReserved, StatusSold, StatusWithdrawn:
+		return true
+	}
+	return false
+}
+
+// Transition is an operation that moves a car from one status to another.
+type Transition string
+
+const (
+	TransitionReserve  Transition = "reserve"
+	TransitionSell     Transition = "sell"
+	TransitionRelease  Transition = "release"
+	TransitionWithdraw Transition = "withdraw"
+)
+
+// transitions lists, per transition, the statuses it starts from and the one
+// it leads to. Release puts a reserved or withdrawn car back on sale.
+var transitions = map[Transition]struct {
+	from []CarStatus
+	to   CarStatus
+}{
+	TransitionReserve:  {from: []CarStatus{StatusAvailable}, to: StatusReserved},
+	TransitionSell:     {from: []CarStatus{StatusAvailable, StatusReserved}, to: StatusSold},
+	TransitionRelease:  {from: []CarStatus{StatusReserved, StatusWithdrawn}, to: StatusAvailable},
+	TransitionWithdraw: {from: []CarStatus{StatusAvailable, StatusReserved}, to: StatusWithdrawn},
+}
+
+// Apply returns the status t leads to from s, or ErrIllegalTransition.
+func (s CarStatus) Apply(t Transition) (CarStatus, error) {
+	rule, ok := transitions[t]
+	if !ok {
+		return s, fmt.Errorf("%w: unknown transition %q", ErrInvalidInput, t)
+	}
+	for _, from := range rule.from {
+		if s == from {
+			return rule.to, nil
+		}
+	}
+	return s, fmt.Errorf("%w: cannot %s a %s car", ErrIllegalTransition, t, s)
+}
+
+// StatusChange is one entry of a car's status history.
+type StatusChange struct {
+	From      CarStatus `json:"from"`
+	To        CarStatus `json:"to"`
+	Reason    string    `json:"reason,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCarStatusApply(t *testing.T) {
+	tests := []struct {
+		from    CarStatus
+		t       Transition
+		want    CarStatus
+		wantErr error
+	}{
+		{from: StatusAvailable, t: TransitionReserve, want: StatusReserved},
+		{from: StatusAvailable, t: TransitionSell, want: StatusSold},
+		{from: StatusAvailable, t: TransitionWithdraw, want: StatusWithdrawn},
+		{from: StatusAvailable, t: TransitionRelease, wantErr: ErrIllegalTransition},
+		{from: StatusReserved, t: TransitionSell, want: StatusSold},
+		{from: StatusReserved, t: TransitionRelease, want: StatusAvailable},
+		{from: StatusReserved, t: TransitionWithdraw, want: StatusWithdrawn},
+		{from: StatusReserved, t: TransitionReserve, wantErr: ErrIllegalTransition},
+		{from: StatusWithdrawn, t: TransitionRelease, want: StatusAvailable},
+		{from: StatusWithdrawn, t: TransitionSell, wantErr: ErrIllegalTransition},
+		{from: StatusSold, t: TransitionRelease, wantErr: ErrIllegalTransition},
+		{from: StatusSold, t: TransitionWithdraw, wantErr: ErrIllegalTransition},
+		{from: StatusAvailable, t: "destroy", wantErr: ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" "+string(tt.t), func(t *testing.T) {
+			got, err := tt.from.Apply(tt.t)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, tt.from, got)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kefir4iick/crud/internal/service"
+)
+
+// multipartOverhead is what an upload may add to the file for the multipart
+// boundaries, part headers and small fields.
+const multipartOverhead = 64 << 10
+
+// AttachmentHandler serves the attachments nested under /cars/{id}.
+type AttachmentHandler struct {
+	service        service.AttachmentService
+	logger         *slog.Logger
+	maxUploadBytes int64
+}
+
+// NewAttachmentHandler returns a handler that accepts files of up to
+// maxUploadBytes. The service enforces its own limit too; this one stops an
+// oversized body before it is read.
+func NewAttachmentHandler(svc service.AttachmentService, logger *slog.Logger, maxUploadBytes int64) *AttachmentHandler {
+	if maxUploadBytes <= 0 {
+		maxUploadBytes = service.DefaultMaxAttachmentSize
+	}
+	return &AttachmentHandler{service: svc, logger: logger, maxUploadBytes: maxUploadBytes}
+}
+
+// Upload stores the part named "file" of a multipart/form-data body. Other
+// parts are ignored.
+func (h *AttachmentHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadBytes+multipartOverhead)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		err = &requestError{status: http.StatusUnsupportedMediaType, msg: "Content-Type must be multipart/form-data"}
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			err = badRequest(`multipart field "file" is required`)
+		}
+		if err != nil {
+			err = uploadError(err)
+			fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		attachment, err := h.service.Upload(r.Context(), chi.URLParam(r, "id"), part.FileName(), part)
+		part.Close()
+		if err != nil {
+			err = uploadError(err)
+			fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+			return
+		}
+
+		respondJSON(w, http.StatusCreated, attachment)
+		return
+	}
+}
+
+// uploadError reports a body over the limit as 413 and a malformed multipart
+// body as 400; anything else is returned as is.
+func uploadError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return decodeError(maxErr)
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, http.ErrMissingBoundary) {
+		return badRequest("malformed multipart body: %v", err)
+	}
+	return err
+}
+
+func (h *AttachmentHandler) List(w http.ResponseWriter, r *http.Request) {
+	attachments, err := h.service.List(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	// Deleting an attachment does not move any timestamp forward, so there
+	// is no Last-Modified to offer.
+	respondCacheable(w, r, attachments, time.Time{})
+}
+
+// Download serves the content of an attachment. The content of an attachment
+// never changes, so its checksum is a strong ETag.
+func (h *AttachmentHandler) Download(w http.ResponseWriter, r *http.Request) {
+	id, err := attachmentID(r)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	attachment, content, err := h.service.Open(r.Context(), chi.URLParam(r, "id"), id)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("ETag", `"`+attachment.Checksum+`"`)
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// ServeContent adds range requests; it evaluates the conditional
+	// headers against the ETag set above.
+	if rs, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", attachment.CreatedAt, rs)
+		return
+	}
+
+	w.Header().Set("Last-Modified", attachment.CreatedAt.UTC().Format(http.TimeFormat))
+	if notModified(r, `"`+attachment.Checksum+`"`, attachment.CreatedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, content); err != nil {
+		h.logger.WarnContext(r.Context(), "failed to send attachment", slog.Any("error", err))
+	}
+}
+
+func (h *AttachmentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := attachmentID(r)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), chi.URLParam(r, "id"), id); err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func attachmentID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "attachmentID"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, badRequest("attachment id must be a positive integer")
+	}
+	return id, nil
+}
@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"encoding/json"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/kefir4iick/crud/internal/domain"
@@ -10,27 +14,95 @@ import (
 )
 
 type CarHandler struct {
-	service service.CarService
+	service      service.CarService
+	logger       *slog.Logger
+	maxBodyBytes int64
 }
 
-func NewCarHandler(service service.CarService) *CarHandler {
-	return &CarHandler{service: service}
+type Option func(*CarHandler)
+
+// WithMaxBodyBytes caps the size of request bodies; larger ones get 413.
+func WithMaxBodyBytes(n int64) Option {
+	return func(h *CarHandler) {
+		if n > 0 {
+			h.maxBodyBytes = n
+		}
+	}
+}
+
+func NewCarHandler(service service.CarService, logger *slog.Logger, opts ...Option) *CarHandler {
+	h := &CarHandler{service: service, logger: logger, maxBodyBytes: defaultMaxBodyBytes}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *CarHandler) fail(w http.ResponseWriter, r *http.Request, status int, err error) {
+	fail(h.logger, w, r, status, err)
+}
+
+// legacyCar renders price as the old API did, as a bare number of whole
+// units of the car's currency.
+type legacyCar struct {
+	domain.Car
+	Price json.Number `json:"price"`
+}
+
+// legacyPrices reports whether the client opted into the old price format
+// with ?price_format=legacy.
+func legacyPrices(r *http.Request) bool {
+	return r.URL.Query().Get("price_format") == "legacy"
+}
+
+func presentCar(r *http.Request, car *domain.Car) interface{} {
+	if !legacyPrices(r) {
+		return car
+	}
+	return legacyCar{Car: *car, Price: json.Number(car.Price.Major())}
+}
+
+func presentCars(r *http.Request, cars []domain.Car) interface{} {
+	if !legacyPrices(r) {
+		return cars
+	}
+	legacy := make([]legacyCar, len(cars))
+	for i, car := range cars {
+		legacy[i] = legacyCar{Car: car, Price: json.Number(car.Price.Major())}
+	}
+	return legacy
 }
 
 func (h *CarHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var input domain.Car
-	if err := decodeJSON(r, &input); err != nil {
-		respondError(w, http.StatusBadRequest, err)
+	if err := decodeJSON(w, r, &input, h.maxBodyBytes); err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusBadRequest), err)
 		return
 	}
 
 	car, err := h.service.Create(r.Context(), input)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err)
+		h.fail(w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, presentCar(r, car))
+}
+
+func (h *CarHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	var input []domain.Car
+	if err := decodeJSON(w, r, &input, h.maxBodyBytes); err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	cars, err := h.service.CreateBatch(r.Context(), input)
+	if err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusInternalServerError), err)
 		return
 	}
 
-	respondJSON(w, http.StatusCreated, car)
+	respondJSON(w, http.StatusCreated, presentCars(r, cars))
 }
 
 func (h *CarHandler) GetByID(w http.ResponseWriter, r *http.Request) {
@@ -38,49 +110,260 @@ func (h *CarHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 
 	car, err := h.service.GetByID(r.Context(), id)
 	if err != nil {
-		respondError(w, http.StatusNotFound, err)
+		h.fail(w, r, errorStatus(err, http.StatusNotFound), err)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, car)
+	respondCacheable(w, r, presentCar(r, car), car.UpdatedAt)
+}
+
+func (h *CarHandler) GetByVIN(w http.ResponseWriter, r *http.Request) {
+	vin := chi.URLParam(r, "vin")
+
+	car, err := h.service.GetByVIN(r.Context(), vin)
+	if err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusNotFound), err)
+		return
+	}
+
+	respondCacheable(w, r, presentCar(r, car), car.UpdatedAt)
 }
 
 func (h *CarHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	filter, err := parseCarFilter(r.URL.Query())
+	if err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	cars, err := h.service.GetAll(r.Context(), filter)
+	if err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	// A list has no single modification time (a deletion leaves no trace), so
+	// only the ETag is used for validation.
+	respondCacheable(w, r, presentCars(r, cars), time.Time{})
+}
 
-	cars, err := h.service.GetAll(r.Context(), limit, offset)
+// Stats summarises the cars matching the same filters as GetAll, grouped by
+// the optional group_by parameter: make, model, year or color.
+func (h *CarHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseCarFilter(r.URL.Query())
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err)
+		h.fail(w, r, errorStatus(err, http.StatusBadRequest), err)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, cars)
+	stats, err := h.service.Stats(r.Context(), filter, domain.StatsGroupBy(r.URL.Query().Get("group_by")))
+	if err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondCacheable(w, r, stats, time.Time{})
+}
+
+// GetByDealer lists the cars kept at the dealer in the URL, taking the same
+// parameters as GetAll.
+func (h *CarHandler) GetByDealer(w http.ResponseWriter, r *http.Request) {
+	id, err := dealerID(r)
+	if err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+	filter, err := parseCarFilter(r.URL.Query())
+	if err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+	filter.DealerID = id
+
+	cars, err := h.service.GetAll(r.Context(), filter)
+	if err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondCacheable(w, r, presentCars(r, cars), time.Time{})
+}
+
+// parseCarFilter reads the list query parameters. limit and offset keep their
+// old lenient parsing, where anything unparsable means the default.
+func parseCarFilter(q url.Values) (domain.CarFilter, error) {
+	filter := domain.CarFilter{
+		Make:     q.Get("make"),
+		Model:    q.Get("model"),
+		Currency: q.Get("currency"),
+		Sort:     q.Get("sort"),
+
+		Condition:    domain.Condition(q.Get("condition")),
+		FuelType:     domain.FuelType(q.Get("fuel_type")),
+		Transmission: domain.Transmission(q.Get("transmission")),
+		BodyType:     domain.BodyType(q.Get("body_type")),
+		Status:       domain.CarStatus(q.Get("status")),
+	}
+	filter.Limit, _ = strconv.Atoi(q.Get("limit"))
+	filter.Offset, _ = strconv.Atoi(q.Get("offset"))
+
+	ints := map[string]*int{
+		"year_min":    &filter.YearMin,
+		"year_max":    &filter.YearMax,
+		"doors":       &filter.Doors,
+		"mileage_min": &filter.MileageMin,
+		"mileage_max": &filter.MileageMax,
+	}
+	for name, dst := range ints {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return filter, badRequest("%s must be an integer", name)
+			}
+			*dst = n
+		}
+	}
+
+	amounts := map[string]*int64{"price_min": &filter.PriceMin, "price_max": &filter.PriceMax}
+	for name, dst := range amounts {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return filter, badRequest("%s must be an integer amount in minor units", name)
+			}
+			*dst = n
+		}
+	}
+
+	if v := q.Get("dealer_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return filter, badRequest("dealer_id must be an integer")
+		}
+		filter.DealerID = id
+	}
+
+	if v := q.Get("price_dropped_since"); v != "" {
+		since, err := parseTime(v)
+		if err != nil {
+			return filter, badRequest("price_dropped_since must be a date (2006-01-02) or an RFC 3339 timestamp")
+		}
+		filter.PriceDroppedSince = &since
+	}
+
+	return filter, nil
+}
+
+// parseTime accepts an RFC 3339 timestamp or a date, which means midnight UTC.
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, v)
+}
+
+func (h *CarHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	changes, err := h.service.GetPriceHistory(r.Context(), id)
+	if err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusNotFound), err)
+		return
+	}
+
+	var lastModified time.Time
+	if len(changes) > 0 {
+		lastModified = changes[len(changes)-1].ChangedAt
+	}
+	respondCacheable(w, r, changes, lastModified)
 }
 
 func (h *CarHandler) Update(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	var input domain.UpdateCarInput
-	if err := decodeJSON(r, &input); err != nil {
-		respondError(w, http.StatusBadRequest, err)
+	if err := decodeJSON(w, r, &input, h.maxBodyBytes); err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusBadRequest), err)
 		return
 	}
 
 	car, err := h.service.Update(r.Context(), id, input)
 	if err != nil {
-		respondError(w, http.StatusNotFound, err)
+		h.fail(w, r, errorStatus(err, http.StatusNotFound), err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, presentCar(r, car))
+}
+
+// Transfer moves a car to the dealer named by {"dealer_id": ...}.
+func (h *CarHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var input struct {
+		DealerID int64 `json:"dealer_id"`
+	}
+	if err := decodeJSON(w, r, &input, h.maxBodyBytes); err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	car, err := h.service.Transfer(r.Context(), id, input.DealerID)
+	if err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, presentCar(r, car))
+}
+
+// Transition returns the handler of POST /cars/{id}/<t>. The body is
+// optional and may give a reason: {"reason": "..."}.
+func (h *CarHandler) Transition(t domain.Transition) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		var input struct {
+			Reason string `json:"reason"`
+		}
+		if r.ContentLength != 0 {
+			if err := decodeJSON(w, r, &input, h.maxBodyBytes); err != nil {
+				h.fail(w, r, errorStatus(err, http.StatusBadRequest), err)
+				return
+			}
+		}
+
+		car, err := h.service.Transition(r.Context(), id, t, input.Reason)
+		if err != nil {
+			h.fail(w, r, errorStatus(err, http.StatusInternalServerError), err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, presentCar(r, car))
+	}
+}
+
+func (h *CarHandler) GetStatusHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	changes, err := h.service.GetStatusHistory(r.Context(), id)
+	if err != nil {
+		h.fail(w, r, errorStatus(err, http.StatusNotFound), err)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, car)
+	var lastModified time.Time
+	if len(changes) > 0 {
+		lastModified = changes[len(changes)-1].ChangedAt
+	}
+	respondCacheable(w, r, changes, lastModified)
 }
 
 func (h *CarHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	if err := h.service.Delete(r.Context(), id); err != nil {
-		respondError(w, http.StatusNotFound, err)
+		h.fail(w, r, errorStatus(err, http.StatusNotFound), err)
 		return
 	}
 
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLegacyPrices(t *testing.T) {
+	car := &domain.Car{ID: "1", Make: "Toyota", Price: domain.Money{Amount: 2500050, Currency: "USD"}}
+
+	w := httptest.NewRecorder()
+	respondJSON(w, http.StatusOK, presentCar(httptest.NewRequest(http.MethodGet, "/cars/1", nil), car))
+	assert.Contains(t, w.Body.String(), `"price":{"amount":2500050,"currency":"USD"}`)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/cars?price_format=legacy", nil)
+	respondJSON(w, http.StatusOK, presentCars(r, []domain.Car{*car}))
+	assert.Contains(t, w.Body.String(), `"price":25000.5`)
+	assert.NotContains(t, w.Body.String(), `"amount"`)
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/service"
+)
+
+// CatalogHandler serves /makes and the models nested under it.
+type CatalogHandler struct {
+	service      service.CatalogService
+	logger       *slog.Logger
+	maxBodyBytes int64
+}
+
+func NewCatalogHandler(service service.CatalogService, logger *slog.Logger, maxBodyBytes int64) *CatalogHandler {
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = defaultMaxBodyBytes
+	}
+	return &CatalogHandler{service: service, logger: logger, maxBodyBytes: maxBodyBytes}
+}
+
+func (h *CatalogHandler) CreateMake(w http.ResponseWriter, r *http.Request) {
+	var input domain.Make
+	if err := decodeJSON(w, r, &input, h.maxBodyBytes); err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	m, err := h.service.CreateMake(r.Context(), input)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, m)
+}
+
+func (h *CatalogHandler) GetMake(w http.ResponseWriter, r *http.Request) {
+	m, err := h.service.GetMake(r.Context(), chi.URLParam(r, "makeID"))
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondCacheable(w, r, m, time.Time{})
+}
+
+func (h *CatalogHandler) ListMakes(w http.ResponseWriter, r *http.Request) {
+	makes, err := h.service.ListMakes(r.Context())
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondCacheable(w, r, makes, time.Time{})
+}
+
+func (h *CatalogHandler) UpdateMake(w http.ResponseWriter, r *http.Request) {
+	var input domain.Make
+	if err := decodeJSON(w, r, &input, h.maxBodyBytes); err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	m, err := h.service.UpdateMake(r.Context(), chi.URLParam(r, "makeID"), input)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, m)
+}
+
+func (h *CatalogHandler) DeleteMake(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteMake(r.Context(), chi.URLParam(r, "makeID")); err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CatalogHandler) CreateModel(w http.ResponseWriter, r *http.Request) {
+	var input domain.Model
+	if err := decodeJSON(w, r, &input, h.maxBodyBytes); err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	m, err := h.service.CreateModel(r.Context(), chi.URLParam(r, "makeID"), input)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, m)
+}
+
+func (h *CatalogHandler) GetModel(w http.ResponseWriter, r *http.Request) {
+	m, err := h.service.GetModel(r.Context(), chi.URLParam(r, "makeID"), chi.URLParam(r, "modelID"))
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondCacheable(w, r, m, time.Time{})
+}
+
+func (h *CatalogHandler) ListModels(w http.ResponseWriter, r *http.Request) {
+	models, err := h.service.ListModels(r.Context(), chi.URLParam(r, "makeID"))
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondCacheable(w, r, models, time.Time{})
+}
+
+func (h *CatalogHandler) UpdateModel(w http.ResponseWriter, r *http.Request) {
+	var input domain.Model
+	if err := decodeJSON(w, r, &input, h.maxBodyBytes); err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	m, err := h.service.UpdateModel(r.Context(), chi.URLParam(r, "makeID"), chi.URLParam(r, "modelID"), input)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, m)
+}
+
+func (h *CatalogHandler) DeleteModel(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteModel(r.Context(), chi.URLParam(r, "makeID"), chi.URLParam(r, "modelID")); err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/service"
+)
+
+// DealerHandler serves /dealers. The cars of a dealer are listed by
+// CarHandler.GetByDealer.
+type DealerHandler struct {
+	service      service.DealerService
+	logger       *slog.Logger
+	maxBodyBytes int64
+}
+
+func NewDealerHandler(service service.DealerService, logger *slog.Logger, maxBodyBytes int64) *DealerHandler {
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = defaultMaxBodyBytes
+	}
+	return &DealerHandler{service: service, logger: logger, maxBodyBytes: maxBodyBytes}
+}
+
+func dealerID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, badRequest("dealer id must be a positive integer")
+	}
+	return id, nil
+}
+
+func (h *DealerHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input domain.Dealer
+	if err := decodeJSON(w, r, &input, h.maxBodyBytes); err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	dealer, err := h.service.Create(r.Context(), input)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, dealer)
+}
+
+func (h *DealerHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := dealerID(r)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	dealer, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondCacheable(w, r, dealer, dealer.UpdatedAt)
+}
+
+func (h *DealerHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	dealers, err := h.service.GetAll(r.Context())
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondCacheable(w, r, dealers, time.Time{})
+}
+
+func (h *DealerHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := dealerID(r)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	var input domain.Dealer
+	if err := decodeJSON(w, r, &input, h.maxBodyBytes); err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	dealer, err := h.service.Update(r.Context(), id, input)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, dealer)
+}
+
+func (h *DealerHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := dealerID(r)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/service"
+)
+
+// ReservationHandler serves the reservations nested under /cars/{id}.
+type ReservationHandler struct {
+	service      service.ReservationService
+	logger       *slog.Logger
+	maxBodyBytes int64
+}
+
+func NewReservationHandler(svc service.ReservationService, logger *slog.Logger, maxBodyBytes int64) *ReservationHandler {
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = defaultMaxBodyBytes
+	}
+	return &ReservationHandler{service: svc, logger: logger, maxBodyBytes: maxBodyBytes}
+}
+
+// Create reserves a car for {"holder": ..., "note": ..., "expires_at": ...};
+// only the holder is required.
+func (h *ReservationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Holder    string    `json:"holder"`
+		Note      string    `json:"note"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := decodeJSON(w, r, &input, h.maxBodyBytes); err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	reservation, err := h.service.Create(r.Context(), chi.URLParam(r, "id"), domain.Reservation{
+		Holder:    input.Holder,
+		Note:      input.Note,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, reservation)
+}
+
+func (h *ReservationHandler) List(w http.ResponseWriter, r *http.Request) {
+	reservations, err := h.service.List(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	var lastModified time.Time
+	for _, reservation := range reservations {
+		if reservation.UpdatedAt.After(lastModified) {
+			lastModified = reservation.UpdatedAt
+		}
+	}
+	respondCacheable(w, r, reservations, lastModified)
+}
+
+// Extend moves the expiry of a reservation to {"expires_at": ...}.
+func (h *ReservationHandler) Extend(w http.ResponseWriter, r *http.Request) {
+	id, err := reservationID(r)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	var input struct {
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := decodeJSON(w, r, &input, h.maxBodyBytes); err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+	if input.ExpiresAt.IsZero() {
+		err := badRequest("expires_at is required")
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	reservation, err := h.service.Extend(r.Context(), chi.URLParam(r, "id"), id, input.ExpiresAt)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, reservation)
+}
+
+// Cancel ends a reservation. The body is optional and may give a reason:
+// {"reason": "..."}.
+func (h *ReservationHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := reservationID(r)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := decodeJSON(w, r, &input, h.maxBodyBytes); err != nil {
+			fail(h.logger, w, r, errorStatus(err, http.StatusBadRequest), err)
+			return
+		}
+	}
+
+	reservation, err := h.service.Cancel(r.Context(), chi.URLParam(r, "id"), id, input.Reason)
+	if err != nil {
+		fail(h.logger, w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, reservation)
+}
+
+func reservationID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "reservationID"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, badRequest("reservation id must be a positive integer")
+	}
+	return id, nil
+}
@@ -1,14 +1,122 @@
 package handler
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/kefir4iick/crud/internal/domain"
 )
 
-func decodeJSON(r *http.Request, v interface{}) error {
-	decoder := json.NewDecoder(r.Body)
+const defaultMaxBodyBytes = 1 << 20
+
+// requestError is a problem with the request itself, reported with its own
+// status code and a message meant for the client.
+type requestError struct {
+	status int
+	msg    string
+}
+
+func (e *requestError) Error() string {
+	return e.msg
+}
+
+func badRequest(format string, args ...any) error {
+	return &requestError{status: http.StatusBadRequest, msg: fmt.Sprintf(format, args...)}
+}
+
+// decodeJSON reads exactly one JSON value of at most maxBytes from the body
+// into v. Unknown fields, trailing data and non-JSON content types are
+// rejected, and decoding errors are rewritten to name the offending field.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}, maxBytes int64) error {
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		return &requestError{status: http.StatusUnsupportedMediaType, msg: "Content-Type must be application/json"}
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes))
 	decoder.DisallowUnknownFields()
-	return decoder.Decode(v)
+	if err := decoder.Decode(v); err != nil {
+		return decodeError(err)
+	}
+
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return decodeError(err)
+		}
+		return badRequest("request body must contain a single JSON value")
+	}
+	return nil
+}
+
+func isJSONContentType(value string) bool {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func decodeError(err error) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		maxErr    *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &maxErr):
+		return &requestError{
+			status: http.StatusRequestEntityTooLarge,
+			msg:    fmt.Sprintf("request body must not exceed %d bytes", maxErr.Limit),
+		}
+	case errors.As(err, &syntaxErr):
+		return badRequest("malformed JSON at position %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return badRequest("request body must be %s", jsonType(typeErr.Type))
+		}
+		return badRequest("field %q must be %s, got %s at position %d",
+			typeErr.Field, jsonType(typeErr.Type), typeErr.Value, typeErr.Offset)
+	case errors.Is(err, io.EOF):
+		return badRequest("request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return badRequest("malformed JSON: unexpected end of body")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return badRequest("unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	}
+	return badRequest("invalid request body: %v", err)
+}
+
+// jsonType describes a Go type in terms of the JSON value it accepts.
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	}
+	return t.String()
 }
 
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
@@ -17,6 +125,123 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
+// respondCacheable writes data with status 200 like respondJSON, adding an
+// ETag derived from the body and, if lastModified is set, a Last-Modified
+// header. When the request's If-None-Match or If-Modified-Since still matches,
+// it answers 304 without a body instead.
+func respondCacheable(w http.ResponseWriter, r *http.Request, data interface{}, lastModified time.Time) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(append(body, '\n'))
+}
+
+// notModified evaluates the conditional headers as RFC 9110 prescribes:
+// If-None-Match, when present, takes precedence over If-Modified-Since.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// fail logs err and writes it as the response. Server errors are logged at
+// error level, client errors at debug level.
+func fail(logger *slog.Logger, w http.ResponseWriter, r *http.Request, status int, err error) {
+	level := slog.LevelDebug
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	logger.Log(r.Context(), level, "request failed",
+		slog.Int("status", status),
+		slog.Any("error", err),
+	)
+
+	respondError(w, status, err)
+}
+
 func respondError(w http.ResponseWriter, status int, err error) {
 	respondJSON(w, status, map[string]string{"error": err.Error()})
 }
+
+// errorStatus picks the HTTP status for errors we recognise and falls back to
+// the handler's default for everything else.
+func errorStatus(err error, fallback int) int {
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		return reqErr.status
+	case errors.Is(err, domain.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, domain.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrCarNotFound),
+		errors.Is(err, domain.ErrAttachmentNotFound),
+		errors.Is(err, domain.ErrReservationNotFound),
+		errors.Is(err, domain.ErrDealerNotFound),
+		errors.Is(err, domain.ErrMakeNotFound),
+		errors.Is(err, domain.ErrModelNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrDuplicateCarID),
+		errors.Is(err, domain.ErrDuplicateVIN),
+		errors.Is(err, domain.ErrDuplicateMake),
+		errors.Is(err, domain.ErrDuplicateModel),
+		errors.Is(err, domain.ErrDealerHasInventory),
+		errors.Is(err, domain.ErrSameDealer),
+		errors.Is(err, domain.ErrIllegalTransition),
+		errors.Is(err, domain.ErrCarSold),
+		errors.Is(err, domain.ErrCarReserved),
+		errors.Is(err, domain.ErrReservationNotActive),
+		errors.Is(err, domain.ErrUniqueViolation),
+		errors.Is(err, domain.ErrForeignKeyViolation),
+		errors.Is(err, domain.ErrConcurrentUpdate):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrAttachmentTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, domain.ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, domain.ErrNotNullViolation),
+		errors.Is(err, domain.ErrCheckViolation),
+		errors.Is(err, domain.ErrValueTooLong),
+		errors.Is(err, domain.ErrTenantRequired),
+		errors.Is(err, domain.ErrInvalidVIN),
+		errors.Is(err, domain.ErrInvalidCurrency),
+		errors.Is(err, domain.ErrCurrencyMismatch),
+		errors.Is(err, domain.ErrUnknownMakeModel),
+		errors.Is(err, domain.ErrModelYearMismatch),
+		errors.Is(err, domain.ErrInvalidInput):
+		return http.StatusBadRequest
+	}
+	return fallback
+}
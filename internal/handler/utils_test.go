@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		msg         string
+	}{
+		{name: "valid", contentType: "application/json; charset=utf-8", body: `{"id":"1","year":2020}`},
+		{name: "missing content type", body: `{}`, status: http.StatusUnsupportedMediaType, msg: "Content-Type must be application/json"},
+		{name: "wrong content type", contentType: "text/plain", body: `{}`, status: http.StatusUnsupportedMediaType, msg: "Content-Type must be application/json"},
+		{name: "too large", contentType: "application/json", body: `{"make":"` + strings.Repeat("a", 64) + `"}`, status: http.StatusRequestEntityTooLarge, msg: "request body must not exceed 32 bytes"},
+		{name: "trailing value", contentType: "application/json", body: `{"id":"1"}{"id":"2"}`, status: http.StatusBadRequest, msg: "request body must contain a single JSON value"},
+		{name: "trailing garbage", contentType: "application/json", body: `{"id":"1"} x`, status: http.StatusBadRequest, msg: "request body must contain a single JSON value"},
+		{name: "wrong type", contentType: "application/json", body: `{"year":"new"}`, status: http.StatusBadRequest, msg: `field "year" must be an integer, got string at position 13`},
+		{name: "not an object", contentType: "application/json", body: `[1]`, status: http.StatusBadRequest, msg: "request body must be an object"},
+		{name: "syntax error", contentType: "application/json", body: `{"id":}`, status: http.StatusBadRequest, msg: "malformed JSON at position 7"},
+		{name: "truncated", contentType: "application/json", body: `{"id":"1"`, status: http.StatusBadRequest, msg: "malformed JSON: unexpected end of body"},
+		{name: "empty", contentType: "application/json", body: ``, status: http.StatusBadRequest, msg: "request body is empty"},
+		{name: "unknown field", contentType: "application/json", body: `{"colour":"red"}`, status: http.StatusBadRequest, msg: `unknown field "colour"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/cars", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			var car domain.Car
+			err := decodeJSON(httptest.NewRecorder(), r, &car, 32)
+			if tt.status == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Equal(t, tt.msg, err.Error())
+				assert.Equal(t, tt.status, errorStatus(err, http.StatusInternalServerError))
+			}
+		})
+	}
+}
+
+func TestRespondCacheable(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+	car := domain.Car{ID: "1", UpdatedAt: modified}
+
+	first := httptest.NewRecorder()
+	respondCacheable(first, httptest.NewRequest(http.MethodGet, "/cars/1", nil), car, modified)
+	etag := first.Header().Get("ETag")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, "Wed, 01 May 2024 12:00:00 GMT", first.Header().Get("Last-Modified"))
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+	}{
+		{name: "matching etag", headers: map[string]string{"If-None-Match": `"other", ` + etag}, status: http.StatusNotModified},
+		{name: "weak etag", headers: map[string]string{"If-None-Match": "W/" + etag}, status: http.StatusNotModified},
+		{name: "stale etag", headers: map[string]string{"If-None-Match": `"other"`}, status: http.StatusOK},
+		{name: "not modified since", headers: map[string]string{"If-Modified-Since": "Wed, 01 May 2024 12:00:00 GMT"}, status: http.StatusNotModified},
+		{name: "modified since", headers: map[string]string{"If-Modified-Since": "Wed, 01 May 2024 11:59:59 GMT"}, status: http.StatusOK},
+		{name: "etag wins", headers: map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": "Wed, 01 May 2024 12:00:00 GMT"}, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/cars/1", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			w := httptest.NewRecorder()
+			respondCacheable(w, r, car, modified)
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusNotModified {
+				assert.Empty(t, w.Body.String())
+			}
+		})
+	}
+}
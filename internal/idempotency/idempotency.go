@@ -0,0 +1,70 @@
+// Package idempotency remembers the responses to requests sent with an
+// Idempotency-Key header so that a retried request gets the original response
+// instead of being executed twice.
+package idempotency
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Response is a stored response. Header holds only the headers worth
+// replaying, such as Location.
+type Response struct {
+	Status int
+	Header map[string]string
+	Body   []byte
+}
+
+// Record is what is stored per key. Status is zero while the first request
+// is still being processed.
+type Record struct {
+	RequestHash string
+	Response
+}
+
+func (r *Record) Completed() bool {
+	return r.Status != 0
+}
+
+// Store persists records. Keys are unique per scope, which callers use to keep
+// different clients' keys apart.
+type Store interface {
+	// Begin reserves key for a request with the given hash. It returns nil if
+	// the key was free (or its record had expired) and is now held by the
+	// caller, otherwise the record already stored for it. The reservation
+	// lapses after lease, so a request that never completes, for instance
+	// because the process died, does not hold the key for long.
+	Begin(ctx context.Context, scope, key, requestHash string, lease time.Duration) (*Record, error)
+	// Complete stores the response for a key reserved with Begin and keeps it
+	// for ttl.
+	Complete(ctx context.Context, scope, key string, resp Response, ttl time.Duration) error
+	// Release frees a key reserved with Begin without storing a response, so
+	// the request can be retried.
+	Release(ctx context.Context, scope, key string) error
+	// DeleteExpired removes records past their TTL.
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// RunJanitor deletes expired records every interval until ctx is cancelled.
+func RunJanitor(ctx context.Context, store Store, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := store.DeleteExpired(ctx)
+			if err != nil {
+				logger.ErrorContext(ctx, "failed to delete expired idempotency keys", slog.Any("error", err))
+				continue
+			}
+			if n > 0 {
+				logger.DebugContext(ctx, "deleted expired idempotency keys", slog.Int64("count", n))
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps records in process memory. It is meant for single-replica
+// deployments and tests; records are lost on restart.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[memoryKey]*memoryRecord
+	now     func() time.Time
+}
+
+type memoryKey struct {
+	scope, key string
+}
+
+type memoryRecord struct {
+	Record
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[memoryKey]*memoryRecord), now: time.Now}
+}
+
+func (s *MemoryStore) Begin(_ context.Context, scope, key, requestHash string, lease time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := memoryKey{scope, key}
+	now := s.now()
+	if rec, ok := s.records[k]; ok && now.Before(rec.expiresAt) {
+		stored := rec.Record
+		return &stored, nil
+	}
+
+	s.records[k] = &memoryRecord{Record: Record{RequestHash: requestHash}, expiresAt: now.Add(lease)}
+	return nil, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, scope, key string, resp Response, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.records[memoryKey{scope, key}]; ok && !rec.Completed() {
+		rec.Response = resp
+		rec.expiresAt = s.now().Add(ttl)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, memoryKey{scope, key})
+	return nil
+}
+
+func (s *MemoryStore) DeleteExpired(context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	now := s.now()
+	for k, rec := range s.records {
+		if !now.Before(rec.expiresAt) {
+			delete(s.records, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+var _ Store = (*MemoryStore)(nil)
@@ -0,0 +1,57 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	rec, err := store.Begin(ctx, "alice", "k1", "hash", time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, rec, "a new key is reserved")
+
+	rec, err = store.Begin(ctx, "alice", "k1", "hash", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, rec)
+	assert.False(t, rec.Completed(), "the first request is still in progress")
+
+	rec, err = store.Begin(ctx, "bob", "k1", "other", time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, rec, "keys are scoped")
+
+	resp := Response{Status: 201, Header: map[string]string{"Location": "/cars/1"}, Body: []byte(`{"id":"1"}`)}
+	require.NoError(t, store.Complete(ctx, "alice", "k1", resp, time.Hour))
+	rec, err = store.Begin(ctx, "alice", "k1", "hash", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, rec)
+	assert.Equal(t, Record{RequestHash: "hash", Response: resp}, *rec)
+
+	require.NoError(t, store.Release(ctx, "bob", "k1"))
+	rec, err = store.Begin(ctx, "bob", "k1", "other", time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, rec, "a released key can be reserved again")
+
+	now = now.Add(time.Minute)
+	n, err := store.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n, "the pending key lapses after its lease")
+
+	rec, err = store.Begin(ctx, "alice", "k1", "hash", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, rec, "a completed response is kept for its TTL")
+	assert.True(t, rec.Completed())
+
+	now = now.Add(time.Hour)
+	n, err = store.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
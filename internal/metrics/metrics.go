@@ -0,0 +1,118 @@
+// Package metrics defines the service's Prometheus collectors. They are
+// registered with the default registry, which promhttp.Handler serves.
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "crud"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Repository method latency, including retries.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"repository", "method"})
+
+	queryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_errors_total",
+		Help:      "Repository method failures, including expected ones such as constraint violations.",
+	}, []string{"repository", "method"})
+
+	rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected with 429 by budget (read or write).",
+	}, []string{"budget"})
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "lookups_total",
+		Help:      "Read-through cache lookups by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	carsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cars_created_total",
+		Help:      "Cars created through the service.",
+	})
+
+	carsDeleted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cars_deleted_total",
+		Help:      "Cars deleted through the service.",
+	})
+)
+
+// ObserveHTTP records a finished request. route must be the router pattern
+// (e.g. /cars/{id}), never the raw path, to keep label cardinality bounded.
+func ObserveHTTP(method, route string, status int, d time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+// ObserveQuery returns a func that records the time since ObserveQuery was
+// called; use it as defer metrics.ObserveQuery("cars", "GetByID")().
+func ObserveQuery(repository, method string) func() {
+	start := time.Now()
+	return func() {
+		queryDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+func RateLimited(budget string) {
+	rateLimited.WithLabelValues(budget).Inc()
+}
+
+func QueryFailed(repository, method string) {
+	queryErrors.WithLabelValues(repository, method).Inc()
+}
+
+func CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+func CarsCreated(n int) {
+	carsCreated.Add(float64(n))
+}
+
+func CarDeleted() {
+	carsDeleted.Inc()
+}
+
+// RegisterDBStats exports db.Stats() as crud_db_* pool gauges and counters.
+func RegisterDBStats(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
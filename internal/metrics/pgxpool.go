@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector exports pgxpool.Stat with the same names the database/sql
+// collector uses where the two overlap, so dashboards work for both drivers.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	maxOpen      *prometheus.Desc
+	open         *prometheus.Desc
+	inUse        *prometheus.Desc
+	idle         *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+	maxIdle      *prometheus.Desc
+	maxLifetime  *prometheus.Desc
+}
+
+// RegisterPoolStats is RegisterDBStats for a pgx pool.
+func RegisterPoolStats(pool *pgxpool.Pool, name string) error {
+	labels := prometheus.Labels{"db_name": name}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("go", "sql", name), help, nil, labels)
+	}
+
+	return prometheus.Register(&poolCollector{
+		pool:         pool,
+		maxOpen:      desc("max_open_connections", "Maximum number of open connections to the database."),
+		open:         desc("open_connections", "The number of established connections both in use and idle."),
+		inUse:        desc("in_use_connections", "The number of connections currently in use."),
+		idle:         desc("idle_connections", "The number of idle connections."),
+		waitCount:    desc("wait_count_total", "The total number of connections waited for."),
+		waitDuration: desc("wait_duration_seconds_total", "The total time blocked waiting for a new connection."),
+		maxIdle:      desc("max_idle_time_closed_total", "The total number of connections closed due to max idle time."),
+		maxLifetime:  desc("max_lifetime_closed_total", "The total number of connections closed due to max lifetime."),
+	})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdle
+	ch <- c.maxLifetime
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdle, prometheus.CounterValue, float64(stat.MaxIdleDestroyCount()))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetime, prometheus.CounterValue, float64(stat.MaxLifetimeDestroyCount()))
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+	ctx := context.Background()
+
+	for i := 1; i >= 0; i-- {
+		res, err := store.Allow(ctx, "a", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, err := store.Allow(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 2*time.Second, res.Reset)
+
+	res, err = store.Allow(ctx, "b", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "buckets are per key")
+
+	now = now.Add(time.Second)
+	res, err = store.Allow(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "a token is refilled after a second")
+
+	now = now.Add(time.Hour)
+	_, err = store.Allow(ctx, "c", limit)
+	require.NoError(t, err)
+	assert.NotContains(t, store.buckets, "a", "full buckets are swept")
+}
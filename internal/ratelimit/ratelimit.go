@@ -0,0 +1,38 @@
+// Package ratelimit implements token-bucket rate limiting. The Store interface
+// lets the buckets live in process memory today and in a shared backend once
+// the service runs with more than one replica.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit describes a token bucket: it holds up to Burst tokens and refills at
+// Rate tokens per second. A Rate of zero or less means no limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+// Result is the outcome of taking one token from a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token is available; zero when the
+	// request was allowed.
+	RetryAfter time.Duration
+}
+
+// Store takes tokens from the bucket named by key, creating it full on first
+// use.
+type Store interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/kefir4iick/crud/internal/domain"
+)
+
+// AttachmentRepository keeps the metadata of car attachments. It is only
+// available inside a UnitOfWork, so that the content of an attachment can be
+// stored or removed while its checksum is locked with LockContent.
+type AttachmentRepository interface {
+	Create(ctx context.Context, a domain.Attachment) (*domain.Attachment, error)
+	Get(ctx context.Context, carID string, id int64) (*domain.Attachment, error)
+	// List returns the attachments of a car, oldest first.
+	List(ctx context.Context, carID string) ([]domain.Attachment, error)
+	// Delete deletes an attachment and returns what it was.
+	Delete(ctx context.Context, carID string, id int64) (*domain.Attachment, error)
+	// LockContent locks the content with checksum until the transaction ends
+	// and returns how many attachments refer to it once the lock is held.
+	LockContent(ctx context.Context, checksum string) (int, error)
+}
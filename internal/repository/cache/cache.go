@@ -0,0 +1,112 @@
+// Package cache provides an in-process read-through cache in front of
+// repository.CarRepository.
+//
+// Every entry is stamped with its tenant's generation at the time the read
+// started, and every write bumps the generation once it has finished, so a
+// read racing with a write can never leave a stale entry behind. Invalidation
+// is therefore per tenant rather than per car, which suits a catalogue that is
+// read far more often than it is written.
+//
+// The cache lives in the process, and writes only invalidate the cache of the
+// process that made them. It is therefore only safe with a single instance of
+// the service.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type Cache struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	maxEntries  int
+	lru         *list.List
+	index       map[string]*list.Element
+	generations map[string]uint64
+	now         func() time.Time
+}
+
+type entry struct {
+	key        string
+	value      any
+	generation uint64
+	expiresAt  time.Time
+}
+
+// New returns a cache that keeps at most maxEntries entries, each for at most
+// ttl.
+func New(ttl time.Duration, maxEntries int) *Cache {
+	return &Cache{
+		ttl:         ttl,
+		maxEntries:  maxEntries,
+		lru:         list.New(),
+		index:       make(map[string]*list.Element),
+		generations: make(map[string]uint64),
+		now:         time.Now,
+	}
+}
+
+func (c *Cache) generation(tenant string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generations[tenant]
+}
+
+// invalidate drops every entry of tenant.
+func (c *Cache) invalidate(tenant string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generations[tenant]++
+}
+
+func (c *Cache) get(tenant, key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.index[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if e.generation != c.generations[tenant] || !c.now().Before(e.expiresAt) {
+		c.remove(el)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return e.value, true
+}
+
+// set stores value unless tenant has been written to since generation was
+// read, in which case value may already be stale. The entry expires after the
+// ttl, or at until if that is earlier and not zero.
+func (c *Cache) set(tenant string, generation uint64, key string, value any, until time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generations[tenant] {
+		return
+	}
+
+	expiresAt := c.now().Add(c.ttl)
+	if !until.IsZero() && until.Before(expiresAt) {
+		expiresAt = until
+	}
+	e := &entry{key: key, value: value, generation: generation, expiresAt: expiresAt}
+	if el, ok := c.index[key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.index[key] = c.lru.PushFront(e)
+
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.index, el.Value.(*entry).key)
+}
@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/metrics"
+	"github.com/kefir4iick/crud/internal/repository"
+	"github.com/kefir4iick/crud/internal/tenant"
+)
+
+type carRepository struct {
+	next  repository.CarRepository
+	cache *Cache
+}
+
+// NewCarRepository caches GetByID and GetAll results of next. Locking reads
+// always go to next.
+func NewCarRepository(next repository.CarRepository, cache *Cache) repository.CarRepository {
+	return &carRepository{next: next, cache: cache}
+}
+
+func (r *carRepository) Create(ctx context.Context, car domain.Car) (*domain.Car, error) {
+	defer r.invalidate(ctx)
+	return r.next.Create(ctx, car)
+}
+
+func (r *carRepository) CreateBatch(ctx context.Context, cars []domain.Car) ([]domain.Car, error) {
+	defer r.invalidate(ctx)
+	return r.next.CreateBatch(ctx, cars)
+}
+
+func (r *carRepository) GetByID(ctx context.Context, id string) (*domain.Car, error) {
+	return r.getCar(ctx, "car:", id, r.next.GetByID)
+}
+
+func (r *carRepository) GetByVIN(ctx context.Context, vin string) (*domain.Car, error) {
+	return r.getCar(ctx, "vin:", vin, r.next.GetByVIN)
+}
+
+func (r *carRepository) getCar(ctx context.Context, prefix, key string, load func(context.Context, string) (*domain.Car, error)) (*domain.Car, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return load(ctx, key)
+	}
+
+	cacheKey := prefix + tenantID + ":" + key
+	if v, ok := r.cache.get(tenantID, cacheKey); ok {
+		metrics.CacheLookup("cars", true)
+		car := v.(domain.Car)
+		return &car, nil
+	}
+	metrics.CacheLookup("cars", false)
+
+	generation := r.cache.generation(tenantID)
+	car, err := load(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.set(tenantID, generation, cacheKey, *car, holdsUntil(*car))
+	return car, nil
+}
+
+func (r *carRepository) GetByIDForUpdate(ctx context.Context, id string) (*domain.Car, error) {
+	return r.next.GetByIDForUpdate(ctx, id)
+}
+
+func (r *carRepository) GetAll(ctx context.Context, filter domain.CarFilter) ([]domain.Car, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return r.next.GetAll(ctx, filter)
+	}
+	// Encoding the whole filter keeps the key complete as filters are added.
+	encoded, err := json.Marshal(filter)
+	if err != nil {
+		return r.next.GetAll(ctx, filter)
+	}
+
+	key := "cars:" + tenantID + ":" + string(encoded)
+	if v, ok := r.cache.get(tenantID, key); ok {
+		metrics.CacheLookup("cars", true)
+		return append([]domain.Car(nil), v.([]domain.Car)...), nil
+	}
+	metrics.CacheLookup("cars", false)
+
+	generation := r.cache.generation(tenantID)
+	cars, err := r.next.GetAll(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.set(tenantID, generation, key, append([]domain.Car(nil), cars...), holdsUntil(cars...))
+	return cars, nil
+}
+
+// holdsUntil returns the earliest expiry of a reservation among cars, zero if
+// none is reserved. A car reads as available once its hold lapses, without a
+// write that would invalidate the cache, so entries must not outlive it.
+func holdsUntil(cars ...domain.Car) time.Time {
+	var until time.Time
+	for _, car := range cars {
+		if car.ReservedUntil != nil && (until.IsZero() || car.ReservedUntil.Before(until)) {
+			until = *car.ReservedUntil
+		}
+	}
+	return until
+}
+
+// Stats are not cached; they are asked for rarely and would be invalidated by
+// every write anyway.
+func (r *carRepository) Stats(ctx context.Context, filter domain.CarFilter, groupBy domain.StatsGroupBy) (*domain.CarStats, error) {
+	return r.next.Stats(ctx, filter, groupBy)
+}
+
+func (r *carRepository) Update(ctx context.Context, id string, car domain.Car) (*domain.Car, error) {
+	defer r.invalidate(ctx)
+	return r.next.Update(ctx, id, car)
+}
+
+func (r *carRepository) Delete(ctx context.Context, id string) error {
+	defer r.invalidate(ctx)
+	return r.next.Delete(ctx, id)
+}
+
+// invalidate runs after every write, whether it succeeded or not: a failed
+// write may still have been applied.
+func (r *carRepository) invalidate(ctx context.Context) {
+	if tenantID, ok := tenant.FromContext(ctx); ok {
+		r.cache.invalidate(tenantID)
+	}
+}
+
+type unitOfWork struct {
+	next  repository.UnitOfWork
+	cache *Cache
+}
+
+// NewUnitOfWork invalidates the cache once a transaction of next has ended.
+// Repositories inside the transaction are not cached, so they read their own
+// writes.
+func NewUnitOfWork(next repository.UnitOfWork, cache *Cache) repository.UnitOfWork {
+	return &unitOfWork{next: next, cache: cache}
+}
+
+func (u *unitOfWork) WithTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	if tenantID, ok := tenant.FromContext(ctx); ok {
+		defer u.cache.invalidate(tenantID)
+	}
+	return u.next.WithTx(ctx, fn)
+}
@@ -0,0 +1,92 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/repository"
+	"github.com/kefir4iick/crud/internal/repository/cache"
+	"github.com/kefir4iick/crud/internal/service/mocks"
+	"github.com/kefir4iick/crud/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCarRepository(t *testing.T) {
+	ctx := tenant.WithID(context.Background(), "t1")
+	other := tenant.WithID(context.Background(), "t2")
+	camry := &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: domain.Money{Amount: 25000, Currency: "USD"}}
+	cheaper := &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: domain.Money{Amount: 23000, Currency: "USD"}}
+
+	next := new(mocks.CarRepository)
+	c := cache.New(time.Minute, 100)
+	repo := cache.NewCarRepository(next, c)
+	uow := cache.NewUnitOfWork(&mocks.UnitOfWork{Cars: next}, c)
+
+	next.On("GetByID", ctx, "1").Return(camry, nil).Once()
+	for i := 0; i < 2; i++ {
+		got, err := repo.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, camry, got)
+	}
+
+	next.On("GetByID", other, "1").Return(nil, domain.ErrCarNotFound).Once()
+	_, err := repo.GetByID(other, "1")
+	assert.ErrorIs(t, err, domain.ErrCarNotFound, "entries are per tenant")
+
+	next.On("Update", ctx, "1", mock.Anything).Return(cheaper, nil).Once()
+	err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+		_, err := repos.Cars.Update(ctx, "1", *cheaper)
+		return err
+	})
+	require.NoError(t, err)
+
+	next.On("GetByID", ctx, "1").Return(cheaper, nil).Once()
+	got, err := repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, cheaper, got, "a transaction invalidates the tenant")
+
+	next.On("Delete", ctx, "1").Return(nil).Once()
+	require.NoError(t, repo.Delete(ctx, "1"))
+
+	next.On("GetByID", ctx, "1").Return(nil, domain.ErrCarNotFound).Once()
+	_, err = repo.GetByID(ctx, "1")
+	assert.ErrorIs(t, err, domain.ErrCarNotFound, "a delete invalidates the tenant")
+
+	next.AssertExpectations(t)
+}
+
+func TestCarRepository_ReservedCars(t *testing.T) {
+	ctx := tenant.WithID(context.Background(), "t1")
+	until := time.Now().Add(10 * time.Millisecond)
+	reserved := &domain.Car{ID: "1", Make: "Toyota", Status: domain.StatusReserved, ReservedUntil: &until}
+	available := &domain.Car{ID: "1", Make: "Toyota", Status: domain.StatusAvailable}
+
+	next := new(mocks.CarRepository)
+	repo := cache.NewCarRepository(next, cache.New(time.Hour, 100))
+
+	next.On("GetByID", ctx, "1").Return(reserved, nil).Once()
+	next.On("GetAll", ctx, domain.CarFilter{}).Return([]domain.Car{*reserved}, nil).Once()
+	got, err := repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, reserved, got)
+	page, err := repo.GetAll(ctx, domain.CarFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Car{*reserved}, page)
+
+	// Once the hold lapses the car reads as available without any write.
+	time.Sleep(time.Until(until))
+	next.On("GetByID", ctx, "1").Return(available, nil).Once()
+	next.On("GetAll", ctx, domain.CarFilter{}).Return([]domain.Car{*available}, nil).Once()
+	got, err = repo.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, available, got, "entries expire with the hold")
+	page, err = repo.GetAll(ctx, domain.CarFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Car{*available}, page, "lists expire with the earliest hold")
+
+	next.AssertExpectations(t)
+}
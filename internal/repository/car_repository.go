@@ -7,8 +7,16 @@ import (
 
 type CarRepository interface {
 	Create(ctx context.Context, car domain.Car) (*domain.Car, error)
+	CreateBatch(ctx context.Context, cars []domain.Car) ([]domain.Car, error)
 	GetByID(ctx context.Context, id string) (*domain.Car, error)
-	GetAll(ctx context.Context, limit, offset int) ([]domain.Car, error)
+	GetByVIN(ctx context.Context, vin string) (*domain.Car, error)
+	// GetByIDForUpdate is GetByID with a row lock held until the surrounding
+	// transaction ends. Outside a UnitOfWork the lock is released immediately.
+	GetByIDForUpdate(ctx context.Context, id string) (*domain.Car, error)
+	GetAll(ctx context.Context, filter domain.CarFilter) ([]domain.Car, error)
+	// Stats summarises the cars matching filter, grouped by groupBy. The
+	// sort order and paging of filter are ignored.
+	Stats(ctx context.Context, filter domain.CarFilter, groupBy domain.StatsGroupBy) (*domain.CarStats, error)
 	Update(ctx context.Context, id string, car domain.Car) (*domain.Car, error)
 	Delete(ctx context.Context, id string) error
 }
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/kefir4iick/crud/internal/domain"
+)
+
+// CatalogRepository stores the make and model reference catalog. The catalog
+// is shared by all tenants. Names and aliases are matched by domain.NameKey
+// and must be unique among the makes, and among the models of one make.
+type CatalogRepository interface {
+	CreateMake(ctx context.Context, m domain.Make) (*domain.Make, error)
+	GetMake(ctx context.Context, id string) (*domain.Make, error)
+	ListMakes(ctx context.Context) ([]domain.Make, error)
+	// UpdateMake replaces the name and aliases of a make.
+	UpdateMake(ctx context.Context, id string, m domain.Make) (*domain.Make, error)
+	// DeleteMake deletes a make together with its models.
+	DeleteMake(ctx context.Context, id string) error
+	// FindMake returns the make whose name or alias has the key of name.
+	FindMake(ctx context.Context, name string) (*domain.Make, error)
+
+	CreateModel(ctx context.Context, m domain.Model) (*domain.Model, error)
+	GetModel(ctx context.Context, makeID, id string) (*domain.Model, error)
+	ListModels(ctx context.Context, makeID string) ([]domain.Model, error)
+	// UpdateModel replaces the name, aliases and years of a model.
+	UpdateModel(ctx context.Context, makeID, id string, m domain.Model) (*domain.Model, error)
+	DeleteModel(ctx context.Context, makeID, id string) error
+	// FindModel returns the model of makeID whose name or alias has the key
+	// of name.
+	FindModel(ctx context.Context, makeID, name string) (*domain.Model, error)
+}
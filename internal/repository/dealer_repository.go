@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/kefir4iick/crud/internal/domain"
+)
+
+type DealerRepository interface {
+	Create(ctx context.Context, dealer domain.Dealer) (*domain.Dealer, error)
+	GetByID(ctx context.Context, id int64) (*domain.Dealer, error)
+	// GetAll returns the dealers of the tenant ordered by name.
+	GetAll(ctx context.Context) ([]domain.Dealer, error)
+	// Update replaces the name, address and location of a dealer.
+	Update(ctx context.Context, id int64, dealer domain.Dealer) (*domain.Dealer, error)
+	// Delete fails with domain.ErrDealerHasInventory while cars are kept at
+	// the dealer.
+	Delete(ctx context.Context, id int64) error
+}
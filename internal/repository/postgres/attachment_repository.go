@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/metrics"
+)
+
+const attachmentColumns = `id, car_id, filename, content_type, size, checksum, created_at`
+
+// attachmentRepository is only created by unitOfWork, always on a
+// transaction.
+type attachmentRepository struct {
+	store
+}
+
+func scanAttachment(row interface{ Scan(dest ...any) error }, a *domain.Attachment) error {
+	return row.Scan(&a.ID, &a.CarID, &a.Filename, &a.ContentType, &a.Size, &a.Checksum, &a.CreatedAt)
+}
+
+func (r *attachmentRepository) Create(ctx context.Context, a domain.Attachment) (*domain.Attachment, error) {
+	defer metrics.ObserveQuery("car_attachments", "Create")()
+
+	query := `
+		INSERT INTO car_attachments (tenant_id, car_id, filename, content_type, size, checksum)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + attachmentColumns
+
+	ctx, span := r.startSpan(ctx, "Create", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err = r.run(ctx, func(db dbtx) error {
+		return scanAttachment(db.queryRow(ctx, query, tenantID, a.CarID, a.Filename, a.ContentType, a.Size, a.Checksum), &a)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment: %w", r.translate(ctx, "Create", err))
+	}
+
+	return &a, nil
+}
+
+func (r *attachmentRepository) Get(ctx context.Context, carID string, id int64) (*domain.Attachment, error) {
+	defer metrics.ObserveQuery("car_attachments", "Get")()
+
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM car_attachments
+		WHERE tenant_id = $1 AND car_id = $2 AND id = $3
+	`
+
+	ctx, span := r.startSpan(ctx, "Get", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var a domain.Attachment
+	err = r.run(ctx, func(db dbtx) error {
+		return scanAttachment(db.queryRow(ctx, query, tenantID, carID, id), &a)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrAttachmentNotFound
+		}
+		return nil, fmt.Errorf("failed to get attachment: %w", r.translate(ctx, "Get", err))
+	}
+
+	return &a, nil
+}
+
+func (r *attachmentRepository) List(ctx context.Context, carID string) ([]domain.Attachment, error) {
+	defer metrics.ObserveQuery("car_attachments", "List")()
+
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM car_attachments
+		WHERE tenant_id = $1 AND car_id = $2
+		ORDER BY id
+	`
+
+	ctx, span := r.startSpan(ctx, "List", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	attachments := []domain.Attachment{}
+	err = r.run(ctx, func(db dbtx) error {
+		rows, err := db.query(ctx, query, tenantID, carID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var a domain.Attachment
+			if err := scanAttachment(rows, &a); err != nil {
+				return fmt.Errorf("failed to scan attachment: %w", err)
+			}
+			attachments = append(attachments, a)
+		}
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("rows error: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", r.translate(ctx, "List", err))
+	}
+
+	return attachments, nil
+}
+
+func (r *attachmentRepository) Delete(ctx context.Context, carID string, id int64) (*domain.Attachment, error) {
+	defer metrics.ObserveQuery("car_attachments", "Delete")()
+
+	query := `
+		DELETE FROM car_attachments
+		WHERE tenant_id = $1 AND car_id = $2 AND id = $3
+		RETURNING ` + attachmentColumns
+
+	ctx, span := r.startSpan(ctx, "Delete", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var a domain.Attachment
+	err = r.run(ctx, func(db dbtx) error {
+		return scanAttachment(db.queryRow(ctx, query, tenantID, carID, id), &a)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrAttachmentNotFound
+		}
+		return nil, fmt.Errorf("failed to delete attachment: %w", r.translate(ctx, "Delete", err))
+	}
+
+	return &a, nil
+}
+
+// LockContent takes a transaction-level advisory lock on the tenant and
+// checksum before counting, in a separate statement: a count taken in the
+// same statement would use a snapshot from before the lock was granted and
+// miss an attachment committed while waiting for it.
+func (r *attachmentRepository) LockContent(ctx context.Context, checksum string) (int, error) {
+	defer metrics.ObserveQuery("car_attachments", "LockContent")()
+
+	lock := `SELECT pg_advisory_xact_lock(hashtextextended($1::text || '/' || $2::text, 0))`
+	count := `
+		SELECT count(*)
+		FROM car_attachments
+		WHERE tenant_id = $1 AND checksum = $2
+	`
+
+	ctx, span := r.startSpan(ctx, "LockContent", count)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var refs int
+	err = r.run(ctx, func(db dbtx) error {
+		if _, err := db.exec(ctx, lock, tenantID, checksum); err != nil {
+			return err
+		}
+		return db.queryRow(ctx, count, tenantID, checksum).Scan(&refs)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock attachment content: %w", r.translate(ctx, "LockContent", err))
+	}
+
+	return refs, nil
+}
@@ -6,42 +6,223 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/metrics"
 	_ "github.com/lib/pq"
 )
 
 type postgresCarRepository struct {
-	db *sql.DB
+	store
 }
 
-func NewPostgresCarRepository(db *sql.DB) *postgresCarRepository {
-	return &postgresCarRepository{db: db}
+func NewPostgresCarRepository(db *sql.DB, cfg Config) *postgresCarRepository {
+	return &postgresCarRepository{store{db: sqlConn{db}, begin: beginSQL(db), cfg: cfg, table: "cars"}}
 }
 
-func (r *postgresCarRepository) Create(ctx context.Context, car domain.Car) (*domain.Car, error) {
-	query := `
-		INSERT INTO cars (id, make, model, year, price)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, make, model, year, price
-	`
+func NewPgxCarRepository(pool *pgxpool.Pool, cfg Config) *postgresCarRepository {
+	return &postgresCarRepository{store{db: pgxConn{pool}, begin: beginPgx(pool), cfg: cfg, table: "cars"}}
+}
 
-	err := r.db.QueryRowContext(ctx, query,
-		car.ID,
-		car.Make,
-		car.Model,
-		car.Year,
-		car.Price,
-	).Scan(
+// duplicateCarKey and duplicateVINKey are how Postgres names the key columns
+// in the detail of a unique violation.
+const (
+	duplicateCarKey = "tenant_id, id"
+	duplicateVINKey = "tenant_id, vin"
+)
+
+// carColumns is the column list scanCar expects, in order, selected from
+// carFrom. The listed price is the old price of the first recorded change,
+// which is the price the car was listed at; it is NULL while the price has
+// never changed. reserved_until is the expiry of the active reservation.
+// A car whose reservation has lapsed reads as available before the sweeper
+// ends the reservation.
+var carColumns = selectCarColumns(
+	`CASE WHEN cars.status = 'reserved' AND reservation.expires_at <= now() THEN 'available' ELSE cars.status END`,
+	`CASE WHEN reservation.expires_at > now() THEN reservation.expires_at END`)
+
+// lockedCarColumns is carColumns with the status as stored, for the services
+// that lock a car to change it and end a lapsed reservation themselves.
+var lockedCarColumns = selectCarColumns("cars.status", "reservation.expires_at")
+
+func selectCarColumns(status, reservedUntil string) string {
+	return `id, make, model, year, price, currency, vin,
+	mileage, condition, fuel_type, transmission, body_type, doors, engine_displacement, color,
+	dealer_id, ` + status + ` AS status, created_at, updated_at,
+	listed.old_price, listed.old_currency, ` + reservedUntil + ` AS reserved_until`
+}
+
+// carFrom joins cars with the rows carColumns reads from other tables.
+const carFrom = `cars
+	LEFT JOIN LATERAL (
+		SELECT h.old_price, h.old_currency FROM car_price_history h
+		WHERE h.tenant_id = cars.tenant_id AND h.car_id = cars.id
+		ORDER BY h.id LIMIT 1
+	) listed ON true
+	LEFT JOIN LATERAL (
+		SELECT r.expires_at FROM car_reservations r
+		WHERE r.tenant_id = cars.tenant_id AND r.car_id = cars.id AND r.status = 'active'
+	) reservation ON true`
+
+// returningCar selects carColumns for the rows written by statement, an
+// INSERT or UPDATE of cars, since RETURNING cannot join. The written rows
+// stand in for the cars table, and the joins see the other tables as they
+// were before the statement.
+func returningCar(statement string) string {
+	return `
+		WITH cars AS (` + statement + `
+		RETURNING *)
+		SELECT ` + carColumns + `
+		FROM ` + carFrom
+}
+
+// carValueColumns are the columns written by inserts and updates.
+const carValueColumns = `make, model, year, price, currency, vin,
+	mileage, condition, fuel_type, transmission, body_type, doors, engine_displacement, color,
+	dealer_id, status`
+
+func scanCar(row row, car *domain.Car) error {
+	var (
+		vin, color                                  sql.NullString
+		condition, fuelType, transmission, bodyType sql.NullString
+		doors, engineDisplacement, dealerID         sql.NullInt64
+		originalAmount                              sql.NullInt64
+		originalCurrency                            sql.NullString
+		status                                      string
+		reservedUntil                               sql.NullTime
+	)
+	err := row.Scan(
 		&car.ID,
 		&car.Make,
 		&car.Model,
 		&car.Year,
-		&car.Price,
+		&car.Price.Amount,
+		&car.Price.Currency,
+		&vin,
+		&car.Mileage,
+		&condition,
+		&fuelType,
+		&transmission,
+		&bodyType,
+		&doors,
+		&engineDisplacement,
+		&color,
+		&dealerID,
+		&status,
+		&car.CreatedAt,
+		&car.UpdatedAt,
+		&originalAmount,
+		&originalCurrency,
+		&reservedUntil,
 	)
+	car.VIN = vin.String
+	car.Condition = domain.Condition(condition.String)
+	car.FuelType = domain.FuelType(fuelType.String)
+	car.Transmission = domain.Transmission(transmission.String)
+	car.BodyType = domain.BodyType(bodyType.String)
+	car.Doors = int(doors.Int64)
+	car.EngineDisplacement = int(engineDisplacement.Int64)
+	car.Color = color.String
+	car.DealerID = dealerID.Int64
+	car.Status = domain.CarStatus(status)
+	car.ReservedUntil = nil
+	if reservedUntil.Valid {
+		car.ReservedUntil = &reservedUntil.Time
+	}
+	car.OriginalPrice, car.DiscountPercent = nil, nil
+	if originalAmount.Valid {
+		car.SetOriginalPrice(domain.Money{Amount: originalAmount.Int64, Currency: originalCurrency.String})
+	}
+	return err
+}
+
+// nullString stores empty optional strings as NULL, so that unique indexes
+// ignore them.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullInt stores unknown optional numbers as NULL.
+func nullInt(n int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(n), Valid: n != 0}
+}
+
+// carStatus defaults the status of cars that were built without one, such as
+// those passed to Create, to available.
+func carStatus(s domain.CarStatus) string {
+	if s == "" {
+		return string(domain.StatusAvailable)
+	}
+	return string(s)
+}
+
+// carValues returns the writable columns of car in the order of
+// carValueColumns.
+func carValues(car domain.Car) []any {
+	return []any{
+		car.Make,
+		car.Model,
+		car.Year,
+		car.Price.Amount,
+		car.Price.Currency,
+		nullString(car.VIN),
+		car.Mileage,
+		nullString(string(car.Condition)),
+		nullString(string(car.FuelType)),
+		nullString(string(car.Transmission)),
+		nullString(string(car.BodyType)),
+		nullInt(car.Doors),
+		nullInt(car.EngineDisplacement),
+		nullString(car.Color),
+		sql.NullInt64{Int64: car.DealerID, Valid: car.DealerID != 0},
+		carStatus(car.Status),
+	}
+}
+
+// dealerForeignKey ties cars to the dealer they are kept at.
+const dealerForeignKey = "cars_tenant_id_dealer_id_fkey"
+
+// duplicateError replaces unique violations of the business keys, and
+// references to a missing dealer, with the matching domain error.
+func duplicateError(err error) error {
+	switch {
+	case isForeignKeyViolationOf(err, dealerForeignKey):
+		return domain.ErrDealerNotFound
+	case isUniqueViolationOn(err, duplicateCarKey):
+		return domain.ErrDuplicateCarID
+	case isUniqueViolationOn(err, duplicateVINKey):
+		return domain.ErrDuplicateVIN
+	}
+	return err
+}
+
+func (r *postgresCarRepository) Create(ctx context.Context, car domain.Car) (*domain.Car, error) {
+	defer metrics.ObserveQuery("cars", "Create")()
+
+	query := returningCar(`
+		INSERT INTO cars (tenant_id, id, ` + carValueColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`)
+
+	ctx, span := r.startSpan(ctx, "Create", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err = r.run(ctx, func(db dbtx) error {
+		args := append([]any{tenantID, car.ID}, carValues(car)...)
+		return scanCar(db.queryRow(ctx, query, args...), &car)
+	})
 
 	if err != nil {
-		if err.Error() == "pq: duplicate key value violates unique constraint \"cars_pkey\"" {
-			return nil, domain.ErrDuplicateCarID
+		err = duplicateError(r.translate(ctx, "Create", err))
+		if errors.Is(err, domain.ErrDuplicateCarID) || errors.Is(err, domain.ErrDuplicateVIN) {
+			return nil, err
 		}
 		return nil, fmt.Errorf("failed to create car: %w", err)
 	}
@@ -49,95 +230,341 @@ func (r *postgresCarRepository) Create(ctx context.Context, car domain.Car) (*do
 	return &car, nil
 }
 
+func (r *postgresCarRepository) CreateBatch(ctx context.Context, cars []domain.Car) ([]domain.Car, error) {
+	defer metrics.ObserveQuery("cars", "CreateBatch")()
+
+	query := returningCar(`
+		INSERT INTO cars (tenant_id, id, ` + carValueColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`)
+
+	ctx, span := r.startSpan(ctx, "CreateBatch", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	argsList := make([][]any, len(cars))
+	for i, car := range cars {
+		argsList[i] = append([]any{tenantID, car.ID}, carValues(car)...)
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var created []domain.Car
+	err = r.run(ctx, func(db dbtx) error {
+		created = make([]domain.Car, 0, len(cars))
+		return db.batch(ctx, query, argsList, func(row row) error {
+			var car domain.Car
+			if err := scanCar(row, &car); err != nil {
+				err = duplicateError(translateError(err))
+				return fmt.Errorf("car %d: %w", len(created), err)
+			}
+			created = append(created, car)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cars: %w", r.translate(ctx, "CreateBatch", err))
+	}
+
+	return created, nil
+}
+
 func (r *postgresCarRepository) GetByID(ctx context.Context, id string) (*domain.Car, error) {
+	defer metrics.ObserveQuery("cars", "GetByID")()
+
 	query := `
-		SELECT id, make, model, year, price
-		FROM cars
-		WHERE id = $1
+		SELECT ` + carColumns + `
+		FROM ` + carFrom + `
+		WHERE tenant_id = $1 AND id = $2
 	`
 
+	ctx, span := r.startSpan(ctx, "GetByID", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var car domain.Car
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&car.ID,
-		&car.Make,
-		&car.Model,
-		&car.Year,
-		&car.Price,
-	)
+	err = r.retryRead(ctx, func(ctx context.Context) error {
+		return r.run(ctx, func(db dbtx) error {
+			return scanCar(db.queryRow(ctx, query, tenantID, id), &car)
+		})
+	})
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, domain.ErrCarNotFound
 		}
-		return nil, fmt.Errorf("failed to get car: %w", err)
+		return nil, fmt.Errorf("failed to get car: %w", r.translate(ctx, "GetByID", err))
 	}
 
 	return &car, nil
 }
 
-func (r *postgresCarRepository) GetAll(ctx context.Context, limit, offset int) ([]domain.Car, error) {
+func (r *postgresCarRepository) GetByVIN(ctx context.Context, vin string) (*domain.Car, error) {
+	defer metrics.ObserveQuery("cars", "GetByVIN")()
+
 	query := `
-		SELECT id, make, model, year, price
-		FROM cars
-		ORDER BY id
-		LIMIT $1 OFFSET $2
+		SELECT ` + carColumns + `
+		FROM ` + carFrom + `
+		WHERE tenant_id = $1 AND vin = $2
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	ctx, span := r.startSpan(ctx, "GetByVIN", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get cars: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
-	var cars []domain.Car
-	for rows.Next() {
-		var car domain.Car
-		if err := rows.Scan(
-			&car.ID,
-			&car.Make,
-			&car.Model,
-			&car.Year,
-			&car.Price,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan car: %w", err)
+	var car domain.Car
+	err = r.retryRead(ctx, func(ctx context.Context) error {
+		return r.run(ctx, func(db dbtx) error {
+			return scanCar(db.queryRow(ctx, query, tenantID, vin), &car)
+		})
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrCarNotFound
 		}
-		cars = append(cars, car)
+		return nil, fmt.Errorf("failed to get car by VIN: %w", r.translate(ctx, "GetByVIN", err))
+	}
+
+	return &car, nil
+}
+
+func (r *postgresCarRepository) GetByIDForUpdate(ctx context.Context, id string) (*domain.Car, error) {
+	defer metrics.ObserveQuery("cars", "GetByIDForUpdate")()
+
+	query := `
+		SELECT ` + lockedCarColumns + `
+		FROM ` + carFrom + `
+		WHERE tenant_id = $1 AND id = $2
+		FOR UPDATE OF cars
+	`
+
+	ctx, span := r.startSpan(ctx, "GetByIDForUpdate", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows error: %w", err)
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var car domain.Car
+	err = r.run(ctx, func(db dbtx) error {
+		return scanCar(db.queryRow(ctx, query, tenantID, id), &car)
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrCarNotFound
+		}
+		return nil, fmt.Errorf("failed to lock car: %w", r.translate(ctx, "GetByIDForUpdate", err))
+	}
+
+	return &car, nil
+}
+
+func (r *postgresCarRepository) GetAll(ctx context.Context, filter domain.CarFilter) ([]domain.Car, error) {
+	defer metrics.ObserveQuery("cars", "GetAll")()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var args queryArgs
+	price := priceExpr(&args, filter)
+	query := `
+		SELECT ` + carColumns + `
+		FROM ` + carFrom + `
+		WHERE ` + carWhere(&args, tenantID, filter, price) + `
+		ORDER BY ` + carOrderBy(filter, price) + `
+		LIMIT ` + args.add(filter.Limit) + ` OFFSET ` + args.add(filter.Offset)
+
+	ctx, span := r.startSpan(ctx, "GetAll", query)
+	defer span.End()
+
+	var cars []domain.Car
+	err = r.retryRead(ctx, func(ctx context.Context) error {
+		return r.run(ctx, func(db dbtx) error {
+			cars = nil
+
+			rows, err := db.query(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+
+			for rows.Next() {
+				var car domain.Car
+				if err := scanCar(rows, &car); err != nil {
+					return fmt.Errorf("failed to scan car: %w", err)
+				}
+				cars = append(cars, car)
+			}
+
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("rows error: %w", err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cars: %w", r.translate(ctx, "GetAll", err))
 	}
 
 	return cars, nil
 }
 
-func (r *postgresCarRepository) Update(ctx context.Context, id string, car domain.Car) (*domain.Car, error) {
+// statsKey is the expression cars are grouped by for groupBy. Years are
+// grouped as text so that every key scans the same way.
+func statsKey(groupBy domain.StatsGroupBy) string {
+	switch groupBy {
+	case domain.StatsByMake:
+		return "make"
+	case domain.StatsByModel:
+		return "model"
+	case domain.StatsByYear:
+		return "year::text"
+	case domain.StatsByColor:
+		return "coalesce(color, '')"
+	}
+	return "''"
+}
+
+// Stats computes each group twice with GROUPING SETS: once over all its cars
+// for the count and years, and once per currency for the prices, since prices
+// in different currencies cannot be compared. The first row of a group is the
+// one over all its cars. Without a grouping an empty inventory still yields a
+// single group with a zero count.
+func (r *postgresCarRepository) Stats(ctx context.Context, filter domain.CarFilter, groupBy domain.StatsGroupBy) (*domain.CarStats, error) {
+	defer metrics.ObserveQuery("cars", "Stats")()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := statsKey(groupBy)
+	sets := "((" + key + "), (" + key + ", currency))"
+	if groupBy == "" {
+		sets = "((), (currency))"
+	}
+
+	var args queryArgs
+	price := priceExpr(&args, filter)
 	query := `
+		SELECT ` + key + ` AS key, coalesce(currency, ''), GROUPING(currency) AS all_currencies, count(*),
+			coalesce(min(year)::float8, 0), coalesce(max(year)::float8, 0), coalesce(avg(year)::float8, 0),
+			coalesce(percentile_cont(0.5) WITHIN GROUP (ORDER BY year), 0),
+			coalesce(min(price)::float8, 0), coalesce(max(price)::float8, 0), coalesce(avg(price)::float8, 0),
+			coalesce(percentile_cont(0.5) WITHIN GROUP (ORDER BY price), 0)
+		FROM cars
+		WHERE ` + carWhere(&args, tenantID, filter, price) + `
+		GROUP BY GROUPING SETS ` + sets + `
+		ORDER BY key, all_currencies DESC, currency`
+
+	ctx, span := r.startSpan(ctx, "Stats", query)
+	defer span.End()
+
+	var stats domain.CarStats
+	err = r.retryRead(ctx, func(ctx context.Context) error {
+		return r.run(ctx, func(db dbtx) error {
+			stats = domain.CarStats{GroupBy: groupBy, Groups: []domain.StatsGroup{}}
+
+			rows, err := db.query(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+
+			for rows.Next() {
+				var (
+					key, currency string
+					allCurrencies int
+					count         int64
+					year, price   domain.Aggregate
+				)
+				err := rows.Scan(&key, &currency, &allCurrencies, &count,
+					&year.Min, &year.Max, &year.Avg, &year.Median,
+					&price.Min, &price.Max, &price.Avg, &price.Median)
+				if err != nil {
+					return fmt.Errorf("failed to scan stats: %w", err)
+				}
+
+				if allCurrencies == 1 {
+					stats.Groups = append(stats.Groups, domain.StatsGroup{Key: key, Count: count, Year: year, Prices: []domain.PriceStats{}})
+					continue
+				}
+				if len(stats.Groups) == 0 {
+					return errors.New("stats rows out of order")
+				}
+				group := &stats.Groups[len(stats.Groups)-1]
+				group.Prices = append(group.Prices, domain.PriceStats{Currency: currency, Count: count, Aggregate: price})
+			}
+
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("rows error: %w", err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get car stats: %w", r.translate(ctx, "Stats", err))
+	}
+
+	return &stats, nil
+}
+
+func (r *postgresCarRepository) Update(ctx context.Context, id string, car domain.Car) (*domain.Car, error) {
+	defer metrics.ObserveQuery("cars", "Update")()
+
+	query := returningCar(`
 		UPDATE cars
-		SET make = $1, model = $2, year = $3, price = $4
-		WHERE id = $5
-		RETURNING id, make, model, year, price
-	`
+		SET make = $1, model = $2, year = $3, price = $4, currency = $5, vin = $6,
+			mileage = $7, condition = $8, fuel_type = $9, transmission = $10, body_type = $11,
+			doors = $12, engine_displacement = $13, color = $14, dealer_id = $15, status = $16, updated_at = now()
+		WHERE tenant_id = $17 AND id = $18`)
+
+	ctx, span := r.startSpan(ctx, "Update", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
 	var updatedCar domain.Car
-	err := r.db.QueryRowContext(ctx, query,
-		car.Make,
-		car.Model,
-		car.Year,
-		car.Price,
-		id,
-	).Scan(
-		&updatedCar.ID,
-		&updatedCar.Make,
-		&updatedCar.Model,
-		&updatedCar.Year,
-		&updatedCar.Price,
-	)
+	err = r.run(ctx, func(db dbtx) error {
+		args := append(carValues(car), tenantID, id)
+		return scanCar(db.queryRow(ctx, query, args...), &updatedCar)
+	})
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, domain.ErrCarNotFound
 		}
+		err = duplicateError(r.translate(ctx, "Update", err))
+		if errors.Is(err, domain.ErrDuplicateVIN) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to update car: %w", err)
 	}
 
@@ -145,19 +572,31 @@ func (r *postgresCarRepository) Update(ctx context.Context, id string, car domai
 }
 
 func (r *postgresCarRepository) Delete(ctx context.Context, id string) error {
+	defer metrics.ObserveQuery("cars", "Delete")()
+
 	query := `
 		DELETE FROM cars
-		WHERE id = $1
+		WHERE tenant_id = $1 AND id = $2
 	`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	ctx, span := r.startSpan(ctx, "Delete", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to delete car: %w", err)
+		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var rowsAffected int64
+	err = r.run(ctx, func(db dbtx) (err error) {
+		rowsAffected, err = db.exec(ctx, query, tenantID, id)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to delete car: %w", r.translate(ctx, "Delete", err))
 	}
 
 	if rowsAffected == 0 {
@@ -0,0 +1,427 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/metrics"
+	"github.com/kefir4iick/crud/internal/repository"
+)
+
+// catalogRepository keeps the reference catalog in the makes and models
+// tables and the names of both in make_names and model_names. The catalog is
+// not tenant scoped, so its statements do not go through run.
+type catalogRepository struct {
+	store
+}
+
+func NewCatalogRepository(db *sql.DB, cfg Config) repository.CatalogRepository {
+	return &catalogRepository{store{db: sqlConn{db}, begin: beginSQL(db), cfg: cfg, table: "catalog"}}
+}
+
+func NewPgxCatalogRepository(pool *pgxpool.Pool, cfg Config) repository.CatalogRepository {
+	return &catalogRepository{store{db: pgxConn{pool}, begin: beginPgx(pool), cfg: cfg, table: "catalog"}}
+}
+
+const (
+	selectMakes = `
+		SELECT m.id, m.name, n.name
+		FROM makes m
+		LEFT JOIN make_names n ON n.make_id = m.id AND n.alias
+	`
+	orderMakes = ` ORDER BY m.name, m.id, n.name`
+
+	insertMakeName = `
+		INSERT INTO make_names (key, make_id, name, alias)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	selectModels = `
+		SELECT m.make_id, m.id, m.name, m.year_from, m.year_to, n.name
+		FROM models m
+		LEFT JOIN model_names n ON n.make_id = m.make_id AND n.model_id = m.id AND n.alias
+	`
+	orderModels = ` ORDER BY m.name, m.id, n.name`
+
+	insertModelName = `
+		INSERT INTO model_names (make_id, key, model_id, name, alias)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+)
+
+// scanMakes folds the rows of selectMakes, one per alias, into makes.
+func scanMakes(rows rows) ([]domain.Make, error) {
+	makes := []domain.Make{}
+	for rows.Next() {
+		var (
+			m     domain.Make
+			alias sql.NullString
+		)
+		if err := rows.Scan(&m.ID, &m.Name, &alias); err != nil {
+			return nil, fmt.Errorf("failed to scan make: %w", err)
+		}
+		if len(makes) == 0 || makes[len(makes)-1].ID != m.ID {
+			m.Aliases = []string{}
+			makes = append(makes, m)
+		}
+		if alias.Valid {
+			last := &makes[len(makes)-1]
+			last.Aliases = append(last.Aliases, alias.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return makes, nil
+}
+
+// scanModels folds the rows of selectModels, one per alias, into models.
+func scanModels(rows rows) ([]domain.Model, error) {
+	models := []domain.Model{}
+	for rows.Next() {
+		var (
+			m                domain.Model
+			yearFrom, yearTo sql.NullInt64
+			alias            sql.NullString
+		)
+		if err := rows.Scan(&m.MakeID, &m.ID, &m.Name, &yearFrom, &yearTo, &alias); err != nil {
+			return nil, fmt.Errorf("failed to scan model: %w", err)
+		}
+		if len(models) == 0 || models[len(models)-1].ID != m.ID {
+			m.YearFrom, m.YearTo = int(yearFrom.Int64), int(yearTo.Int64)
+			m.Aliases = []string{}
+			models = append(models, m)
+		}
+		if alias.Valid {
+			last := &models[len(models)-1]
+			last.Aliases = append(last.Aliases, alias.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return models, nil
+}
+
+// insertNames runs insert for the name and for every alias. Its arguments are
+// ids followed by the key, the name and whether it is an alias.
+func insertNames(ctx context.Context, db dbtx, insert string, ids []any, name string, aliases []string) error {
+	names := append([]string{name}, aliases...)
+	for i, n := range names {
+		args := append(append([]any{}, ids...), domain.NameKey(n), n, i > 0)
+		if _, err := db.exec(ctx, insert, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// duplicate replaces a unique violation with the domain error dup.
+func duplicate(err, dup error) error {
+	if errors.Is(err, domain.ErrUniqueViolation) {
+		return dup
+	}
+	return err
+}
+
+func (r *catalogRepository) queryMakes(ctx context.Context, method, where string, args ...any) ([]domain.Make, error) {
+	defer metrics.ObserveQuery("makes", method)()
+
+	query := selectMakes + where + orderMakes
+
+	ctx, span := r.startSpan(ctx, method, query)
+	defer span.End()
+
+	var makes []domain.Make
+	err := r.retryRead(ctx, func(ctx context.Context) error {
+		rows, err := r.db.query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		makes, err = scanMakes(rows)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get makes: %w", r.translate(ctx, method, err))
+	}
+
+	return makes, nil
+}
+
+func (r *catalogRepository) queryMake(ctx context.Context, method, where string, args ...any) (*domain.Make, error) {
+	makes, err := r.queryMakes(ctx, method, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(makes) == 0 {
+		return nil, domain.ErrMakeNotFound
+	}
+	return &makes[0], nil
+}
+
+func (r *catalogRepository) CreateMake(ctx context.Context, m domain.Make) (*domain.Make, error) {
+	defer metrics.ObserveQuery("makes", "CreateMake")()
+
+	query := `INSERT INTO makes (id, name) VALUES ($1, $2)`
+
+	ctx, span := r.startSpan(ctx, "CreateMake", query)
+	defer span.End()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err := r.transact(ctx, func(db dbtx) error {
+		if _, err := db.exec(ctx, query, m.ID, m.Name); err != nil {
+			return err
+		}
+		return insertNames(ctx, db, insertMakeName, []any{}, m.Name, m.Aliases)
+	})
+	if err != nil {
+		err = duplicate(r.translate(ctx, "CreateMake", err), domain.ErrDuplicateMake)
+		if errors.Is(err, domain.ErrDuplicateMake) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create make: %w", err)
+	}
+
+	return &m, nil
+}
+
+func (r *catalogRepository) GetMake(ctx context.Context, id string) (*domain.Make, error) {
+	return r.queryMake(ctx, "GetMake", "WHERE m.id = $1", id)
+}
+
+func (r *catalogRepository) ListMakes(ctx context.Context) ([]domain.Make, error) {
+	return r.queryMakes(ctx, "ListMakes", "")
+}
+
+func (r *catalogRepository) FindMake(ctx context.Context, name string) (*domain.Make, error) {
+	return r.queryMake(ctx, "FindMake", "WHERE m.id = (SELECT make_id FROM make_names WHERE key = $1)", domain.NameKey(name))
+}
+
+func (r *catalogRepository) UpdateMake(ctx context.Context, id string, m domain.Make) (*domain.Make, error) {
+	defer metrics.ObserveQuery("makes", "UpdateMake")()
+
+	query := `UPDATE makes SET name = $1 WHERE id = $2`
+
+	ctx, span := r.startSpan(ctx, "UpdateMake", query)
+	defer span.End()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	m.ID = id
+	err := r.transact(ctx, func(db dbtx) error {
+		updated, err := db.exec(ctx, query, m.Name, id)
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return domain.ErrMakeNotFound
+		}
+		if _, err := db.exec(ctx, `DELETE FROM make_names WHERE make_id = $1`, id); err != nil {
+			return err
+		}
+		return insertNames(ctx, db, insertMakeName, []any{}, m.Name, m.Aliases)
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrMakeNotFound) {
+			return nil, err
+		}
+		err = duplicate(r.translate(ctx, "UpdateMake", err), domain.ErrDuplicateMake)
+		if errors.Is(err, domain.ErrDuplicateMake) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update make: %w", err)
+	}
+
+	return &m, nil
+}
+
+func (r *catalogRepository) DeleteMake(ctx context.Context, id string) error {
+	defer metrics.ObserveQuery("makes", "DeleteMake")()
+
+	query := `DELETE FROM makes WHERE id = $1`
+
+	ctx, span := r.startSpan(ctx, "DeleteMake", query)
+	defer span.End()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	deleted, err := r.db.exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete make: %w", r.translate(ctx, "DeleteMake", err))
+	}
+	if deleted == 0 {
+		return domain.ErrMakeNotFound
+	}
+
+	return nil
+}
+
+func (r *catalogRepository) queryModels(ctx context.Context, method, where string, args ...any) ([]domain.Model, error) {
+	defer metrics.ObserveQuery("models", method)()
+
+	query := selectModels + where + orderModels
+
+	ctx, span := r.startSpan(ctx, method, query)
+	defer span.End()
+
+	var models []domain.Model
+	err := r.retryRead(ctx, func(ctx context.Context) error {
+		rows, err := r.db.query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		models, err = scanModels(rows)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get models: %w", r.translate(ctx, method, err))
+	}
+
+	return models, nil
+}
+
+func (r *catalogRepository) queryModel(ctx context.Context, method, where string, args ...any) (*domain.Model, error) {
+	models, err := r.queryModels(ctx, method, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(models) == 0 {
+		return nil, domain.ErrModelNotFound
+	}
+	return &models[0], nil
+}
+
+func (r *catalogRepository) CreateModel(ctx context.Context, m domain.Model) (*domain.Model, error) {
+	defer metrics.ObserveQuery("models", "CreateModel")()
+
+	query := `
+		INSERT INTO models (make_id, id, name, year_from, year_to)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	ctx, span := r.startSpan(ctx, "CreateModel", query)
+	defer span.End()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err := r.transact(ctx, func(db dbtx) error {
+		// Locks the make against a concurrent delete and turns a missing
+		// make into ErrMakeNotFound rather than a foreign key violation.
+		var one int
+		err := db.queryRow(ctx, `SELECT 1 FROM makes WHERE id = $1 FOR KEY SHARE`, m.MakeID).Scan(&one)
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrMakeNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := db.exec(ctx, query, m.MakeID, m.ID, m.Name, nullInt(m.YearFrom), nullInt(m.YearTo)); err != nil {
+			return err
+		}
+		return insertNames(ctx, db, insertModelName, []any{m.MakeID}, m.Name, m.Aliases)
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrMakeNotFound) {
+			return nil, err
+		}
+		err = duplicate(r.translate(ctx, "CreateModel", err), domain.ErrDuplicateModel)
+		if errors.Is(err, domain.ErrDuplicateModel) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create model: %w", err)
+	}
+
+	return &m, nil
+}
+
+func (r *catalogRepository) GetModel(ctx context.Context, makeID, id string) (*domain.Model, error) {
+	return r.queryModel(ctx, "GetModel", "WHERE m.make_id = $1 AND m.id = $2", makeID, id)
+}
+
+func (r *catalogRepository) ListModels(ctx context.Context, makeID string) ([]domain.Model, error) {
+	return r.queryModels(ctx, "ListModels", "WHERE m.make_id = $1", makeID)
+}
+
+func (r *catalogRepository) FindModel(ctx context.Context, makeID, name string) (*domain.Model, error) {
+	where := "WHERE m.make_id = $1 AND m.id = (SELECT model_id FROM model_names WHERE make_id = $1 AND key = $2)"
+	return r.queryModel(ctx, "FindModel", where, makeID, domain.NameKey(name))
+}
+
+func (r *catalogRepository) UpdateModel(ctx context.Context, makeID, id string, m domain.Model) (*domain.Model, error) {
+	defer metrics.ObserveQuery("models", "UpdateModel")()
+
+	query := `
+		UPDATE models
+		SET name = $1, year_from = $2, year_to = $3
+		WHERE make_id = $4 AND id = $5
+	`
+
+	ctx, span := r.startSpan(ctx, "UpdateModel", query)
+	defer span.End()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	m.MakeID, m.ID = makeID, id
+	err := r.transact(ctx, func(db dbtx) error {
+		updated, err := db.exec(ctx, query, m.Name, nullInt(m.YearFrom), nullInt(m.YearTo), makeID, id)
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return domain.ErrModelNotFound
+		}
+		if _, err := db.exec(ctx, `DELETE FROM model_names WHERE make_id = $1 AND model_id = $2`, makeID, id); err != nil {
+			return err
+		}
+		return insertNames(ctx, db, insertModelName, []any{makeID}, m.Name, m.Aliases)
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrModelNotFound) {
+			return nil, err
+		}
+		err = duplicate(r.translate(ctx, "UpdateModel", err), domain.ErrDuplicateModel)
+		if errors.Is(err, domain.ErrDuplicateModel) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update model: %w", err)
+	}
+
+	return &m, nil
+}
+
+func (r *catalogRepository) DeleteModel(ctx context.Context, makeID, id string) error {
+	defer metrics.ObserveQuery("models", "DeleteModel")()
+
+	query := `DELETE FROM models WHERE make_id = $1 AND id = $2`
+
+	ctx, span := r.startSpan(ctx, "DeleteModel", query)
+	defer span.End()
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	deleted, err := r.db.exec(ctx, query, makeID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete model: %w", r.translate(ctx, "DeleteModel", err))
+	}
+	if deleted == 0 {
+		return domain.ErrModelNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/kefir4iick/crud/internal/repository"
+	"github.com/kefir4iick/crud/internal/repository/postgres"
+	"github.com/kefir4iick/crud/internal/repository/repotest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogRepository(t *testing.T) {
+	db := openDB(t)
+	pool := openPool(t)
+	cfg := postgres.DefaultConfig()
+
+	newRepos := map[string]func() repository.CatalogRepository{
+		"pq":  func() repository.CatalogRepository { return postgres.NewCatalogRepository(db, cfg) },
+		"pgx": func() repository.CatalogRepository { return postgres.NewPgxCatalogRepository(pool, cfg) },
+	}
+	for name, newRepo := range newRepos {
+		t.Run(name, func(t *testing.T) {
+			repotest.RunCatalogRepositoryTests(t, func(t *testing.T) repository.CatalogRepository {
+				_, err := db.Exec("TRUNCATE makes CASCADE")
+				require.NoError(t, err)
+				return newRepo()
+			})
+		})
+	}
+}
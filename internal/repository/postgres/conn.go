@@ -0,0 +1,235 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// dbtx is what the repositories need from a connection. It has one
+// implementation on top of database/sql (lib/pq) and one on top of pgx, so the
+// same queries run on either driver; sql.ErrNoRows is reported by both.
+type dbtx interface {
+	exec(ctx context.Context, query string, args ...any) (int64, error)
+	queryRow(ctx context.Context, query string, args ...any) row
+	query(ctx context.Context, query string, args ...any) (rows, error)
+	// batch runs query once per argument list as a single atomic unit and
+	// hands every result row to scan in order.
+	batch(ctx context.Context, query string, argsList [][]any, scan func(row) error) error
+}
+
+// txConn is a dbtx bound to an open transaction.
+type txConn interface {
+	dbtx
+	commit(ctx context.Context) error
+	rollback(ctx context.Context) error
+}
+
+type row interface {
+	Scan(dest ...any) error
+}
+
+type rows interface {
+	row
+	Next() bool
+	Err() error
+	Close()
+}
+
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+type sqlConn struct {
+	q sqlQuerier
+}
+
+func (c sqlConn) exec(ctx context.Context, query string, args ...any) (int64, error) {
+	result, err := c.q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (c sqlConn) queryRow(ctx context.Context, query string, args ...any) row {
+	return c.q.QueryRowContext(ctx, query, args...)
+}
+
+func (c sqlConn) query(ctx context.Context, query string, args ...any) (rows, error) {
+	r, err := c.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return sqlRows{r}, nil
+}
+
+func (c sqlConn) batch(ctx context.Context, query string, argsList [][]any, scan func(row) error) error {
+	db, ok := c.q.(*sql.DB)
+	if !ok {
+		return runPrepared(ctx, c.q, query, argsList, scan)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := runPrepared(ctx, tx, query, argsList, scan); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func runPrepared(ctx context.Context, q sqlQuerier, query string, argsList [][]any, scan func(row) error) error {
+	stmt, err := q.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, args := range argsList {
+		if err := scan(stmt.QueryRowContext(ctx, args...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type sqlTx struct {
+	sqlConn
+	tx *sql.Tx
+}
+
+func beginSQL(db *sql.DB) func(ctx context.Context) (txConn, error) {
+	return func(ctx context.Context) (txConn, error) {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return sqlTx{sqlConn: sqlConn{tx}, tx: tx}, nil
+	}
+}
+
+func (t sqlTx) commit(context.Context) error {
+	return t.tx.Commit()
+}
+
+func (t sqlTx) rollback(context.Context) error {
+	return t.tx.Rollback()
+}
+
+type sqlRows struct {
+	*sql.Rows
+}
+
+func (r sqlRows) Close() {
+	r.Rows.Close()
+}
+
+// pgxQuerier is satisfied by *pgxpool.Pool, *pgx.Conn and pgx.Tx. pgx keeps a
+// per-connection cache of prepared statements, so repeated queries are parsed
+// and planned once.
+type pgxQuerier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+type pgxConn struct {
+	q pgxQuerier
+}
+
+func (c pgxConn) exec(ctx context.Context, query string, args ...any) (int64, error) {
+	tag, err := c.q.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (c pgxConn) queryRow(ctx context.Context, query string, args ...any) row {
+	return pgxRow{c.q.QueryRow(ctx, query, args...)}
+}
+
+func (c pgxConn) query(ctx context.Context, query string, args ...any) (rows, error) {
+	r, err := c.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// batch pipelines every statement in one round trip. Outside an explicit
+// transaction Postgres runs the whole pipeline as one implicit transaction,
+// so a failure rolls back the statements before it as well.
+func (c pgxConn) batch(ctx context.Context, query string, argsList [][]any, scan func(row) error) (err error) {
+	b := &pgx.Batch{}
+	for _, args := range argsList {
+		b.Queue(query, args...)
+	}
+
+	results := c.q.SendBatch(ctx, b)
+	defer func() {
+		if closeErr := results.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	for range argsList {
+		if err := scan(pgxRow{results.QueryRow()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type pgxTx struct {
+	pgxConn
+	tx pgx.Tx
+}
+
+func beginPgx(pool *pgxpool.Pool) func(ctx context.Context) (txConn, error) {
+	return func(ctx context.Context) (txConn, error) {
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return pgxTx{pgxConn: pgxConn{tx}, tx: tx}, nil
+	}
+}
+
+func (t pgxTx) commit(ctx context.Context) error {
+	return t.tx.Commit(ctx)
+}
+
+func (t pgxTx) rollback(ctx context.Context) error {
+	return t.tx.Rollback(ctx)
+}
+
+type pgxRow struct {
+	pgx.Row
+}
+
+func (r pgxRow) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+var (
+	_ dbtx   = sqlConn{}
+	_ dbtx   = pgxConn{}
+	_ txConn = sqlTx{}
+	_ txConn = pgxTx{}
+)
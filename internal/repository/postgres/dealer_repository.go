@@ -0,0 +1,237 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/metrics"
+	"github.com/kefir4iick/crud/internal/repository"
+)
+
+type dealerRepository struct {
+	store
+}
+
+func NewDealerRepository(db *sql.DB, cfg Config) repository.DealerRepository {
+	return &dealerRepository{store{db: sqlConn{db}, begin: beginSQL(db), cfg: cfg, table: "dealers"}}
+}
+
+func NewPgxDealerRepository(pool *pgxpool.Pool, cfg Config) repository.DealerRepository {
+	return &dealerRepository{store{db: pgxConn{pool}, begin: beginPgx(pool), cfg: cfg, table: "dealers"}}
+}
+
+const dealerColumns = `id, name, address, latitude, longitude, created_at, updated_at`
+
+func scanDealer(row row, d *domain.Dealer) error {
+	var (
+		address             sql.NullString
+		latitude, longitude sql.NullFloat64
+	)
+	err := row.Scan(&d.ID, &d.Name, &address, &latitude, &longitude, &d.CreatedAt, &d.UpdatedAt)
+	d.Address = address.String
+	d.Location = nil
+	if latitude.Valid && longitude.Valid {
+		d.Location = &domain.Coordinates{Latitude: latitude.Float64, Longitude: longitude.Float64}
+	}
+	return err
+}
+
+// dealerValues returns name, address, latitude and longitude of d.
+func dealerValues(d domain.Dealer) []any {
+	var latitude, longitude sql.NullFloat64
+	if d.Location != nil {
+		latitude = sql.NullFloat64{Float64: d.Location.Latitude, Valid: true}
+		longitude = sql.NullFloat64{Float64: d.Location.Longitude, Valid: true}
+	}
+	return []any{d.Name, nullString(d.Address), latitude, longitude}
+}
+
+func (r *dealerRepository) Create(ctx context.Context, dealer domain.Dealer) (*domain.Dealer, error) {
+	defer metrics.ObserveQuery("dealers", "Create")()
+
+	query := `
+		INSERT INTO dealers (tenant_id, name, address, latitude, longitude)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + dealerColumns
+
+	ctx, span := r.startSpan(ctx, "Create", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err = r.run(ctx, func(db dbtx) error {
+		args := append([]any{tenantID}, dealerValues(dealer)...)
+		return scanDealer(db.queryRow(ctx, query, args...), &dealer)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dealer: %w", r.translate(ctx, "Create", err))
+	}
+
+	return &dealer, nil
+}
+
+func (r *dealerRepository) GetByID(ctx context.Context, id int64) (*domain.Dealer, error) {
+	defer metrics.ObserveQuery("dealers", "GetByID")()
+
+	query := `
+		SELECT ` + dealerColumns + `
+		FROM dealers
+		WHERE tenant_id = $1 AND id = $2
+	`
+
+	ctx, span := r.startSpan(ctx, "GetByID", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var dealer domain.Dealer
+	err = r.retryRead(ctx, func(ctx context.Context) error {
+		return r.run(ctx, func(db dbtx) error {
+			return scanDealer(db.queryRow(ctx, query, tenantID, id), &dealer)
+		})
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrDealerNotFound
+		}
+		return nil, fmt.Errorf("failed to get dealer: %w", r.translate(ctx, "GetByID", err))
+	}
+
+	return &dealer, nil
+}
+
+func (r *dealerRepository) GetAll(ctx context.Context) ([]domain.Dealer, error) {
+	defer metrics.ObserveQuery("dealers", "GetAll")()
+
+	query := `
+		SELECT ` + dealerColumns + `
+		FROM dealers
+		WHERE tenant_id = $1
+		ORDER BY name, id
+	`
+
+	ctx, span := r.startSpan(ctx, "GetAll", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var dealers []domain.Dealer
+	err = r.retryRead(ctx, func(ctx context.Context) error {
+		dealers = []domain.Dealer{}
+		return r.run(ctx, func(db dbtx) error {
+			rows, err := db.query(ctx, query, tenantID)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+
+			for rows.Next() {
+				var dealer domain.Dealer
+				if err := scanDealer(rows, &dealer); err != nil {
+					return fmt.Errorf("failed to scan dealer: %w", err)
+				}
+				dealers = append(dealers, dealer)
+			}
+
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("rows error: %w", err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dealers: %w", r.translate(ctx, "GetAll", err))
+	}
+
+	return dealers, nil
+}
+
+func (r *dealerRepository) Update(ctx context.Context, id int64, dealer domain.Dealer) (*domain.Dealer, error) {
+	defer metrics.ObserveQuery("dealers", "Update")()
+
+	query := `
+		UPDATE dealers
+		SET name = $1, address = $2, latitude = $3, longitude = $4, updated_at = now()
+		WHERE tenant_id = $5 AND id = $6
+		RETURNING ` + dealerColumns
+
+	ctx, span := r.startSpan(ctx, "Update", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var updated domain.Dealer
+	err = r.run(ctx, func(db dbtx) error {
+		args := append(dealerValues(dealer), tenantID, id)
+		return scanDealer(db.queryRow(ctx, query, args...), &updated)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrDealerNotFound
+		}
+		return nil, fmt.Errorf("failed to update dealer: %w", r.translate(ctx, "Update", err))
+	}
+
+	return &updated, nil
+}
+
+func (r *dealerRepository) Delete(ctx context.Context, id int64) error {
+	defer metrics.ObserveQuery("dealers", "Delete")()
+
+	query := `
+		DELETE FROM dealers
+		WHERE tenant_id = $1 AND id = $2
+	`
+
+	ctx, span := r.startSpan(ctx, "Delete", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var rowsAffected int64
+	err = r.run(ctx, func(db dbtx) (err error) {
+		rowsAffected, err = db.exec(ctx, query, tenantID, id)
+		return err
+	})
+	if err != nil {
+		err = r.translate(ctx, "Delete", err)
+		if isForeignKeyViolationOf(err, dealerForeignKey) {
+			return domain.ErrDealerHasInventory
+		}
+		return fmt.Errorf("failed to delete dealer: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return domain.ErrDealerNotFound
+	}
+
+	return nil
+}
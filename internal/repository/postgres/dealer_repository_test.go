@@ -0,0 +1,29 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/kefir4iick/crud/internal/repository"
+	"github.com/kefir4iick/crud/internal/repository/postgres"
+	"github.com/kefir4iick/crud/internal/repository/repotest"
+)
+
+func TestDealerRepository(t *testing.T) {
+	db := openDB(t)
+	pool := openPool(t)
+	cfg := postgres.DefaultConfig()
+
+	t.Run("pq", func(t *testing.T) {
+		repotest.RunDealerRepositoryTests(t, func(t *testing.T) (repository.DealerRepository, repository.CarRepository) {
+			truncate(t, db)
+			return postgres.NewDealerRepository(db, cfg), postgres.NewPostgresCarRepository(db, cfg)
+		})
+	})
+
+	t.Run("pgx", func(t *testing.T) {
+		repotest.RunDealerRepositoryTests(t, func(t *testing.T) (repository.DealerRepository, repository.CarRepository) {
+			truncate(t, db)
+			return postgres.NewPgxDealerRepository(pool, cfg), postgres.NewPgxCarRepository(pool, cfg)
+		})
+	})
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/lib/pq"
+)
+
+const (
+	codeStringTooLong        = "22001"
+	codeNotNullViolation     = "23502"
+	codeForeignKeyViolation  = "23503"
+	codeUniqueViolation      = "23505"
+	codeCheckViolation       = "23514"
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+	codeTooManyConnections   = "53300"
+	codeAdminShutdown        = "57P01"
+	codeCrashShutdown        = "57P02"
+	codeCannotConnectNow     = "57P03"
+
+	classConnectionException = "08"
+)
+
+type sqlStateError interface {
+	error
+	SQLState() string
+}
+
+func sqlState(err error) string {
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState()
+	}
+	return ""
+}
+
+// isTransient reports whether err is worth retrying: the connection dropped
+// or the server rejected the statement because of concurrent activity.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch code := sqlState(err); {
+	case code == codeSerializationFailure,
+		code == codeDeadlockDetected,
+		code == codeTooManyConnections,
+		code == codeAdminShutdown,
+		code == codeCrashShutdown,
+		code == codeCannotConnectNow,
+		strings.HasPrefix(code, classConnectionException):
+		return true
+	case code != "":
+		return false
+	}
+
+	return errors.Is(err, driver.ErrBadConn) ||
+		pgconn.SafeToRetry(err) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// isRetryableConnectError additionally treats network errors such as a
+// refused connection as retryable, which is what we see while Postgres is
+// still starting up.
+func isRetryableConnectError(err error) bool {
+	if isTransient(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+var keyDetailPattern = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// translateError maps Postgres SQLSTATE codes reported by either driver to
+// domain errors. Errors that do not come from the server, carry a code we
+// don't model, or were already translated are returned unchanged.
+func translateError(err error) error {
+	var cerr *domain.ConstraintError
+	if errors.As(err, &cerr) || errors.Is(err, domain.ErrConcurrentUpdate) {
+		return err
+	}
+
+	var column, table, constraint, detail string
+	var pqErr *pq.Error
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &pqErr):
+		column, table, constraint, detail = pqErr.Column, pqErr.Table, pqErr.Constraint, pqErr.Detail
+	case errors.As(err, &pgErr):
+		column, table, constraint, detail = pgErr.ColumnName, pgErr.TableName, pgErr.ConstraintName, pgErr.Detail
+	default:
+		return err
+	}
+
+	var kind error
+	switch sqlState(err) {
+	case codeUniqueViolation:
+		kind = domain.ErrUniqueViolation
+	case codeNotNullViolation:
+		kind = domain.ErrNotNullViolation
+	case codeForeignKeyViolation:
+		kind = domain.ErrForeignKeyViolation
+	case codeCheckViolation:
+		kind = domain.ErrCheckViolation
+	case codeStringTooLong:
+		kind = domain.ErrValueTooLong
+	case codeSerializationFailure, codeDeadlockDetected:
+		return fmt.Errorf("%w: %w", domain.ErrConcurrentUpdate, err)
+	default:
+		return err
+	}
+
+	return &domain.ConstraintError{
+		Kind:       kind,
+		Column:     constraintColumn(column, table, constraint, detail),
+		Constraint: constraint,
+		Err:        err,
+	}
+}
+
+// constraintColumn works out which column a violation refers to. Postgres
+// only fills the column field for NOT NULL violations, so for unique keys we
+// read it from the detail and for checks we rely on the default
+// "<table>_<column>_check" constraint naming.
+func constraintColumn(column, table, constraint, detail string) string {
+	if column != "" {
+		return column
+	}
+	if m := keyDetailPattern.FindStringSubmatch(detail); m != nil {
+		return m[1]
+	}
+	if table != "" && strings.HasPrefix(constraint, table+"_") && strings.HasSuffix(constraint, "_check") {
+		return strings.TrimSuffix(strings.TrimPrefix(constraint, table+"_"), "_check")
+	}
+	return ""
+}
+
+// isForeignKeyViolationOf reports whether err violates the named foreign
+// key, either by referring to a missing row or by deleting a referenced one.
+func isForeignKeyViolationOf(err error, constraint string) bool {
+	var cerr *domain.ConstraintError
+	return errors.As(err, &cerr) && errors.Is(cerr.Kind, domain.ErrForeignKeyViolation) && cerr.Constraint == constraint
+}
+
+// isUniqueViolationOf reports whether err violates the named unique
+// constraint or index.
+func isUniqueViolationOf(err error, constraint string) bool {
+	var cerr *domain.ConstraintError
+	return errors.As(err, &cerr) && errors.Is(cerr.Kind, domain.ErrUniqueViolation) && cerr.Constraint == constraint
+}
+
+func isUniqueViolationOn(err error, column string) bool {
+	var cerr *domain.ConstraintError
+	return errors.As(err, &cerr) && errors.Is(cerr.Kind, domain.ErrUniqueViolation) && cerr.Column == column
+}
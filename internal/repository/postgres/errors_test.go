@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "Nil", err: nil, want: false},
+		{name: "No rows", err: sql.ErrNoRows, want: false},
+		{name: "Serialization failure", err: &pq.Error{Code: "40001"}, want: true},
+		{name: "Deadlock", err: &pq.Error{Code: "40P01"}, want: true},
+		{name: "Connection failure", err: &pq.Error{Code: "08006"}, want: true},
+		{name: "Unique violation", err: &pq.Error{Code: "23505"}, want: false},
+		{name: "Wrapped bad conn", err: fmt.Errorf("query: %w", driver.ErrBadConn), want: true},
+		{name: "Connection reset", err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}, want: true},
+		{name: "Context canceled", err: context.Canceled, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isTransient(tt.err))
+		})
+	}
+}
+
+func TestBackoffRetry(t *testing.T) {
+	transient := &pq.Error{Code: "40001"}
+
+	t.Run("Retries transient errors", func(t *testing.T) {
+		calls := 0
+		err := backoff{attempts: 3, initial: time.Millisecond}.retry(context.Background(), isTransient, func() error {
+			calls++
+			if calls < 3 {
+				return transient
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("Stops on permanent errors", func(t *testing.T) {
+		calls := 0
+		permanent := errors.New("syntax error")
+		err := backoff{attempts: 3, initial: time.Millisecond}.retry(context.Background(), isTransient, func() error {
+			calls++
+			return permanent
+		})
+		assert.ErrorIs(t, err, permanent)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Gives up after attempts", func(t *testing.T) {
+		calls := 0
+		err := backoff{attempts: 2, initial: time.Millisecond}.retry(context.Background(), isTransient, func() error {
+			calls++
+			return transient
+		})
+		assert.ErrorIs(t, err, transient)
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantKind   error
+		wantColumn string
+	}{
+		{
+			name:       "Duplicate primary key",
+			err:        &pq.Error{Code: "23505", Table: "cars", Constraint: "cars_pkey", Detail: "Key (id)=(1) already exists."},
+			wantKind:   domain.ErrUniqueViolation,
+			wantColumn: "id",
+		},
+		{
+			name:       "Missing value",
+			err:        &pq.Error{Code: "23502", Table: "cars", Column: "make"},
+			wantKind:   domain.ErrNotNullViolation,
+			wantColumn: "make",
+		},
+		{
+			name: "Foreign key",
+			err: &pq.Error{Code: "23503", Table: "cars", Constraint: "cars_tenant_id_dealer_id_fkey",
+				Detail: `Key (tenant_id, dealer_id)=(t1, 7) is not present in table "dealers".`},
+			wantKind:   domain.ErrForeignKeyViolation,
+			wantColumn: "tenant_id, dealer_id",
+		},
+		{
+			name:       "Check constraint",
+			err:        &pq.Error{Code: "23514", Table: "cars", Constraint: "cars_year_check"},
+			wantKind:   domain.ErrCheckViolation,
+			wantColumn: "year",
+		},
+		{
+			name:     "String too long",
+			err:      &pq.Error{Code: "22001"},
+			wantKind: domain.ErrValueTooLong,
+		},
+		{
+			name:     "Serialization failure",
+			err:      fmt.Errorf("update: %w", &pq.Error{Code: "40001"}),
+			wantKind: domain.ErrConcurrentUpdate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := translateError(tt.err)
+			assert.ErrorIs(t, err, tt.wantKind)
+
+			var cerr *domain.ConstraintError
+			if errors.As(err, &cerr) {
+				assert.Equal(t, tt.wantColumn, cerr.Column)
+			}
+		})
+	}
+
+	t.Run("Unknown errors pass through", func(t *testing.T) {
+		assert.Equal(t, sql.ErrNoRows, translateError(sql.ErrNoRows))
+	})
+}
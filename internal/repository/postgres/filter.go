@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kefir4iick/crud/internal/domain"
+)
+
+// queryArgs collects positional arguments while a statement is assembled.
+type queryArgs []any
+
+// add appends v and returns its placeholder.
+func (a *queryArgs) add(v any) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// liveStatus is the status of a car as carColumns reads it, for queries that
+// do not join the reservation.
+const liveStatus = `(CASE WHEN cars.status = 'reserved' AND EXISTS (
+		SELECT 1 FROM car_reservations r
+		WHERE r.tenant_id = cars.tenant_id AND r.car_id = cars.id
+			AND r.status = 'active' AND r.expires_at <= now())
+	THEN 'available' ELSE cars.status END)`
+
+// carWhere turns f into the WHERE clause of a cars query scoped to tenantID.
+// price is the expression from priceExpr.
+func carWhere(args *queryArgs, tenantID string, f domain.CarFilter, price string) string {
+	conds := []string{"tenant_id = " + args.add(tenantID)}
+
+	if f.Make != "" {
+		conds = append(conds, "make = "+args.add(f.Make))
+	}
+	if f.Model != "" {
+		conds = append(conds, "model = "+args.add(f.Model))
+	}
+	if f.YearMin != 0 {
+		conds = append(conds, "year >= "+args.add(f.YearMin))
+	}
+	if f.YearMax != 0 {
+		conds = append(conds, "year <= "+args.add(f.YearMax))
+	}
+	if f.Currency != "" {
+		conds = append(conds, "currency = "+args.add(f.Currency))
+	}
+	if f.PriceMin != 0 {
+		conds = append(conds, price+" >= "+args.add(f.PriceMin))
+	}
+	if f.PriceMax != 0 {
+		conds = append(conds, price+" <= "+args.add(f.PriceMax))
+	}
+	if f.Condition != "" {
+		conds = append(conds, "condition = "+args.add(string(f.Condition)))
+	}
+	if f.FuelType != "" {
+		conds = append(conds, "fuel_type = "+args.add(string(f.FuelType)))
+	}
+	if f.Transmission != "" {
+		conds = append(conds, "transmission = "+args.add(string(f.Transmission)))
+	}
+	if f.BodyType != "" {
+		conds = append(conds, "body_type = "+args.add(string(f.BodyType)))
+	}
+	if f.Doors != 0 {
+		conds = append(conds, "doors = "+args.add(f.Doors))
+	}
+	if f.Status != "" {
+		conds = append(conds, liveStatus+" = "+args.add(string(f.Status)))
+	}
+	if f.DealerID != 0 {
+		conds = append(conds, "dealer_id = "+args.add(f.DealerID))
+	}
+	if f.MileageMin != 0 {
+		conds = append(conds, "mileage >= "+args.add(f.MileageMin))
+	}
+	if f.MileageMax != 0 {
+		conds = append(conds, "mileage <= "+args.add(f.MileageMax))
+	}
+	if f.PriceDroppedSince != nil {
+		conds = append(conds, `EXISTS (
+			SELECT 1 FROM car_price_history h
+			WHERE h.tenant_id = cars.tenant_id AND h.car_id = cars.id
+				AND h.changed_at >= `+args.add(*f.PriceDroppedSince)+`
+				AND h.new_currency = h.old_currency AND h.new_price < h.old_price)`)
+	}
+
+	return strings.Join(conds, " AND ")
+}
+
+// priceExpr is the price used for filtering and sorting. It is the stored
+// amount unless prices in several currencies are compared, in which case it
+// is converted to the base currency; cars in currencies without a rate
+// evaluate to NULL.
+func priceExpr(args *queryArgs, f domain.CarFilter) string {
+	if f.Currency != "" || f.Conversion == nil {
+		return "price"
+	}
+
+	currencies := []string{f.Conversion.Base}
+	for currency := range f.Conversion.Rates {
+		if currency != f.Conversion.Base {
+			currencies = append(currencies, currency)
+		}
+	}
+	sort.Strings(currencies)
+
+	var b strings.Builder
+	b.WriteString("(CASE currency")
+	for _, currency := range currencies {
+		factor, _ := f.Conversion.Factor(currency)
+		b.WriteString(" WHEN " + args.add(currency) + " THEN price * " + args.add(factor) + "::float8")
+	}
+	b.WriteString(" END)")
+	return b.String()
+}
+
+// carOrderBy returns the ORDER BY clause for f. IDs break ties so that pages
+// are stable.
+func carOrderBy(f domain.CarFilter, price string) string {
+	column := "id"
+	switch f.SortField() {
+	case domain.CarSortPrice:
+		column = price
+	case domain.CarSortYear:
+		column = "year"
+	case domain.CarSortCreatedAt:
+		column = "created_at"
+	case domain.CarSortMileage:
+		column = "mileage"
+	}
+
+	direction := " ASC"
+	if f.SortDescending() {
+		direction = " DESC"
+	}
+	if column == "id" {
+		return "id" + direction
+	}
+	return column + direction + " NULLS LAST, id"
+}
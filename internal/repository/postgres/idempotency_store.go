@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kefir4iick/crud/internal/idempotency"
+	"github.com/kefir4iick/crud/internal/metrics"
+)
+
+// idempotencyStore keeps idempotency records in the idempotency_keys table.
+// Keys are already scoped by the caller, so the table is not tenant scoped
+// and its statements do not go through run.
+type idempotencyStore struct {
+	store
+}
+
+func NewIdempotencyStore(db *sql.DB, cfg Config) idempotency.Store {
+	return &idempotencyStore{store{db: sqlConn{db}, cfg: cfg, table: "idempotency_keys"}}
+}
+
+func NewPgxIdempotencyStore(pool *pgxpool.Pool, cfg Config) idempotency.Store {
+	return &idempotencyStore{store{db: pgxConn{pool}, cfg: cfg, table: "idempotency_keys"}}
+}
+
+// Begin inserts a pending record that expires after lease, taking over an
+// expired one. When the insert does nothing the key is held by someone else
+// and the stored record is returned; if that record disappears in between,
+// the insert is tried again.
+func (s *idempotencyStore) Begin(ctx context.Context, scope, key, requestHash string, lease time.Duration) (*idempotency.Record, error) {
+	defer metrics.ObserveQuery("idempotency_keys", "Begin")()
+
+	reserve := `
+		INSERT INTO idempotency_keys (scope, key, request_hash, expires_at)
+		VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+		ON CONFLICT (scope, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+			status = NULL,
+			response = NULL,
+			headers = NULL,
+			created_at = now(),
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= now()
+	`
+	lookup := `
+		SELECT request_hash, status, response, headers
+		FROM idempotency_keys
+		WHERE scope = $1 AND key = $2
+	`
+
+	ctx, span := s.startSpan(ctx, "Begin", reserve)
+	defer span.End()
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	for attempt := 0; attempt < 2; attempt++ {
+		inserted, err := s.db.exec(ctx, reserve, scope, key, requestHash, lease.Seconds())
+		if err != nil {
+			return nil, fmt.Errorf("failed to reserve idempotency key: %w", s.translate(ctx, "Begin", err))
+		}
+		if inserted == 1 {
+			return nil, nil
+		}
+
+		var (
+			rec     idempotency.Record
+			status  sql.NullInt32
+			headers []byte
+		)
+		err = s.db.queryRow(ctx, lookup, scope, key).Scan(&rec.RequestHash, &status, &rec.Body, &headers)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get idempotency key: %w", s.translate(ctx, "Begin", err))
+		}
+		rec.Status = int(status.Int32)
+		if headers != nil {
+			if err := json.Unmarshal(headers, &rec.Header); err != nil {
+				return nil, fmt.Errorf("failed to decode idempotent response headers: %w", err)
+			}
+		}
+		return &rec, nil
+	}
+
+	return nil, errors.New("failed to reserve idempotency key: key changed concurrently")
+}
+
+// Complete stores the response and extends the record from its lease to ttl.
+// A record that has already been completed is left alone.
+func (s *idempotencyStore) Complete(ctx context.Context, scope, key string, resp idempotency.Response, ttl time.Duration) error {
+	defer metrics.ObserveQuery("idempotency_keys", "Complete")()
+
+	query := `
+		UPDATE idempotency_keys
+		SET status = $1, response = $2, headers = $3, expires_at = now() + make_interval(secs => $4)
+		WHERE scope = $5 AND key = $6 AND status IS NULL
+	`
+
+	headers, err := json.Marshal(resp.Header)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotent response headers: %w", err)
+	}
+
+	ctx, span := s.startSpan(ctx, "Complete", query)
+	defer span.End()
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.db.exec(ctx, query, resp.Status, resp.Body, string(headers), ttl.Seconds(), scope, key); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", s.translate(ctx, "Complete", err))
+	}
+	return nil
+}
+
+func (s *idempotencyStore) Release(ctx context.Context, scope, key string) error {
+	defer metrics.ObserveQuery("idempotency_keys", "Release")()
+
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND status IS NULL
+	`
+
+	ctx, span := s.startSpan(ctx, "Release", query)
+	defer span.End()
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.db.exec(ctx, query, scope, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", s.translate(ctx, "Release", err))
+	}
+	return nil
+}
+
+func (s *idempotencyStore) DeleteExpired(ctx context.Context) (int64, error) {
+	defer metrics.ObserveQuery("idempotency_keys", "DeleteExpired")()
+
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE expires_at <= now()
+	`
+
+	ctx, span := s.startSpan(ctx, "DeleteExpired", query)
+	defer span.End()
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	n, err := s.db.exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", s.translate(ctx, "DeleteExpired", err))
+	}
+	return n, nil
+}
@@ -0,0 +1,64 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kefir4iick/crud/internal/idempotency"
+	"github.com/kefir4iick/crud/internal/repository/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	db := openDB(t)
+	pool := openPool(t)
+	cfg := postgres.DefaultConfig()
+
+	stores := map[string]idempotency.Store{
+		"pq":  postgres.NewIdempotencyStore(db, cfg),
+		"pgx": postgres.NewPgxIdempotencyStore(pool, cfg),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			_, err := db.Exec("TRUNCATE idempotency_keys")
+			require.NoError(t, err)
+			ctx := context.Background()
+
+			rec, err := store.Begin(ctx, "t1/alice", "k1", "hash", time.Minute)
+			require.NoError(t, err)
+			assert.Nil(t, rec)
+
+			rec, err = store.Begin(ctx, "t1/alice", "k1", "hash", time.Minute)
+			require.NoError(t, err)
+			require.NotNil(t, rec)
+			assert.False(t, rec.Completed())
+
+			resp := idempotency.Response{Status: 201, Header: map[string]string{"Location": "/cars/1"}, Body: []byte(`{"id":"1"}`)}
+			require.NoError(t, store.Complete(ctx, "t1/alice", "k1", resp, time.Hour))
+			rec, err = store.Begin(ctx, "t1/alice", "k1", "hash", time.Minute)
+			require.NoError(t, err)
+			require.NotNil(t, rec)
+			assert.Equal(t, 201, rec.Status)
+			assert.Equal(t, resp.Header, rec.Header)
+			assert.JSONEq(t, `{"id":"1"}`, string(rec.Body))
+
+			var expiresIn float64
+			require.NoError(t, db.QueryRow(`SELECT extract(epoch FROM expires_at - now()) FROM idempotency_keys WHERE key = 'k1'`).Scan(&expiresIn))
+			assert.Greater(t, expiresIn, time.Minute.Seconds(), "completing extends the lease to the TTL")
+
+			rec, err = store.Begin(ctx, "t1/alice", "k2", "hash", -time.Second)
+			require.NoError(t, err)
+			assert.Nil(t, rec)
+			rec, err = store.Begin(ctx, "t1/alice", "k2", "other", time.Minute)
+			require.NoError(t, err)
+			assert.Nil(t, rec, "an expired key is taken over")
+
+			require.NoError(t, store.Release(ctx, "t1/alice", "k2"))
+			n, err := store.DeleteExpired(ctx)
+			require.NoError(t, err)
+			assert.Zero(t, n)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/metrics"
+)
+
+// priceHistoryRepository is only created by unitOfWork, always on a
+// transaction.
+type priceHistoryRepository struct {
+	store
+}
+
+func (r *priceHistoryRepository) Record(ctx context.Context, carID string, oldPrice, newPrice domain.Money) error {
+	defer metrics.ObserveQuery("car_price_history", "Record")()
+
+	query := `
+		INSERT INTO car_price_history (tenant_id, car_id, old_price, old_currency, new_price, new_currency)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	ctx, span := r.startSpan(ctx, "Record", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err = r.run(ctx, func(db dbtx) error {
+		_, err := db.exec(ctx, query, tenantID, carID, oldPrice.Amount, oldPrice.Currency, newPrice.Amount, newPrice.Currency)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record price change: %w", r.translate(ctx, "Record", err))
+	}
+
+	return nil
+}
+
+func (r *priceHistoryRepository) List(ctx context.Context, carID string) ([]domain.PriceChange, error) {
+	defer metrics.ObserveQuery("car_price_history", "List")()
+
+	query := `
+		SELECT old_price, old_currency, new_price, new_currency, changed_at
+		FROM car_price_history
+		WHERE tenant_id = $1 AND car_id = $2
+		ORDER BY id
+	`
+
+	ctx, span := r.startSpan(ctx, "List", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	changes := []domain.PriceChange{}
+	err = r.run(ctx, func(db dbtx) error {
+		rows, err := db.query(ctx, query, tenantID, carID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var c domain.PriceChange
+			err := rows.Scan(&c.OldPrice.Amount, &c.OldPrice.Currency, &c.NewPrice.Amount, &c.NewPrice.Currency, &c.ChangedAt)
+			if err != nil {
+				return fmt.Errorf("failed to scan price change: %w", err)
+			}
+			changes = append(changes, c)
+		}
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("rows error: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list price changes: %w", r.translate(ctx, "List", err))
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,259 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/metrics"
+	"github.com/kefir4iick/crud/internal/repository"
+)
+
+const reservationColumns = `id, car_id, holder, note, status, expires_at, created_at, updated_at`
+
+// activeReservationIndex is the partial unique index that allows one active
+// reservation per car.
+const activeReservationIndex = "car_reservations_active_idx"
+
+// reservationRepository is only created by unitOfWork, always on a
+// transaction.
+type reservationRepository struct {
+	store
+}
+
+func scanReservation(row interface{ Scan(dest ...any) error }, res *domain.Reservation) error {
+	var (
+		note   sql.NullString
+		status string
+	)
+	err := row.Scan(&res.ID, &res.CarID, &res.Holder, &note, &status, &res.ExpiresAt, &res.CreatedAt, &res.UpdatedAt)
+	res.Note = note.String
+	res.Status = domain.ReservationStatus(status)
+	return err
+}
+
+func (r *reservationRepository) Create(ctx context.Context, res domain.Reservation) (*domain.Reservation, error) {
+	defer metrics.ObserveQuery("car_reservations", "Create")()
+
+	query := `
+		INSERT INTO car_reservations (tenant_id, car_id, holder, note, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + reservationColumns
+
+	ctx, span := r.startSpan(ctx, "Create", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if res.Status == "" {
+		res.Status = domain.ReservationActive
+	}
+	err = r.run(ctx, func(db dbtx) error {
+		row := db.queryRow(ctx, query, tenantID, res.CarID, res.Holder, nullString(res.Note), string(res.Status), res.ExpiresAt)
+		return scanReservation(row, &res)
+	})
+	if err != nil {
+		err = r.translate(ctx, "Create", err)
+		if isUniqueViolationOf(err, activeReservationIndex) {
+			return nil, fmt.Errorf("%w: %w", domain.ErrCarReserved, err)
+		}
+		return nil, fmt.Errorf("failed to create reservation: %w", err)
+	}
+
+	return &res, nil
+}
+
+func (r *reservationRepository) Get(ctx context.Context, carID string, id int64) (*domain.Reservation, error) {
+	return r.get(ctx, "Get", "id = $3", carID, id)
+}
+
+func (r *reservationRepository) GetActive(ctx context.Context, carID string) (*domain.Reservation, error) {
+	return r.get(ctx, "GetActive", "status = 'active'", carID)
+}
+
+func (r *reservationRepository) get(ctx context.Context, method, where, carID string, args ...any) (*domain.Reservation, error) {
+	defer metrics.ObserveQuery("car_reservations", method)()
+
+	query := `
+		SELECT ` + reservationColumns + `
+		FROM car_reservations
+		WHERE tenant_id = $1 AND car_id = $2 AND ` + where
+
+	ctx, span := r.startSpan(ctx, method, query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var res domain.Reservation
+	err = r.run(ctx, func(db dbtx) error {
+		return scanReservation(db.queryRow(ctx, query, append([]any{tenantID, carID}, args...)...), &res)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrReservationNotFound
+		}
+		return nil, fmt.Errorf("failed to get reservation: %w", r.translate(ctx, method, err))
+	}
+
+	return &res, nil
+}
+
+func (r *reservationRepository) List(ctx context.Context, carID string) ([]domain.Reservation, error) {
+	defer metrics.ObserveQuery("car_reservations", "List")()
+
+	query := `
+		SELECT ` + reservationColumns + `
+		FROM car_reservations
+		WHERE tenant_id = $1 AND car_id = $2
+		ORDER BY id
+	`
+
+	ctx, span := r.startSpan(ctx, "List", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	reservations := []domain.Reservation{}
+	err = r.run(ctx, func(db dbtx) error {
+		rows, err := db.query(ctx, query, tenantID, carID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var res domain.Reservation
+			if err := scanReservation(rows, &res); err != nil {
+				return fmt.Errorf("failed to scan reservation: %w", err)
+			}
+			reservations = append(reservations, res)
+		}
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("rows error: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reservations: %w", r.translate(ctx, "List", err))
+	}
+
+	return reservations, nil
+}
+
+func (r *reservationRepository) Update(ctx context.Context, res domain.Reservation) (*domain.Reservation, error) {
+	defer metrics.ObserveQuery("car_reservations", "Update")()
+
+	query := `
+		UPDATE car_reservations
+		SET note = $1, status = $2, expires_at = $3, updated_at = now()
+		WHERE tenant_id = $4 AND car_id = $5 AND id = $6
+		RETURNING ` + reservationColumns
+
+	ctx, span := r.startSpan(ctx, "Update", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err = r.run(ctx, func(db dbtx) error {
+		row := db.queryRow(ctx, query, nullString(res.Note), string(res.Status), res.ExpiresAt, tenantID, res.CarID, res.ID)
+		return scanReservation(row, &res)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrReservationNotFound
+		}
+		err = r.translate(ctx, "Update", err)
+		if isUniqueViolationOf(err, activeReservationIndex) {
+			return nil, fmt.Errorf("%w: %w", domain.ErrCarReserved, err)
+		}
+		return nil, fmt.Errorf("failed to update reservation: %w", err)
+	}
+
+	return &res, nil
+}
+
+// expiredReservationRepository reads across tenants, so its statements do not
+// go through run. With row-level security it relies on the
+// car_reservations_sweeper policy.
+type expiredReservationRepository struct {
+	store
+}
+
+func NewExpiredReservationRepository(db *sql.DB, cfg Config) repository.ExpiredReservationRepository {
+	return &expiredReservationRepository{store{db: sqlConn{db}, cfg: cfg, table: "car_reservations"}}
+}
+
+func NewPgxExpiredReservationRepository(pool *pgxpool.Pool, cfg Config) repository.ExpiredReservationRepository {
+	return &expiredReservationRepository{store{db: pgxConn{pool}, cfg: cfg, table: "car_reservations"}}
+}
+
+func (r *expiredReservationRepository) ListExpired(ctx context.Context, after int64, limit int) ([]domain.ExpiredReservation, error) {
+	defer metrics.ObserveQuery("car_reservations", "ListExpired")()
+
+	query := `
+		SELECT tenant_id, car_id, id
+		FROM car_reservations
+		WHERE status = 'active' AND expires_at <= now() AND id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+
+	ctx, span := r.startSpan(ctx, "ListExpired", query)
+	defer span.End()
+
+	var expired []domain.ExpiredReservation
+	err := r.retryRead(ctx, func(ctx context.Context) error {
+		rows, err := r.db.query(ctx, query, after, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		expired = expired[:0]
+		for rows.Next() {
+			var e domain.ExpiredReservation
+			if err := rows.Scan(&e.TenantID, &e.CarID, &e.ID); err != nil {
+				return fmt.Errorf("failed to scan reservation: %w", err)
+			}
+			expired = append(expired, e)
+		}
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("rows error: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired reservations: %w", r.translate(ctx, "ListExpired", err))
+	}
+
+	return expired, nil
+}
@@ -0,0 +1,120 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/repository"
+	"github.com/kefir4iick/crud/internal/repository/postgres"
+	"github.com/kefir4iick/crud/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiredReservationRepository(t *testing.T) {
+	db := openDB(t)
+	pool := openPool(t)
+	cfg := postgres.DefaultConfig()
+
+	run := func(t *testing.T, uow repository.UnitOfWork, cars repository.CarRepository, expired repository.ExpiredReservationRepository) {
+		truncate(t, db)
+		reserve := func(tenantID, carID string, expiresAt time.Time) int64 {
+			ctx := tenant.WithID(context.Background(), tenantID)
+			_, err := cars.Create(ctx, domain.Car{ID: carID, Make: "Toyota", Model: "Camry", Year: 2020, Price: domain.Money{Amount: 25000, Currency: "USD"}})
+			require.NoError(t, err)
+
+			var id int64
+			err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+				r, err := repos.Reservations.Create(ctx, domain.Reservation{CarID: carID, Holder: "Jane Doe", ExpiresAt: expiresAt})
+				if err != nil {
+					return err
+				}
+				id = r.ID
+				return nil
+			})
+			require.NoError(t, err)
+			return id
+		}
+
+		now := time.Now()
+		older := reserve("t1", "1", now.Add(-2*time.Hour))
+		newer := reserve("t2", "1", now.Add(-time.Hour))
+		reserve("t1", "2", now.Add(time.Hour))
+
+		got, err := expired.ListExpired(context.Background(), 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []domain.ExpiredReservation{
+			{TenantID: "t1", CarID: "1", ID: older},
+			{TenantID: "t2", CarID: "1", ID: newer},
+		}, got, "across tenants, in ID order")
+
+		got, err = expired.ListExpired(context.Background(), 0, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []domain.ExpiredReservation{{TenantID: "t1", CarID: "1", ID: older}}, got)
+
+		got, err = expired.ListExpired(context.Background(), older, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []domain.ExpiredReservation{{TenantID: "t2", CarID: "1", ID: newer}}, got, "after a cursor")
+	}
+
+	t.Run("pq", func(t *testing.T) {
+		run(t, postgres.NewUnitOfWork(db, cfg), postgres.NewPostgresCarRepository(db, cfg), postgres.NewExpiredReservationRepository(db, cfg))
+	})
+
+	t.Run("pgx", func(t *testing.T) {
+		run(t, postgres.NewPgxUnitOfWork(pool, cfg), postgres.NewPgxCarRepository(pool, cfg), postgres.NewPgxExpiredReservationRepository(pool, cfg))
+	})
+}
+
+func TestCarRepository_LapsedReservation(t *testing.T) {
+	db := openDB(t)
+	pool := openPool(t)
+	cfg := postgres.DefaultConfig()
+
+	run := func(t *testing.T, uow repository.UnitOfWork, cars repository.CarRepository) {
+		truncate(t, db)
+		ctx := tenant.WithID(context.Background(), "t1")
+		_, err := cars.Create(ctx, domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: domain.Money{Amount: 25000, Currency: "USD"}, Status: domain.StatusReserved})
+		require.NoError(t, err)
+		lapsedAt := time.Now().Add(-time.Minute)
+		err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+			_, err := repos.Reservations.Create(ctx, domain.Reservation{CarID: "1", Holder: "Jane Doe", ExpiresAt: lapsedAt})
+			return err
+		})
+		require.NoError(t, err)
+
+		// Before the sweeper ends the reservation, reads see the car on sale.
+		car, err := cars.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, domain.StatusAvailable, car.Status)
+		assert.Nil(t, car.ReservedUntil)
+
+		available, err := cars.GetAll(ctx, domain.CarFilter{Status: domain.StatusAvailable})
+		require.NoError(t, err)
+		assert.Len(t, available, 1)
+		reserved, err := cars.GetAll(ctx, domain.CarFilter{Status: domain.StatusReserved})
+		require.NoError(t, err)
+		assert.Empty(t, reserved)
+
+		// Writers lock the car as stored, to end the reservation themselves.
+		err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+			car, err := repos.Cars.GetByIDForUpdate(ctx, "1")
+			require.NoError(t, err)
+			assert.Equal(t, domain.StatusReserved, car.Status)
+			require.NotNil(t, car.ReservedUntil)
+			assert.WithinDuration(t, lapsedAt, *car.ReservedUntil, time.Millisecond)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("pq", func(t *testing.T) {
+		run(t, postgres.NewUnitOfWork(db, cfg), postgres.NewPostgresCarRepository(db, cfg))
+	})
+
+	t.Run("pgx", func(t *testing.T) {
+		run(t, postgres.NewPgxUnitOfWork(pool, cfg), postgres.NewPgxCarRepository(pool, cfg))
+	})
+}
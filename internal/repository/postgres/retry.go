@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"time"
+)
+
+type backoff struct {
+	attempts int
+	initial  time.Duration
+	max      time.Duration
+	// onRetry, if set, is told about every failed attempt that will be retried.
+	onRetry func(attempt int, err error, delay time.Duration)
+}
+
+// retry runs fn until it succeeds, returns an error that retryable rejects,
+// or the attempts are exhausted. The delay doubles after every failure and is
+// capped at max; a cancelled ctx stops the loop early.
+func (b backoff) retry(ctx context.Context, retryable func(error) bool, fn func() error) error {
+	attempts := b.attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := b.initial
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = fn(); err == nil || !retryable(err) || i == attempts-1 {
+			return err
+		}
+
+		if b.onRetry != nil {
+			b.onRetry(i+1, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if b.max > 0 && delay > b.max {
+			delay = b.max
+		}
+	}
+
+	return err
+}
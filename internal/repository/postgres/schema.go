@@ -0,0 +1,312 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// expectedSchema lists, per table, the columns the repository reads and writes
+// with their information_schema data types, plus the constraints that back
+// the domain rules. Keep it in sync with db/migration.
+var expectedSchema = map[string]tableSchema{
+	"cars": {
+		columns: map[string]string{
+			"tenant_id":           "character varying",
+			"id":                  "character varying",
+			"make":                "character varying",
+			"model":               "character varying",
+			"year":                "integer",
+			"price":               "bigint",
+			"currency":            "character",
+			"vin":                 "character",
+			"mileage":             "integer",
+			"condition":           "character varying",
+			"fuel_type":           "character varying",
+			"transmission":        "character varying",
+			"body_type":           "character varying",
+			"doors":               "smallint",
+			"engine_displacement": "integer",
+			"dealer_id":           "bigint",
+			"status":              "character varying",
+			"color":               "character varying",
+			"created_at":          "timestamp with time zone",
+			"updated_at":          "timestamp with time zone",
+		},
+		constraints: []string{
+			"cars_pkey",
+			"cars_make_check",
+			"cars_model_check",
+			"cars_year_check",
+			"cars_price_check",
+			"cars_currency_check",
+			"cars_tenant_vin_key",
+			"cars_mileage_check",
+			"cars_condition_check",
+			"cars_fuel_type_check",
+			"cars_transmission_check",
+			"cars_body_type_check",
+			"cars_doors_check",
+			"cars_engine_displacement_check",
+			"cars_tenant_id_dealer_id_fkey",
+			"cars_status_check",
+		},
+	},
+	"car_status_history": {
+		columns: map[string]string{
+			"id":          "bigint",
+			"tenant_id":   "character varying",
+			"car_id":      "character varying",
+			"from_status": "character varying",
+			"to_status":   "character varying",
+			"reason":      "character varying",
+			"changed_at":  "timestamp with time zone",
+		},
+		constraints: []string{
+			"car_status_history_pkey",
+			"car_status_history_tenant_id_car_id_fkey",
+		},
+	},
+	"car_reservations": {
+		columns: map[string]string{
+			"id":         "bigint",
+			"tenant_id":  "character varying",
+			"car_id":     "character varying",
+			"holder":     "character varying",
+			"note":       "character varying",
+			"status":     "character varying",
+			"expires_at": "timestamp with time zone",
+			"created_at": "timestamp with time zone",
+			"updated_at": "timestamp with time zone",
+		},
+		constraints: []string{
+			"car_reservations_pkey",
+			"car_reservations_status_check",
+			"car_reservations_tenant_id_car_id_fkey",
+		},
+	},
+	"dealers": {
+		columns: map[string]string{
+			"tenant_id":  "character varying",
+			"id":         "bigint",
+			"name":       "character varying",
+			"address":    "character varying",
+			"latitude":   "double precision",
+			"longitude":  "double precision",
+			"created_at": "timestamp with time zone",
+			"updated_at": "timestamp with time zone",
+		},
+		constraints: []string{
+			"dealers_pkey",
+			"dealers_latitude_check",
+			"dealers_longitude_check",
+			"dealers_location_check",
+		},
+	},
+	"car_price_history": {
+		columns: map[string]string{
+			"id":           "bigint",
+			"tenant_id":    "character varying",
+			"car_id":       "character varying",
+			"old_price":    "bigint",
+			"old_currency": "character",
+			"new_price":    "bigint",
+			"new_currency": "character",
+			"changed_at":   "timestamp with time zone",
+		},
+		constraints: []string{
+			"car_price_history_pkey",
+			"car_price_history_tenant_id_car_id_fkey",
+		},
+	},
+	"car_attachments": {
+		columns: map[string]string{
+			"id":           "bigint",
+			"tenant_id":    "character varying",
+			"car_id":       "character varying",
+			"filename":     "character varying",
+			"content_type": "character varying",
+			"size":         "bigint",
+			"checksum":     "character",
+			"created_at":   "timestamp with time zone",
+		},
+		constraints: []string{
+			"car_attachments_pkey",
+			"car_attachments_size_check",
+			"car_attachments_tenant_id_car_id_fkey",
+		},
+	},
+	"makes": {
+		columns: map[string]string{
+			"id":   "character varying",
+			"name": "character varying",
+		},
+		constraints: []string{
+			"makes_pkey",
+		},
+	},
+	"make_names": {
+		columns: map[string]string{
+			"key":     "character varying",
+			"make_id": "character varying",
+			"name":    "character varying",
+			"alias":   "boolean",
+		},
+		constraints: []string{
+			"make_names_pkey",
+			"make_names_make_id_fkey",
+		},
+	},
+	"models": {
+		columns: map[string]string{
+			"make_id":   "character varying",
+			"id":        "character varying",
+			"name":      "character varying",
+			"year_from": "integer",
+			"year_to":   "integer",
+		},
+		constraints: []string{
+			"models_pkey",
+			"models_make_id_fkey",
+			"models_years_check",
+		},
+	},
+	"model_names": {
+		columns: map[string]string{
+			"make_id":  "character varying",
+			"key":      "character varying",
+			"model_id": "character varying",
+			"name":     "character varying",
+			"alias":    "boolean",
+		},
+		constraints: []string{
+			"model_names_pkey",
+			"model_names_make_id_model_id_fkey",
+		},
+	},
+	"idempotency_keys": {
+		columns: map[string]string{
+			"scope":        "character varying",
+			"key":          "character varying",
+			"request_hash": "character",
+			"status":       "integer",
+			"response":     "bytea",
+			"headers":      "jsonb",
+			"expires_at":   "timestamp with time zone",
+		},
+		constraints: []string{
+			"idempotency_keys_pkey",
+		},
+	},
+}
+
+type tableSchema struct {
+	columns     map[string]string
+	constraints []string
+}
+
+// CheckSchema compares the live database against expectedSchema and reports
+// every missing table, column or constraint and every column whose type
+// differs, so a missed migration fails at startup rather than on first use.
+func CheckSchema(ctx context.Context, db *sql.DB) error {
+	tables := make([]string, 0, len(expectedSchema))
+	for table := range expectedSchema {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	var problems []string
+	for _, table := range tables {
+		p, err := checkTable(ctx, db, table, expectedSchema[table])
+		if err != nil {
+			return err
+		}
+		problems = append(problems, p...)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("database schema does not match the repository: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func checkTable(ctx context.Context, db *sql.DB, table string, want tableSchema) ([]string, error) {
+	columnsQuery := `
+		SELECT column_name, data_type
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1
+	`
+
+	rows, err := db.QueryContext(ctx, columnsQuery, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	have := make(map[string]string)
+	for rows.Next() {
+		var name, dataType string
+		if err := rows.Scan(&name, &dataType); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		have[name] = dataType
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	if len(have) == 0 {
+		return []string{fmt.Sprintf("table %s is missing", table)}, nil
+	}
+
+	var problems []string
+	columns := make([]string, 0, len(want.columns))
+	for column := range want.columns {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	for _, column := range columns {
+		dataType, ok := have[column]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("column %s.%s is missing", table, column))
+		case dataType != want.columns[column]:
+			problems = append(problems, fmt.Sprintf("column %s.%s is %s, expected %s", table, column, dataType, want.columns[column]))
+		}
+	}
+
+	constraintsQuery := `
+		SELECT conname
+		FROM pg_constraint
+		WHERE conrelid = to_regclass($1)
+	`
+
+	crows, err := db.QueryContext(ctx, constraintsQuery, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read constraints of %s: %w", table, err)
+	}
+	defer crows.Close()
+
+	constraints := make(map[string]bool)
+	for crows.Next() {
+		var name string
+		if err := crows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan constraint: %w", err)
+		}
+		constraints[name] = true
+	}
+	if err := crows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	for _, name := range want.constraints {
+		if !constraints[name] {
+			problems = append(problems, fmt.Sprintf("constraint %s on %s is missing", name, table))
+		}
+	}
+
+	return problems, nil
+}
@@ -1,21 +1,128 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/lib/pq"
 )
 
-func NewDB(connStr string) (*sql.DB, error) {
+type Config struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	ConnectAttempts   int
+	ConnectBackoff    time.Duration
+	ConnectMaxBackoff time.Duration
+
+	QueryTimeout time.Duration
+	ReadRetries  int
+	TxRetries    int
+	RetryBackoff time.Duration
+
+	// TenantRLS sets app.tenant_id for every transaction so the row-level
+	// security policies of the enable_*_rls migrations apply. Statements outside a
+	// unit of work then run in a transaction of their own.
+	TenantRLS bool
+
+	// Logger receives retry warnings and unexpected database errors. A nil
+	// Logger means slog.Default().
+	Logger *slog.Logger
+}
+
+func (c Config) logger() *slog.Logger {
+	if c.Logger == nil {
+		return slog.Default()
+	}
+	return c.Logger
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MaxOpenConns:      25,
+		MaxIdleConns:      25,
+		ConnMaxLifetime:   30 * time.Minute,
+		ConnMaxIdleTime:   5 * time.Minute,
+		ConnectAttempts:   10,
+		ConnectBackoff:    250 * time.Millisecond,
+		ConnectMaxBackoff: 10 * time.Second,
+		QueryTimeout:      5 * time.Second,
+		ReadRetries:       2,
+		TxRetries:         2,
+		RetryBackoff:      50 * time.Millisecond,
+	}
+}
+
+func NewDB(ctx context.Context, connStr string, cfg Config) (*sql.DB, error) {
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	b := backoff{attempts: cfg.ConnectAttempts, initial: cfg.ConnectBackoff, max: cfg.ConnectMaxBackoff, onRetry: logConnectRetry(ctx, cfg)}
+	err = b.retry(ctx, isRetryableConnectError, func() error {
+		return db.PingContext(ctx)
+	})
+	if err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	return db, nil
 }
+
+// NewPool opens a pgx connection pool. pgx has no separate idle limit, so
+// MaxIdleConns is not used; the remaining pool settings map one to one.
+func NewPool(ctx context.Context, connStr string, cfg Config) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
+	}
+
+	if cfg.MaxOpenConns > 0 {
+		poolConfig.MaxConns = int32(cfg.MaxOpenConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.ConnMaxLifetime
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.ConnMaxIdleTime
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	b := backoff{attempts: cfg.ConnectAttempts, initial: cfg.ConnectBackoff, max: cfg.ConnectMaxBackoff, onRetry: logConnectRetry(ctx, cfg)}
+	err = b.retry(ctx, isRetryableConnectError, func() error {
+		return pool.Ping(ctx)
+	})
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return pool, nil
+}
+
+func logConnectRetry(ctx context.Context, cfg Config) func(attempt int, err error, delay time.Duration) {
+	return func(attempt int, err error, delay time.Duration) {
+		cfg.logger().WarnContext(ctx, "database not ready, retrying",
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.Any("error", err),
+		)
+	}
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/metrics"
+)
+
+// statusHistoryRepository is only created by unitOfWork, always on a
+// transaction.
+type statusHistoryRepository struct {
+	store
+}
+
+func (r *statusHistoryRepository) Record(ctx context.Context, carID string, change domain.StatusChange) error {
+	defer metrics.ObserveQuery("car_status_history", "Record")()
+
+	query := `
+		INSERT INTO car_status_history (tenant_id, car_id, from_status, to_status, reason)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	ctx, span := r.startSpan(ctx, "Record", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err = r.run(ctx, func(db dbtx) error {
+		_, err := db.exec(ctx, query, tenantID, carID, string(change.From), string(change.To), nullString(change.Reason))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record status change: %w", r.translate(ctx, "Record", err))
+	}
+
+	return nil
+}
+
+func (r *statusHistoryRepository) List(ctx context.Context, carID string) ([]domain.StatusChange, error) {
+	defer metrics.ObserveQuery("car_status_history", "List")()
+
+	query := `
+		SELECT from_status, to_status, reason, changed_at
+		FROM car_status_history
+		WHERE tenant_id = $1 AND car_id = $2
+		ORDER BY id
+	`
+
+	ctx, span := r.startSpan(ctx, "List", query)
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	changes := []domain.StatusChange{}
+	err = r.run(ctx, func(db dbtx) error {
+		rows, err := db.query(ctx, query, tenantID, carID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				c        domain.StatusChange
+				from, to string
+				reason   sql.NullString
+			)
+			if err := rows.Scan(&from, &to, &reason, &c.ChangedAt); err != nil {
+				return fmt.Errorf("failed to scan status change: %w", err)
+			}
+			c.From, c.To, c.Reason = domain.CarStatus(from), domain.CarStatus(to), reason.String
+			changes = append(changes, c)
+		}
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("rows error: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list status changes: %w", r.translate(ctx, "List", err))
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/metrics"
+	"github.com/kefir4iick/crud/internal/tenant"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/kefir4iick/crud/internal/repository/postgres")
+
+// store is embedded by every repository in this package and holds the
+// plumbing they share: timeouts, read retries, error translation, tracing and
+// tenant scoping.
+type store struct {
+	db dbtx
+	// begin starts a transaction; it is nil for repositories that already run
+	// inside a unit of work.
+	begin func(ctx context.Context) (txConn, error)
+	cfg   Config
+	table string
+}
+
+// withTimeout bounds a single statement by the configured query timeout. The
+// derived context still honours the caller's own deadline and cancellation.
+func (s *store) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.cfg.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.cfg.QueryTimeout)
+}
+
+// retryRead re-runs an idempotent read when it fails with a transient error.
+// Writes are never retried here because the outcome of the failed attempt is
+// unknown.
+func (s *store) retryRead(ctx context.Context, fn func(ctx context.Context) error) error {
+	b := backoff{attempts: s.cfg.ReadRetries + 1, initial: s.cfg.RetryBackoff, max: time.Second}
+	b.onRetry = func(attempt int, err error, delay time.Duration) {
+		s.cfg.logger().WarnContext(ctx, "transient database error, retrying read",
+			slog.String("table", s.table),
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.Any("error", err),
+		)
+	}
+	return b.retry(ctx, isTransient, func() error {
+		ctx, cancel := s.withTimeout(ctx)
+		defer cancel()
+		return fn(ctx)
+	})
+}
+
+// translate maps err to a domain error and logs it. Constraint violations and
+// conflicts are expected outcomes and only logged at debug level.
+func (s *store) translate(ctx context.Context, method string, err error) error {
+	err = translateError(err)
+	metrics.QueryFailed(s.table, method)
+
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+
+	level := slog.LevelError
+	var cerr *domain.ConstraintError
+	if errors.As(err, &cerr) || isExpected(err) {
+		level = slog.LevelDebug
+	}
+	s.cfg.logger().Log(ctx, level, "repository query failed",
+		slog.String("table", s.table),
+		slog.String("method", method),
+		slog.Any("error", err),
+	)
+
+	return err
+}
+
+func isExpected(err error) bool {
+	return errors.Is(err, domain.ErrConcurrentUpdate) ||
+		errors.Is(err, domain.ErrDuplicateCarID) ||
+		errors.Is(err, domain.ErrTenantRequired)
+}
+
+// startSpan opens a client span for one repository method carrying the SQL
+// statement it runs.
+func (s *store) startSpan(ctx context.Context, method, query string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, s.table+"."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBCollectionName(s.table),
+			semconv.DBOperationName(method),
+			semconv.DBQueryText(strings.TrimSpace(query)),
+		),
+	)
+}
+
+// run executes fn against the connection. With row-level security enabled a
+// statement outside a unit of work gets its own transaction, because
+// app.tenant_id can only be set safely for the lifetime of a transaction.
+func (s *store) run(ctx context.Context, fn func(db dbtx) error) error {
+	if !s.cfg.TenantRLS || s.begin == nil {
+		return fn(s.db)
+	}
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := setTenant(ctx, tx); err != nil {
+		tx.rollback(ctx)
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.rollback(ctx)
+		return err
+	}
+	return tx.commit(ctx)
+}
+
+// transact runs fn in a transaction of its own, for statements that have to
+// succeed or fail together outside a unit of work.
+func (s *store) transact(ctx context.Context, fn func(db dbtx) error) error {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.rollback(ctx)
+		return err
+	}
+	return tx.commit(ctx)
+}
+
+func tenantID(ctx context.Context) (string, error) {
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		return "", domain.ErrTenantRequired
+	}
+	return id, nil
+}
+
+// setTenant exposes the tenant to the row-level security policies for the
+// rest of the current transaction.
+func setTenant(ctx context.Context, db dbtx) error {
+	id, err := tenantID(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = db.exec(ctx, `SELECT set_config('app.tenant_id', $1, true)`, id)
+	return err
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kefir4iick/crud/internal/repository"
+)
+
+type unitOfWork struct {
+	begin func(ctx context.Context) (txConn, error)
+	cfg   Config
+}
+
+func NewUnitOfWork(db *sql.DB, cfg Config) repository.UnitOfWork {
+	return &unitOfWork{begin: beginSQL(db), cfg: cfg}
+}
+
+func NewPgxUnitOfWork(pool *pgxpool.Pool, cfg Config) repository.UnitOfWork {
+	return &unitOfWork{begin: beginPgx(pool), cfg: cfg}
+}
+
+// WithTx retries the whole transaction when Postgres aborts it with a
+// serialization failure or deadlock; any other error is returned as is.
+func (u *unitOfWork) WithTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	b := backoff{attempts: u.cfg.TxRetries + 1, initial: u.cfg.RetryBackoff, max: u.cfg.ConnectMaxBackoff}
+	return b.retry(ctx, isTransient, func() error {
+		return u.run(ctx, fn)
+	})
+}
+
+func (u *unitOfWork) run(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	tx, err := u.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if u.cfg.TenantRLS {
+		if err := setTenant(ctx, tx); err != nil {
+			tx.rollback(ctx)
+			return fmt.Errorf("failed to set tenant: %w", err)
+		}
+	}
+
+	// Statements inside a transaction are not retried one by one: after an
+	// error Postgres rejects everything until rollback.
+	cfg := u.cfg
+	cfg.ReadRetries = 0
+
+	repos := repository.Repositories{
+		Cars:         &postgresCarRepository{store{db: tx, cfg: cfg, table: "cars"}},
+		Prices:       &priceHistoryRepository{store{db: tx, cfg: cfg, table: "car_price_history"}},
+		Attachments:  &attachmentRepository{store{db: tx, cfg: cfg, table: "car_attachments"}},
+		Dealers:      &dealerRepository{store{db: tx, cfg: cfg, table: "dealers"}},
+		Statuses:     &statusHistoryRepository{store{db: tx, cfg: cfg, table: "car_status_history"}},
+		Reservations: &reservationRepository{store{db: tx, cfg: cfg, table: "car_reservations"}},
+	}
+
+	if err := fn(repos); err != nil {
+		tx.rollback(ctx)
+		return err
+	}
+
+	if err := tx.commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", translateError(err))
+	}
+	return nil
+}
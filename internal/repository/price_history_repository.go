@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/kefir4iick/crud/internal/domain"
+)
+
+// PriceHistoryRepository keeps the price changes of cars. It is only
+// available inside a UnitOfWork, so a change is recorded in the same
+// transaction as the update that makes it.
+type PriceHistoryRepository interface {
+	Record(ctx context.Context, carID string, oldPrice, newPrice domain.Money) error
+	// List returns the changes of a car, oldest first.
+	List(ctx context.Context, carID string) ([]domain.PriceChange, error)
+}
@@ -0,0 +1,327 @@
+// Package repotest holds the conformance suite every repository.CarRepository
+// implementation is expected to pass.
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/repository"
+	"github.com/kefir4iick/crud/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunCarRepositoryTests runs the suite. newRepo must return a repository over
+// an empty store; it is called once per subtest.
+func RunCarRepositoryTests(t *testing.T, newRepo func(t *testing.T) repository.CarRepository) {
+	ctx := tenant.WithID(context.Background(), "t1")
+	camry := domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: usd(25000), Status: domain.StatusAvailable}
+	civic := domain.Car{ID: "2", Make: "Honda", Model: "Civic", Year: 2018, Price: usd(18000), Status: domain.StatusAvailable}
+	golf := domain.Car{ID: "3", Make: "Volkswagen", Model: "Golf", Year: 2015, Price: usd(12000), Status: domain.StatusAvailable}
+
+	t.Run("Create and get", func(t *testing.T) {
+		repo := newRepo(t)
+
+		created, err := repo.Create(ctx, camry)
+		require.NoError(t, err)
+		assert.Equal(t, camry, stripCar(t, created))
+
+		got, err := repo.GetByID(ctx, camry.ID)
+		require.NoError(t, err)
+		assert.Equal(t, camry, stripCar(t, got))
+	})
+
+	t.Run("Create duplicate ID", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Create(ctx, camry)
+		require.NoError(t, err)
+
+		_, err = repo.Create(ctx, camry)
+		assert.ErrorIs(t, err, domain.ErrDuplicateCarID)
+	})
+
+	t.Run("Get by VIN", func(t *testing.T) {
+		repo := newRepo(t)
+
+		accord := domain.Car{ID: "4", Make: "Honda", Model: "Accord", Year: 2003, Price: usd(5000), VIN: "1HGCM82633A004352", Status: domain.StatusAvailable}
+		_, err := repo.CreateBatch(ctx, []domain.Car{camry, civic, accord})
+		require.NoError(t, err)
+
+		got, err := repo.GetByVIN(ctx, accord.VIN)
+		require.NoError(t, err)
+		assert.Equal(t, accord, stripCar(t, got))
+
+		_, err = repo.GetByVIN(ctx, "11111111111111111")
+		assert.ErrorIs(t, err, domain.ErrCarNotFound)
+	})
+
+	t.Run("Create duplicate VIN", func(t *testing.T) {
+		repo := newRepo(t)
+
+		first, second := camry, civic
+		first.VIN, second.VIN = "1HGCM82633A004352", "1HGCM82633A004352"
+		_, err := repo.Create(ctx, first)
+		require.NoError(t, err)
+
+		_, err = repo.Create(ctx, second)
+		assert.ErrorIs(t, err, domain.ErrDuplicateVIN)
+	})
+
+	t.Run("Create violating a check", func(t *testing.T) {
+		repo := newRepo(t)
+
+		invalid := camry
+		invalid.Year = 1800
+		_, err := repo.Create(ctx, invalid)
+		assert.ErrorIs(t, err, domain.ErrCheckViolation)
+
+		var cerr *domain.ConstraintError
+		if assert.ErrorAs(t, err, &cerr) {
+			assert.Equal(t, "year", cerr.Column)
+		}
+	})
+
+	t.Run("Create batch", func(t *testing.T) {
+		repo := newRepo(t)
+
+		created, err := repo.CreateBatch(ctx, []domain.Car{camry, civic, golf})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Car{camry, civic, golf}, stripCars(t, created))
+	})
+
+	t.Run("Create batch is atomic", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.CreateBatch(ctx, []domain.Car{camry, civic, camry})
+		assert.ErrorIs(t, err, domain.ErrDuplicateCarID)
+
+		_, err = repo.GetByID(ctx, civic.ID)
+		assert.ErrorIs(t, err, domain.ErrCarNotFound)
+	})
+
+	t.Run("Tenants are isolated", func(t *testing.T) {
+		repo := newRepo(t)
+		other := tenant.WithID(context.Background(), "t2")
+
+		_, err := repo.Create(ctx, camry)
+		require.NoError(t, err)
+
+		_, err = repo.GetByID(other, camry.ID)
+		assert.ErrorIs(t, err, domain.ErrCarNotFound)
+
+		cars, err := repo.GetAll(other, domain.CarFilter{Limit: 10})
+		require.NoError(t, err)
+		assert.Empty(t, cars)
+
+		_, err = repo.Update(other, camry.ID, camry)
+		assert.ErrorIs(t, err, domain.ErrCarNotFound)
+		assert.ErrorIs(t, repo.Delete(other, camry.ID), domain.ErrCarNotFound)
+
+		// The same ID may be used by another tenant.
+		_, err = repo.Create(other, camry)
+		require.NoError(t, err)
+	})
+
+	t.Run("Tenant required", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.GetByID(context.Background(), camry.ID)
+		assert.ErrorIs(t, err, domain.ErrTenantRequired)
+	})
+
+	t.Run("Get missing", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.GetByID(ctx, "missing")
+		assert.ErrorIs(t, err, domain.ErrCarNotFound)
+	})
+
+	t.Run("Get all pages by ID", func(t *testing.T) {
+		repo := newRepo(t)
+		for _, car := range []domain.Car{golf, camry, civic} {
+			_, err := repo.Create(ctx, car)
+			require.NoError(t, err)
+		}
+
+		page, err := repo.GetAll(ctx, domain.CarFilter{Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Car{camry, civic}, stripCars(t, page))
+
+		page, err = repo.GetAll(ctx, domain.CarFilter{Limit: 2, Offset: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Car{golf}, stripCars(t, page))
+	})
+
+	t.Run("Get all filtered and sorted", func(t *testing.T) {
+		repo := newRepo(t)
+		camryEUR := camry
+		camryEUR.ID, camryEUR.Price = "4", domain.Money{Amount: 20000, Currency: "EUR"}
+		_, err := repo.CreateBatch(ctx, []domain.Car{golf, camry, civic, camryEUR})
+		require.NoError(t, err)
+
+		page, err := repo.GetAll(ctx, domain.CarFilter{Make: "Toyota", Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Car{camry, camryEUR}, stripCars(t, page))
+
+		page, err = repo.GetAll(ctx, domain.CarFilter{YearMin: 2016, YearMax: 2019, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Car{civic}, stripCars(t, page))
+
+		page, err = repo.GetAll(ctx, domain.CarFilter{Currency: "USD", PriceMax: 20000, Sort: "-price", Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Car{civic, golf}, stripCars(t, page))
+
+		conversion := &domain.ConversionTable{Base: "USD", Rates: map[string]float64{"EUR": 1.5}}
+		page, err = repo.GetAll(ctx, domain.CarFilter{Sort: "-price", Conversion: conversion, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Car{camryEUR, camry, civic, golf}, stripCars(t, page))
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		repo := newRepo(t)
+		cheapGolf := golf
+		cheapGolf.Price = usd(11000)
+		redCamry := camry
+		redCamry.Color = "red"
+		redCivic := civic
+		redCivic.Color = "red"
+		camryEUR := camry
+		camryEUR.ID, camryEUR.Price, camryEUR.Color = "4", domain.Money{Amount: 20000, Currency: "EUR"}, "white"
+		_, err := repo.CreateBatch(ctx, []domain.Car{cheapGolf, redCamry, redCivic, camryEUR})
+		require.NoError(t, err)
+
+		one := func(v float64) domain.Aggregate { return domain.Aggregate{Min: v, Max: v, Avg: v, Median: v} }
+
+		stats, err := repo.Stats(ctx, domain.CarFilter{}, "")
+		require.NoError(t, err)
+		assert.Equal(t, &domain.CarStats{Groups: []domain.StatsGroup{{
+			Count: 4,
+			Year:  domain.Aggregate{Min: 2015, Max: 2020, Avg: 2018.25, Median: 2019},
+			Prices: []domain.PriceStats{
+				{Currency: "EUR", Count: 1, Aggregate: one(20000)},
+				{Currency: "USD", Count: 3, Aggregate: domain.Aggregate{Min: 11000, Max: 25000, Avg: 18000, Median: 18000}},
+			},
+		}}}, stats)
+
+		stats, err = repo.Stats(ctx, domain.CarFilter{YearMin: 2016}, domain.StatsByMake)
+		require.NoError(t, err)
+		assert.Equal(t, &domain.CarStats{GroupBy: domain.StatsByMake, Groups: []domain.StatsGroup{
+			{Key: "Honda", Count: 1, Year: one(2018), Prices: []domain.PriceStats{
+				{Currency: "USD", Count: 1, Aggregate: one(18000)},
+			}},
+			{Key: "Toyota", Count: 2, Year: one(2020), Prices: []domain.PriceStats{
+				{Currency: "EUR", Count: 1, Aggregate: one(20000)},
+				{Currency: "USD", Count: 1, Aggregate: one(25000)},
+			}},
+		}}, stats)
+
+		// Cars without a color are grouped under the empty key.
+		stats, err = repo.Stats(ctx, domain.CarFilter{}, domain.StatsByColor)
+		require.NoError(t, err)
+		assert.Equal(t, &domain.CarStats{GroupBy: domain.StatsByColor, Groups: []domain.StatsGroup{
+			{Key: "", Count: 1, Year: one(2015), Prices: []domain.PriceStats{
+				{Currency: "USD", Count: 1, Aggregate: one(11000)},
+			}},
+			{Key: "red", Count: 2, Year: domain.Aggregate{Min: 2018, Max: 2020, Avg: 2019, Median: 2019}, Prices: []domain.PriceStats{
+				{Currency: "USD", Count: 2, Aggregate: domain.Aggregate{Min: 18000, Max: 25000, Avg: 21500, Median: 21500}},
+			}},
+			{Key: "white", Count: 1, Year: one(2020), Prices: []domain.PriceStats{
+				{Currency: "EUR", Count: 1, Aggregate: one(20000)},
+			}},
+		}}, stats)
+
+		stats, err = repo.Stats(ctx, domain.CarFilter{Make: "Ford"}, "")
+		require.NoError(t, err)
+		assert.Equal(t, &domain.CarStats{Groups: []domain.StatsGroup{{Prices: []domain.PriceStats{}}}}, stats)
+
+		stats, err = repo.Stats(ctx, domain.CarFilter{Make: "Ford"}, domain.StatsByModel)
+		require.NoError(t, err)
+		assert.Equal(t, &domain.CarStats{GroupBy: domain.StatsByModel, Groups: []domain.StatsGroup{}}, stats)
+	})
+
+	t.Run("Attributes", func(t *testing.T) {
+		repo := newRepo(t)
+		wagon := golf
+		wagon.Mileage, wagon.Condition, wagon.FuelType, wagon.Transmission = 84000, domain.ConditionUsed, domain.FuelDiesel, domain.TransmissionManual
+		wagon.BodyType, wagon.Doors, wagon.EngineDisplacement, wagon.Color = domain.BodyWagon, 5, 1968, "silver"
+		_, err := repo.CreateBatch(ctx, []domain.Car{camry, civic, wagon})
+		require.NoError(t, err)
+
+		got, err := repo.GetByID(ctx, wagon.ID)
+		require.NoError(t, err)
+		assert.Equal(t, wagon, stripCar(t, got))
+
+		page, err := repo.GetAll(ctx, domain.CarFilter{BodyType: domain.BodyWagon, FuelType: domain.FuelDiesel, Doors: 5, MileageMin: 50000, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Car{wagon}, stripCars(t, page))
+
+		page, err = repo.GetAll(ctx, domain.CarFilter{Sort: "-mileage", Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, wagon.ID, page[0].ID)
+
+		cleared := wagon
+		cleared.Doors, cleared.BodyType, cleared.Color = 0, "", ""
+		updated, err := repo.Update(ctx, wagon.ID, cleared)
+		require.NoError(t, err)
+		assert.Equal(t, cleared, stripCar(t, updated))
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.Create(ctx, camry)
+		require.NoError(t, err)
+
+		changed := camry
+		changed.Price = usd(23000)
+		updated, err := repo.Update(ctx, camry.ID, changed)
+		require.NoError(t, err)
+		assert.False(t, updated.UpdatedAt.Before(updated.CreatedAt))
+		assert.Equal(t, changed, stripCar(t, updated))
+
+		_, err = repo.Update(ctx, "missing", changed)
+		assert.ErrorIs(t, err, domain.ErrCarNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.Create(ctx, camry)
+		require.NoError(t, err)
+
+		require.NoError(t, repo.Delete(ctx, camry.ID))
+
+		_, err = repo.GetByID(ctx, camry.ID)
+		assert.ErrorIs(t, err, domain.ErrCarNotFound)
+
+		assert.ErrorIs(t, repo.Delete(ctx, camry.ID), domain.ErrCarNotFound)
+	})
+}
+
+// stripCar checks that the repository filled in the timestamps and zeroes
+// them, so the result can be compared with the fixtures.
+func stripCar(t *testing.T, car *domain.Car) domain.Car {
+	t.Helper()
+	assert.False(t, car.CreatedAt.IsZero(), "created_at is set")
+	assert.False(t, car.UpdatedAt.IsZero(), "updated_at is set")
+
+	stripped := *car
+	stripped.CreatedAt = time.Time{}
+	stripped.UpdatedAt = time.Time{}
+	return stripped
+}
+
+func stripCars(t *testing.T, cars []domain.Car) []domain.Car {
+	t.Helper()
+	stripped := make([]domain.Car, len(cars))
+	for i := range cars {
+		stripped[i] = stripCar(t, &cars[i])
+	}
+	return stripped
+}
+
+func usd(amount int64) domain.Money {
+	return domain.Money{Amount: amount, Currency: "USD"}
+}
@@ -0,0 +1,118 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunCatalogRepositoryTests runs the suite for repository.CatalogRepository.
+// newRepo must return a repository over an empty catalog.
+func RunCatalogRepositoryTests(t *testing.T, newRepo func(t *testing.T) repository.CatalogRepository) {
+	ctx := context.Background()
+	vw := domain.Make{ID: "volkswagen", Name: "Volkswagen", Aliases: []string{"VW"}}
+	golf := domain.Model{ID: "golf", MakeID: "volkswagen", Name: "Golf", Aliases: []string{"Rabbit"}, YearFrom: 1974}
+	polo := domain.Model{ID: "polo", MakeID: "volkswagen", Name: "Polo", Aliases: []string{}, YearFrom: 1975, YearTo: 2024}
+
+	t.Run("Makes", func(t *testing.T) {
+		repo := newRepo(t)
+		lada := domain.Make{ID: "lada", Name: "Lada", Aliases: []string{}}
+
+		_, err := repo.CreateMake(ctx, vw)
+		require.NoError(t, err)
+		_, err = repo.CreateMake(ctx, lada)
+		require.NoError(t, err)
+
+		got, err := repo.GetMake(ctx, vw.ID)
+		require.NoError(t, err)
+		assert.Equal(t, vw, *got)
+
+		makes, err := repo.ListMakes(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Make{lada, vw}, makes)
+
+		for _, name := range []string{"VOLKSWAGEN", "v.w."} {
+			got, err = repo.FindMake(ctx, name)
+			require.NoError(t, err, name)
+			assert.Equal(t, vw.ID, got.ID)
+		}
+		_, err = repo.FindMake(ctx, "Skoda")
+		assert.ErrorIs(t, err, domain.ErrMakeNotFound)
+
+		renamed := domain.Make{Name: "VW", Aliases: []string{"Volkswagen"}}
+		updated, err := repo.UpdateMake(ctx, vw.ID, renamed)
+		require.NoError(t, err)
+		assert.Equal(t, "VW", updated.Name)
+		got, err = repo.FindMake(ctx, "volkswagen")
+		require.NoError(t, err)
+		assert.Equal(t, "VW", got.Name)
+
+		_, err = repo.UpdateMake(ctx, "missing", renamed)
+		assert.ErrorIs(t, err, domain.ErrMakeNotFound)
+
+		require.NoError(t, repo.DeleteMake(ctx, lada.ID))
+		assert.ErrorIs(t, repo.DeleteMake(ctx, lada.ID), domain.ErrMakeNotFound)
+		_, err = repo.GetMake(ctx, lada.ID)
+		assert.ErrorIs(t, err, domain.ErrMakeNotFound)
+	})
+
+	t.Run("Duplicate names", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.CreateMake(ctx, vw)
+		require.NoError(t, err)
+
+		_, err = repo.CreateMake(ctx, domain.Make{ID: "volkswagen", Name: "Other"})
+		assert.ErrorIs(t, err, domain.ErrDuplicateMake)
+		_, err = repo.CreateMake(ctx, domain.Make{ID: "vw", Name: "V-W"})
+		assert.ErrorIs(t, err, domain.ErrDuplicateMake, "the key of V-W is taken by an alias")
+
+		_, err = repo.GetMake(ctx, "vw")
+		assert.ErrorIs(t, err, domain.ErrMakeNotFound, "the failed create is rolled back")
+	})
+
+	t.Run("Models", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.CreateMake(ctx, vw)
+		require.NoError(t, err)
+
+		_, err = repo.CreateModel(ctx, golf)
+		require.NoError(t, err)
+		_, err = repo.CreateModel(ctx, polo)
+		require.NoError(t, err)
+
+		models, err := repo.ListModels(ctx, vw.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Model{golf, polo}, models)
+
+		got, err := repo.FindModel(ctx, vw.ID, "rabbit")
+		require.NoError(t, err)
+		assert.Equal(t, golf, *got)
+		_, err = repo.FindModel(ctx, "lada", "golf")
+		assert.ErrorIs(t, err, domain.ErrModelNotFound)
+
+		_, err = repo.CreateModel(ctx, domain.Model{ID: "rabbit", MakeID: vw.ID, Name: "Rabbit"})
+		assert.ErrorIs(t, err, domain.ErrDuplicateModel)
+		_, err = repo.CreateModel(ctx, domain.Model{ID: "niva", MakeID: "lada", Name: "Niva"})
+		assert.ErrorIs(t, err, domain.ErrMakeNotFound)
+
+		changed := polo
+		changed.YearTo = 0
+		updated, err := repo.UpdateModel(ctx, vw.ID, polo.ID, changed)
+		require.NoError(t, err)
+		assert.Equal(t, changed, *updated)
+		got, err = repo.GetModel(ctx, vw.ID, polo.ID)
+		require.NoError(t, err)
+		assert.Equal(t, changed, *got)
+
+		require.NoError(t, repo.DeleteModel(ctx, vw.ID, polo.ID))
+		assert.ErrorIs(t, repo.DeleteModel(ctx, vw.ID, polo.ID), domain.ErrModelNotFound)
+
+		require.NoError(t, repo.DeleteMake(ctx, vw.ID))
+		_, err = repo.GetModel(ctx, vw.ID, golf.ID)
+		assert.ErrorIs(t, err, domain.ErrModelNotFound, "models go with their make")
+	})
+}
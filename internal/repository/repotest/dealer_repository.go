@@ -0,0 +1,86 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/repository"
+	"github.com/kefir4iick/crud/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunDealerRepositoryTests runs the suite for repository.DealerRepository.
+// newRepos must return a dealer and a car repository over the same empty
+// store.
+func RunDealerRepositoryTests(t *testing.T, newRepos func(t *testing.T) (repository.DealerRepository, repository.CarRepository)) {
+	ctx := tenant.WithID(context.Background(), "t1")
+	north := domain.Dealer{Name: "North Lot", Address: "1 Main St", Location: &domain.Coordinates{Latitude: 52.52, Longitude: 13.405}}
+	south := domain.Dealer{Name: "South Lot"}
+
+	t.Run("CRUD", func(t *testing.T) {
+		dealers, _ := newRepos(t)
+
+		created, err := dealers.Create(ctx, north)
+		require.NoError(t, err)
+		assert.NotZero(t, created.ID)
+		assert.False(t, created.CreatedAt.IsZero())
+		assert.Equal(t, north.Location, created.Location)
+
+		got, err := dealers.GetByID(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created, got)
+
+		_, err = dealers.GetByID(tenant.WithID(context.Background(), "t2"), created.ID)
+		assert.ErrorIs(t, err, domain.ErrDealerNotFound, "dealers are tenant scoped")
+
+		_, err = dealers.Create(ctx, south)
+		require.NoError(t, err)
+		all, err := dealers.GetAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		assert.Equal(t, "North Lot", all[0].Name)
+
+		moved := domain.Dealer{Name: "North Lot", Address: "2 Main St"}
+		updated, err := dealers.Update(ctx, created.ID, moved)
+		require.NoError(t, err)
+		assert.Equal(t, "2 Main St", updated.Address)
+		assert.Nil(t, updated.Location)
+		assert.Equal(t, created.CreatedAt, updated.CreatedAt)
+
+		_, err = dealers.Update(ctx, created.ID+100, moved)
+		assert.ErrorIs(t, err, domain.ErrDealerNotFound)
+
+		require.NoError(t, dealers.Delete(ctx, created.ID))
+		assert.ErrorIs(t, dealers.Delete(ctx, created.ID), domain.ErrDealerNotFound)
+	})
+
+	t.Run("Inventory", func(t *testing.T) {
+		dealers, cars := newRepos(t)
+		lot, err := dealers.Create(ctx, north)
+		require.NoError(t, err)
+
+		camry := domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: usd(25000), DealerID: lot.ID}
+		created, err := cars.Create(ctx, camry)
+		require.NoError(t, err)
+		assert.Equal(t, lot.ID, created.DealerID)
+
+		civic := domain.Car{ID: "2", Make: "Honda", Model: "Civic", Year: 2018, Price: usd(18000), DealerID: lot.ID + 100}
+		_, err = cars.Create(ctx, civic)
+		assert.ErrorIs(t, err, domain.ErrDealerNotFound)
+		civic.DealerID = 0
+		_, err = cars.Create(ctx, civic)
+		require.NoError(t, err)
+
+		page, err := cars.GetAll(ctx, domain.CarFilter{DealerID: lot.ID, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, page, 1)
+		assert.Equal(t, camry.ID, page[0].ID)
+
+		assert.ErrorIs(t, dealers.Delete(ctx, lot.ID), domain.ErrDealerHasInventory)
+
+		require.NoError(t, cars.Delete(ctx, camry.ID))
+		require.NoError(t, dealers.Delete(ctx, lot.ID))
+	})
+}
@@ -0,0 +1,321 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/repository"
+	"github.com/kefir4iick/crud/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunUnitOfWorkTests checks commit and rollback behaviour. newUoW must return a
+// unit of work and a repository outside it, both over the same empty store.
+func RunUnitOfWorkTests(t *testing.T, newUoW func(t *testing.T) (repository.UnitOfWork, repository.CarRepository)) {
+	ctx := tenant.WithID(context.Background(), "t1")
+	camry := domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: usd(25000), Status: domain.StatusAvailable}
+
+	t.Run("Commit", func(t *testing.T) {
+		uow, repo := newUoW(t)
+
+		err := uow.WithTx(ctx, func(repos repository.Repositories) error {
+			_, err := repos.Cars.Create(ctx, camry)
+			return err
+		})
+		require.NoError(t, err)
+
+		got, err := repo.GetByID(ctx, camry.ID)
+		require.NoError(t, err)
+		assert.Equal(t, camry, stripCar(t, got))
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		uow, repo := newUoW(t)
+		errAbort := errors.New("abort")
+
+		err := uow.WithTx(ctx, func(repos repository.Repositories) error {
+			if _, err := repos.Cars.Create(ctx, camry); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		_, err = repo.GetByID(ctx, camry.ID)
+		assert.ErrorIs(t, err, domain.ErrCarNotFound)
+	})
+
+	t.Run("Lock and update", func(t *testing.T) {
+		uow, repo := newUoW(t)
+		_, err := repo.Create(ctx, camry)
+		require.NoError(t, err)
+
+		err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+			car, err := repos.Cars.GetByIDForUpdate(ctx, camry.ID)
+			if err != nil {
+				return err
+			}
+			car.Price = usd(23000)
+			_, err = repos.Cars.Update(ctx, car.ID, *car)
+			return err
+		})
+		require.NoError(t, err)
+
+		got, err := repo.GetByID(ctx, camry.ID)
+		require.NoError(t, err)
+		assert.Equal(t, usd(23000), got.Price)
+	})
+
+	t.Run("Price history", func(t *testing.T) {
+		uow, repo := newUoW(t)
+		_, err := repo.Create(ctx, camry)
+		require.NoError(t, err)
+		since := time.Now().Add(-time.Minute)
+
+		var updated *domain.Car
+		for _, price := range []domain.Money{usd(23000), usd(24000)} {
+			err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+				car, err := repos.Cars.GetByIDForUpdate(ctx, camry.ID)
+				if err != nil {
+					return err
+				}
+				if err := repos.Prices.Record(ctx, car.ID, car.Price, price); err != nil {
+					return err
+				}
+				car.Price = price
+				updated, err = repos.Cars.Update(ctx, car.ID, *car)
+				return err
+			})
+			require.NoError(t, err)
+		}
+
+		require.NotNil(t, updated.OriginalPrice, "the update already sees its own change")
+		assert.Equal(t, usd(25000), *updated.OriginalPrice)
+		require.NotNil(t, updated.DiscountPercent)
+		assert.Equal(t, 4.0, *updated.DiscountPercent)
+
+		var changes []domain.PriceChange
+		err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+			changes, err = repos.Prices.List(ctx, camry.ID)
+			return err
+		})
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		assert.Equal(t, usd(25000), changes[0].OldPrice)
+		assert.Equal(t, usd(23000), changes[0].NewPrice)
+		assert.Equal(t, usd(24000), changes[1].NewPrice)
+		assert.False(t, changes[1].ChangedAt.Before(changes[0].ChangedAt))
+
+		civic := domain.Car{ID: "2", Make: "Honda", Model: "Civic", Year: 2018, Price: usd(18000)}
+		_, err = repo.Create(ctx, civic)
+		require.NoError(t, err)
+
+		page, err := repo.GetAll(ctx, domain.CarFilter{PriceDroppedSince: &since, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, page, 1)
+		assert.Equal(t, camry.ID, page[0].ID)
+
+		later := time.Now().Add(time.Minute)
+		page, err = repo.GetAll(ctx, domain.CarFilter{PriceDroppedSince: &later, Limit: 10})
+		require.NoError(t, err)
+		assert.Empty(t, page)
+	})
+
+	t.Run("Attachments", func(t *testing.T) {
+		uow, repo := newUoW(t)
+		_, err := repo.Create(ctx, camry)
+		require.NoError(t, err)
+		checksum := strings.Repeat("ab", 32)
+		photo := domain.Attachment{CarID: camry.ID, Filename: "front.jpg", ContentType: "image/jpeg", Size: 1024, Checksum: checksum}
+
+		var created []domain.Attachment
+		err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+			for i := 0; i < 2; i++ {
+				a, err := repos.Attachments.Create(ctx, photo)
+				if err != nil {
+					return err
+				}
+				created = append(created, *a)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, created, 2)
+		assert.NotZero(t, created[0].ID)
+		assert.False(t, created[0].CreatedAt.IsZero())
+		photo.ID, photo.CreatedAt = created[0].ID, created[0].CreatedAt
+		assert.Equal(t, photo, created[0])
+
+		err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+			list, err := repos.Attachments.List(ctx, camry.ID)
+			require.NoError(t, err)
+			assert.Equal(t, created, list)
+
+			got, err := repos.Attachments.Get(ctx, camry.ID, created[1].ID)
+			require.NoError(t, err)
+			assert.Equal(t, created[1], *got)
+			_, err = repos.Attachments.Get(ctx, "2", created[1].ID)
+			assert.ErrorIs(t, err, domain.ErrAttachmentNotFound, "attachments belong to their car")
+
+			deleted, err := repos.Attachments.Delete(ctx, camry.ID, created[0].ID)
+			require.NoError(t, err)
+			assert.Equal(t, created[0], *deleted)
+			_, err = repos.Attachments.Delete(ctx, camry.ID, created[0].ID)
+			assert.ErrorIs(t, err, domain.ErrAttachmentNotFound)
+
+			refs, err := repos.Attachments.LockContent(ctx, checksum)
+			require.NoError(t, err)
+			assert.Equal(t, 1, refs)
+			return nil
+		})
+		require.NoError(t, err)
+
+		other := tenant.WithID(context.Background(), "t2")
+		err = uow.WithTx(other, func(repos repository.Repositories) error {
+			refs, err := repos.Attachments.LockContent(other, checksum)
+			require.NoError(t, err)
+			assert.Zero(t, refs, "content is counted per tenant")
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, repo.Delete(ctx, camry.ID))
+		err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+			refs, err := repos.Attachments.LockContent(ctx, checksum)
+			require.NoError(t, err)
+			assert.Zero(t, refs, "attachments go with their car")
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Status history", func(t *testing.T) {
+		uow, repo := newUoW(t)
+		_, err := repo.Create(ctx, camry)
+		require.NoError(t, err)
+
+		var updated *domain.Car
+		for _, change := range []domain.StatusChange{
+			{From: domain.StatusAvailable, To: domain.StatusReserved, Reason: "deposit paid"},
+			{From: domain.StatusReserved, To: domain.StatusSold},
+		} {
+			err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+				car, err := repos.Cars.GetByIDForUpdate(ctx, camry.ID)
+				if err != nil {
+					return err
+				}
+				if err := repos.Statuses.Record(ctx, car.ID, change); err != nil {
+					return err
+				}
+				car.Status = change.To
+				updated, err = repos.Cars.Update(ctx, car.ID, *car)
+				return err
+			})
+			require.NoError(t, err)
+		}
+		assert.Equal(t, domain.StatusSold, updated.Status)
+
+		var changes []domain.StatusChange
+		err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+			changes, err = repos.Statuses.List(ctx, camry.ID)
+			return err
+		})
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		assert.Equal(t, domain.StatusAvailable, changes[0].From)
+		assert.Equal(t, "deposit paid", changes[0].Reason)
+		assert.Equal(t, domain.StatusSold, changes[1].To)
+		assert.Empty(t, changes[1].Reason)
+		assert.False(t, changes[1].ChangedAt.Before(changes[0].ChangedAt))
+
+		civic := domain.Car{ID: "2", Make: "Honda", Model: "Civic", Year: 2018, Price: usd(18000)}
+		_, err = repo.Create(ctx, civic)
+		require.NoError(t, err)
+
+		page, err := repo.GetAll(ctx, domain.CarFilter{Status: domain.StatusAvailable, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, page, 1)
+		assert.Equal(t, civic.ID, page[0].ID)
+	})
+
+	t.Run("Reservations", func(t *testing.T) {
+		uow, repo := newUoW(t)
+		_, err := repo.Create(ctx, camry)
+		require.NoError(t, err)
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+		var first *domain.Reservation
+		err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+			first, err = repos.Reservations.Create(ctx, domain.Reservation{CarID: camry.ID, Holder: "Jane Doe", ExpiresAt: expiresAt})
+			return err
+		})
+		require.NoError(t, err)
+		assert.Equal(t, domain.ReservationActive, first.Status)
+		assert.Empty(t, first.Note)
+		assert.True(t, expiresAt.Equal(first.ExpiresAt))
+
+		got, err := repo.GetByID(ctx, camry.ID)
+		require.NoError(t, err)
+		require.NotNil(t, got.ReservedUntil)
+		assert.True(t, expiresAt.Equal(*got.ReservedUntil))
+
+		err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+			_, err := repos.Reservations.Create(ctx, domain.Reservation{CarID: camry.ID, Holder: "John Roe", ExpiresAt: expiresAt})
+			return err
+		})
+		assert.ErrorIs(t, err, domain.ErrCarReserved, "one active reservation per car")
+
+		err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+			active, err := repos.Reservations.GetActive(ctx, camry.ID)
+			if err != nil {
+				return err
+			}
+			assert.Equal(t, first.ID, active.ID)
+
+			active.Status, active.Note = domain.ReservationCancelled, "changed their mind"
+			ended, err := repos.Reservations.Update(ctx, *active)
+			if err != nil {
+				return err
+			}
+			assert.Equal(t, domain.ReservationCancelled, ended.Status)
+			assert.Equal(t, "changed their mind", ended.Note)
+
+			_, err = repos.Reservations.GetActive(ctx, camry.ID)
+			assert.ErrorIs(t, err, domain.ErrReservationNotFound)
+
+			_, err = repos.Reservations.Create(ctx, domain.Reservation{CarID: camry.ID, Holder: "John Roe", ExpiresAt: expiresAt})
+			return err
+		})
+		require.NoError(t, err, "an ended reservation no longer holds the car")
+
+		var reservations []domain.Reservation
+		err = uow.WithTx(ctx, func(repos repository.Repositories) error {
+			reservations, err = repos.Reservations.List(ctx, camry.ID)
+			if err != nil {
+				return err
+			}
+			_, err = repos.Reservations.Get(ctx, camry.ID, first.ID+100)
+			assert.ErrorIs(t, err, domain.ErrReservationNotFound)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, reservations, 2)
+		assert.Equal(t, first.ID, reservations[0].ID)
+		assert.Equal(t, "John Roe", reservations[1].Holder)
+	})
+
+	t.Run("Lock missing", func(t *testing.T) {
+		uow, _ := newUoW(t)
+
+		err := uow.WithTx(ctx, func(repos repository.Repositories) error {
+			_, err := repos.Cars.GetByIDForUpdate(ctx, "missing")
+			return err
+		})
+		assert.ErrorIs(t, err, domain.ErrCarNotFound)
+	})
+}
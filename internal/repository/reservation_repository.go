@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/kefir4iick/crud/internal/domain"
+)
+
+// ReservationRepository keeps the reservations of cars. It is only available
+// inside a UnitOfWork, so a reservation starts and ends in the same
+// transaction as the status change of its car.
+type ReservationRepository interface {
+	// Create returns domain.ErrCarReserved when the car already has an
+	// active reservation.
+	Create(ctx context.Context, r domain.Reservation) (*domain.Reservation, error)
+	Get(ctx context.Context, carID string, id int64) (*domain.Reservation, error)
+	// GetActive returns domain.ErrReservationNotFound when the car has no
+	// active reservation.
+	GetActive(ctx context.Context, carID string) (*domain.Reservation, error)
+	// List returns the reservations of a car, oldest first.
+	List(ctx context.Context, carID string) ([]domain.Reservation, error)
+	// Update stores the note, status and expiry of r.
+	Update(ctx context.Context, r domain.Reservation) (*domain.Reservation, error)
+}
+
+// ExpiredReservationRepository finds the reservations the sweeper has to end.
+// Unlike the other repositories it is not scoped to a tenant.
+type ExpiredReservationRepository interface {
+	// ListExpired returns up to limit active reservations past their expiry
+	// with an ID above after, in ID order, so that a sweep can page past
+	// reservations it failed to end.
+	ListExpired(ctx context.Context, after int64, limit int) ([]domain.ExpiredReservation, error)
+}
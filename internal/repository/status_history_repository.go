@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/kefir4iick/crud/internal/domain"
+)
+
+// StatusHistoryRepository keeps the status changes of cars. Like
+// PriceHistoryRepository it is only available inside a UnitOfWork.
+type StatusHistoryRepository interface {
+	Record(ctx context.Context, carID string, change domain.StatusChange) error
+	// List returns the changes of a car, oldest first.
+	List(ctx context.Context, carID string) ([]domain.StatusChange, error)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import "context"
+
+// Repositories are the repositories bound to a single transaction.
+type Repositories struct {
+	Cars         CarRepository
+	Prices       PriceHistoryRepository
+	Attachments  AttachmentRepository
+	Dealers      DealerRepository
+	Statuses     StatusHistoryRepository
+	Reservations ReservationRepository
+}
+
+type UnitOfWork interface {
+	// WithTx runs fn inside a transaction, committing when it returns nil and
+	// rolling back otherwise. Implementations may run fn again when the
+	// transaction fails to serialize, so fn must not have side effects outside
+	// the repositories it is given.
+	WithTx(ctx context.Context, fn func(repos Repositories) error) error
+}
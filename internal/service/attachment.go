@@ -0,0 +1,358 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kefir4iick/crud/internal/auth"
+	"github.com/kefir4iick/crud/internal/blob"
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/repository"
+	"github.com/kefir4iick/crud/internal/tenant"
+)
+
+type AttachmentService interface {
+	// Upload stores content as an attachment of a car. The content type is
+	// sniffed from the content; what the client claims is not trusted.
+	Upload(ctx context.Context, carID, filename string, content io.Reader) (*domain.Attachment, error)
+	List(ctx context.Context, carID string) ([]domain.Attachment, error)
+	// Open returns an attachment with its content, which the caller must
+	// close.
+	Open(ctx context.Context, carID string, id int64) (*domain.Attachment, io.ReadCloser, error)
+	Delete(ctx context.Context, carID string, id int64) error
+}
+
+// DefaultMaxAttachmentSize is the upload limit used when none is configured.
+const DefaultMaxAttachmentSize = 10 << 20
+
+// attachmentTypes are the photo and document formats http.DetectContentType
+// recognises and browsers can display.
+var attachmentTypes = []string{
+	"image/jpeg",
+	"image/png",
+	"image/gif",
+	"image/webp",
+	"application/pdf",
+}
+
+type attachmentService struct {
+	uow     repository.UnitOfWork
+	blobs   blob.Store
+	maxSize int64
+	authz   Authorizer
+	logger  *slog.Logger
+}
+
+// NewAttachmentService returns the service behind the attachment endpoints.
+// Uploads are held in memory while they are checked, so maxSize also bounds
+// the memory an upload takes; zero means DefaultMaxAttachmentSize. A nil
+// authz allows every call.
+func NewAttachmentService(uow repository.UnitOfWork, blobs blob.Store, maxSize int64, authz Authorizer, logger *slog.Logger) AttachmentService {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxAttachmentSize
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &attachmentService{uow: uow, blobs: blobs, maxSize: maxSize, authz: authz, logger: logger}
+}
+
+func (s *attachmentService) authorize(ctx context.Context, action auth.Action) error {
+	if s.authz == nil {
+		return nil
+	}
+	return s.authz.Authorize(ctx, action)
+}
+
+// contentKey is the blob key of the content with checksum. Content is shared
+// within a tenant only, matching what LockContent counts.
+func contentKey(ctx context.Context, checksum string) (string, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return "", domain.ErrTenantRequired
+	}
+	return path.Join(tenantID, checksum[:2], checksum), nil
+}
+
+// releaseContent deletes the blobs of checksums that no attachment refers to
+// any more. It runs once the transaction that removed the references has
+// committed, in a transaction of its own that locks each checksum and counts
+// its references again, so content an upload started to use in between is
+// kept. Failures are only logged: the blobs are then left behind unused,
+// which wastes space but, unlike deleting them before the commit, never
+// loses the content of an attachment that still exists.
+func releaseContent(ctx context.Context, uow repository.UnitOfWork, blobs blob.Store, logger *slog.Logger, checksums []string) {
+	checksums = slices.Clone(checksums)
+	// A fixed lock order keeps two releases from deadlocking.
+	slices.Sort(checksums)
+	checksums = slices.Compact(checksums)
+	if len(checksums) == 0 {
+		return
+	}
+
+	// The client may have gone away once its request was done.
+	ctx = context.WithoutCancel(ctx)
+	err := uow.WithTx(ctx, func(repos repository.Repositories) error {
+		for _, checksum := range checksums {
+			refs, err := repos.Attachments.LockContent(ctx, checksum)
+			if err != nil {
+				return err
+			}
+			if refs > 0 {
+				continue
+			}
+
+			key, err := contentKey(ctx, checksum)
+			if err != nil {
+				return err
+			}
+			if err := blobs.Delete(ctx, key); err != nil {
+				return fmt.Errorf("failed to delete attachment content: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.WarnContext(ctx, "failed to release attachment content",
+			slog.Any("checksums", checksums), slog.Any("error", err))
+	}
+}
+
+// lockUnsoldCar locks the car whose attachments are about to change, so that
+// it cannot be sold meanwhile, and refuses cars that are already sold.
+func lockUnsoldCar(ctx context.Context, repos repository.Repositories, carID string) error {
+	car, err := repos.Cars.GetByIDForUpdate(ctx, carID)
+	if err != nil {
+		return fmt.Errorf("failed to get car: %w", err)
+	}
+	if car.Status == domain.StatusSold {
+		return domain.ErrCarSold
+	}
+	return nil
+}
+
+// sniffContentType returns the media type of content without parameters, or
+// ErrUnsupportedMediaType.
+func sniffContentType(content []byte) (string, error) {
+	detected := http.DetectContentType(content)
+	mediaType, _, err := mime.ParseMediaType(detected)
+	if err != nil || !slices.Contains(attachmentTypes, mediaType) {
+		return "", fmt.Errorf("%w: %s, expected one of %s",
+			domain.ErrUnsupportedMediaType, detected, strings.Join(attachmentTypes, ", "))
+	}
+	return mediaType, nil
+}
+
+// cleanFilename keeps the last element of a client's file name, which may be
+// a full path, and shortens it to fit the column.
+func cleanFilename(name string) string {
+	name = strings.TrimSpace(name)
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" || name == "." || name == ".." {
+		return "attachment"
+	}
+	return name
+}
+
+func (s *attachmentService) Upload(ctx context.Context, carID, filename string, content io.Reader) (*domain.Attachment, error) {
+	if err := s.authorize(ctx, auth.ActionUpdateCars); err != nil {
+		return nil, err
+	}
+
+	if carID == "" {
+		return nil, errors.New("id is required")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, s.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, fmt.Errorf("%w: the limit is %d bytes", domain.ErrAttachmentTooLarge, s.maxSize)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: attachment is empty", domain.ErrInvalidInput)
+	}
+
+	contentType, err := sniffContentType(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	input := domain.Attachment{
+		CarID:       carID,
+		Filename:    cleanFilename(filename),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Checksum:    hex.EncodeToString(sum[:]),
+	}
+	key, err := contentKey(ctx, input.Checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		attachment *domain.Attachment
+		stored     bool
+	)
+	err = s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		if err := lockUnsoldCar(ctx, repos, carID); err != nil {
+			return err
+		}
+
+		refs, err := repos.Attachments.LockContent(ctx, input.Checksum)
+		if err != nil {
+			return err
+		}
+		// Putting the same content again is harmless, so a retried
+		// transaction may do it twice.
+		if refs == 0 {
+			if err := s.blobs.Put(ctx, key, bytes.NewReader(data)); err != nil {
+				return fmt.Errorf("failed to store attachment content: %w", err)
+			}
+			stored = true
+		}
+
+		attachment, err = repos.Attachments.Create(ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to create attachment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if stored {
+			// Unless another upload has started to use it in the meantime.
+			releaseContent(ctx, s.uow, s.blobs, s.logger, []string{input.Checksum})
+		}
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "attachment uploaded",
+		slog.String("car_id", carID),
+		slog.Int64("attachment_id", attachment.ID),
+		slog.Bool("deduplicated", !stored))
+	return attachment, nil
+}
+
+func (s *attachmentService) List(ctx context.Context, carID string) ([]domain.Attachment, error) {
+	if err := s.authorize(ctx, auth.ActionReadCars); err != nil {
+		return nil, err
+	}
+
+	if carID == "" {
+		return nil, errors.New("id is required")
+	}
+
+	var attachments []domain.Attachment
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		if _, err := repos.Cars.GetByID(ctx, carID); err != nil {
+			return fmt.Errorf("failed to get car: %w", err)
+		}
+
+		var err error
+		attachments, err = repos.Attachments.List(ctx, carID)
+		if err != nil {
+			return fmt.Errorf("failed to list attachments: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (s *attachmentService) Open(ctx context.Context, carID string, id int64) (*domain.Attachment, io.ReadCloser, error) {
+	if err := s.authorize(ctx, auth.ActionReadCars); err != nil {
+		return nil, nil, err
+	}
+
+	if carID == "" {
+		return nil, nil, errors.New("id is required")
+	}
+
+	var attachment *domain.Attachment
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		var err error
+		attachment, err = repos.Attachments.Get(ctx, carID, id)
+		if err != nil {
+			return fmt.Errorf("failed to get attachment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := contentKey(ctx, attachment.Checksum)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.blobs.Open(ctx, key)
+	if errors.Is(err, blob.ErrNotFound) {
+		// The attachment was deleted since it was read.
+		return nil, nil, domain.ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open attachment content: %w", err)
+	}
+
+	return attachment, content, nil
+}
+
+func (s *attachmentService) Delete(ctx context.Context, carID string, id int64) error {
+	if err := s.authorize(ctx, auth.ActionUpdateCars); err != nil {
+		return err
+	}
+
+	if carID == "" {
+		return errors.New("id is required")
+	}
+
+	var attachment *domain.Attachment
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		if err := lockUnsoldCar(ctx, repos, carID); err != nil {
+			return err
+		}
+
+		var err error
+		attachment, err = repos.Attachments.Delete(ctx, carID, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete attachment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	releaseContent(ctx, s.uow, s.blobs, s.logger, []string{attachment.Checksum})
+
+	s.logger.InfoContext(ctx, "attachment deleted", slog.String("car_id", carID), slog.Int64("attachment_id", id))
+	return nil
+}
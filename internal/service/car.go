@@ -4,42 +4,357 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
 
+	"github.com/kefir4iick/crud/internal/auth"
+	"github.com/kefir4iick/crud/internal/blob"
 	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/metrics"
 	"github.com/kefir4iick/crud/internal/repository"
+	"github.com/kefir4iick/crud/internal/vin"
 )
 
 type CarService interface {
 	Create(ctx context.Context, input domain.Car) (*domain.Car, error)
+	CreateBatch(ctx context.Context, inputs []domain.Car) ([]domain.Car, error)
 	GetByID(ctx context.Context, id string) (*domain.Car, error)
-	GetAll(ctx context.Context, limit, offset int) ([]domain.Car, error)
+	GetByVIN(ctx context.Context, vin string) (*domain.Car, error)
+	GetAll(ctx context.Context, filter domain.CarFilter) ([]domain.Car, error)
+	// Stats summarises the cars matching filter, ignoring its sort order and
+	// paging.
+	Stats(ctx context.Context, filter domain.CarFilter, groupBy domain.StatsGroupBy) (*domain.CarStats, error)
+	GetPriceHistory(ctx context.Context, id string) ([]domain.PriceChange, error)
 	Update(ctx context.Context, id string, input domain.UpdateCarInput) (*domain.Car, error)
+	// Transfer moves a car to another dealer.
+	Transfer(ctx context.Context, id string, dealerID int64) (*domain.Car, error)
+	// Transition changes the status of a car and records why. Cars are
+	// reserved through ReservationService.Create instead, so that every hold
+	// has a holder and an expiry.
+	Transition(ctx context.Context, id string, t domain.Transition, reason string) (*domain.Car, error)
+	GetStatusHistory(ctx context.Context, id string) ([]domain.StatusChange, error)
 	Delete(ctx context.Context, id string) error
 }
 
+const maxBatchSize = 100
+
+// maxNewCarMileage allows for delivery and test drives.
+const maxNewCarMileage = 500
+
+// maxReasonLength matches the reason column of car_status_history.
+const maxReasonLength = 500
+
+// maxColorLength matches the color column of cars.
+const maxColorLength = 50
+
+// Authorizer decides whether the caller in ctx may perform action. It is
+// checked by the service so every transport enforces the same rules.
+type Authorizer interface {
+	Authorize(ctx context.Context, action auth.Action) error
+}
+
 type carService struct {
-	repo repository.CarRepository
+	repo       repository.CarRepository
+	uow        repository.UnitOfWork
+	logger     *slog.Logger
+	authz      Authorizer
+	vinPrefill bool
+
+	defaultCurrency string
+	conversion      *domain.ConversionTable
+
+	catalog       repository.CatalogRepository
+	strictCatalog bool
+
+	blobs blob.Store
 }
 
-func NewCarService(repo repository.CarRepository) CarService {
-	return &carService{repo: repo}
+type Option func(*carService)
+
+// WithAuthorizer enables authorization checks. Without it every call is
+// allowed, which is what a deployment with authentication disabled needs.
+func WithAuthorizer(authz Authorizer) Option {
+	return func(s *carService) {
+		s.authz = authz
+	}
 }
 
-func (s *carService) Create(ctx context.Context, input domain.Car) (*domain.Car, error) {
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *carService) {
+		s.logger = logger
+	}
+}
+
+// WithVINPrefill fills in a missing make or year from the car's VIN.
+func WithVINPrefill() Option {
+	return func(s *carService) {
+		s.vinPrefill = true
+	}
+}
+
+// WithDefaultCurrency sets the currency of prices sent as a bare integer, the
+// format used before prices carried a currency. It defaults to USD.
+func WithDefaultCurrency(code string) Option {
+	return func(s *carService) {
+		s.defaultCurrency = code
+	}
+}
+
+// WithConversionTable allows list queries to filter and sort by price across
+// currencies. Without it they have to name a currency to do so.
+func WithConversionTable(table *domain.ConversionTable) Option {
+	return func(s *carService) {
+		s.conversion = table
+	}
+}
+
+// WithCatalog stores the make and model of cars under their names in the
+// reference catalog, so that aliases and differently spelled names collapse
+// into one, and rejects model years outside the production years of known
+// models. With strict set, makes and models missing from the catalog are
+// rejected as well.
+func WithCatalog(catalog repository.CatalogRepository, strict bool) Option {
+	return func(s *carService) {
+		s.catalog = catalog
+		s.strictCatalog = strict
+	}
+}
+
+// WithAttachmentContent deletes the attachment content of deleted cars from
+// blobs. Without it Delete leaves the content behind.
+func WithAttachmentContent(blobs blob.Store) Option {
+	return func(s *carService) {
+		s.blobs = blobs
+	}
+}
+
+func NewCarService(repo repository.CarRepository, uow repository.UnitOfWork, opts ...Option) CarService {
+	s := &carService{repo: repo, uow: uow, logger: slog.Default(), defaultCurrency: "USD"}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *carService) authorize(ctx context.Context, action auth.Action) error {
+	if s.authz == nil {
+		return nil
+	}
+	return s.authz.Authorize(ctx, action)
+}
+
+func validateCar(input domain.Car) error {
 	if input.Make == "" {
-		return nil, errors.New("make is required")
+		return errors.New("make is required")
 	}
 	if len(input.Make) > 255 {
-		return nil, errors.New("make must be less than 255 characters")
+		return errors.New("make must be less than 255 characters")
 	}
 	if input.Model == "" {
-		return nil, errors.New("model is required")
+		return errors.New("model is required")
 	}
 	if input.Year < 1900 {
-		return nil, errors.New("year must be >= 1900")
+		return errors.New("year must be >= 1900")
+	}
+	if err := validateAttributes(input); err != nil {
+		return err
+	}
+	if input.DealerID < 0 {
+		return fmt.Errorf("%w: dealer id must be positive", domain.ErrInvalidInput)
+	}
+	if input.Status != "" && input.Status != domain.StatusAvailable {
+		return fmt.Errorf("%w: new cars are available; use the transition endpoints to change the status", domain.ErrInvalidInput)
+	}
+	return validatePrice(input.Price)
+}
+
+// normalizeAttributes lower-cases the enum attributes and the color of car so
+// clients may send them in any case, and cars group by color however it was
+// written.
+func normalizeAttributes(car *domain.Car) {
+	car.Condition = domain.Condition(strings.ToLower(string(car.Condition)))
+	car.FuelType = domain.FuelType(strings.ToLower(string(car.FuelType)))
+	car.Transmission = domain.Transmission(strings.ToLower(string(car.Transmission)))
+	car.BodyType = domain.BodyType(strings.ToLower(string(car.BodyType)))
+	car.Color = strings.ToLower(strings.TrimSpace(car.Color))
+}
+
+func validateAttributes(car domain.Car) error {
+	if car.Mileage < 0 {
+		return fmt.Errorf("%w: mileage must not be negative", domain.ErrInvalidInput)
+	}
+	if !car.Condition.Valid() {
+		return fmt.Errorf("%w: unknown condition %q", domain.ErrInvalidInput, car.Condition)
+	}
+	if !car.FuelType.Valid() {
+		return fmt.Errorf("%w: unknown fuel_type %q", domain.ErrInvalidInput, car.FuelType)
+	}
+	if !car.Transmission.Valid() {
+		return fmt.Errorf("%w: unknown transmission %q", domain.ErrInvalidInput, car.Transmission)
+	}
+	if !car.BodyType.Valid() {
+		return fmt.Errorf("%w: unknown body_type %q", domain.ErrInvalidInput, car.BodyType)
+	}
+	if car.Doors < 0 || car.Doors > 9 {
+		return fmt.Errorf("%w: doors must be between 1 and 9", domain.ErrInvalidInput)
+	}
+	if car.EngineDisplacement < 0 {
+		return fmt.Errorf("%w: engine_displacement must not be negative", domain.ErrInvalidInput)
+	}
+	if len(car.Color) > maxColorLength {
+		return fmt.Errorf("%w: color must be at most %d characters", domain.ErrInvalidInput, maxColorLength)
+	}
+	if car.FuelType == domain.FuelElectric && car.EngineDisplacement != 0 {
+		return fmt.Errorf("%w: electric cars have no engine_displacement", domain.ErrInvalidInput)
+	}
+	if car.Condition == domain.ConditionNew && car.Mileage > maxNewCarMileage {
+		return fmt.Errorf("%w: a new car cannot have more than %d km", domain.ErrInvalidInput, maxNewCarMileage)
+	}
+	return nil
+}
+
+func validatePrice(price domain.Money) error {
+	if price.Amount <= 0 {
+		return errors.New("price must be positive")
+	}
+	if !domain.ValidCurrency(price.Currency) {
+		return fmt.Errorf("%w: %q", domain.ErrInvalidCurrency, price.Currency)
+	}
+	return nil
+}
+
+// normalizeCurrency upper-cases the currency of price. A price without one is
+// a bare amount in the whole units of the old API; it is priced in fallback
+// and scaled to its minor unit.
+func normalizeCurrency(price *domain.Money, fallback string) {
+	price.Currency = strings.ToUpper(strings.TrimSpace(price.Currency))
+	if price.Currency == "" {
+		price.Currency = fallback
+		for i := 0; i < domain.CurrencyExponent(fallback); i++ {
+			price.Amount *= 10
+		}
+	}
+}
+
+// checkVIN normalizes car.VIN, validates it and cross-checks make and year
+// against what the VIN encodes. Fields that are still empty are left to
+// validateCar.
+func (s *carService) checkVIN(car *domain.Car) error {
+	if car.VIN == "" {
+		return nil
+	}
+
+	car.VIN = vin.Normalize(car.VIN)
+	if err := vin.Validate(car.VIN); err != nil {
+		return fmt.Errorf("%w: %w", domain.ErrInvalidVIN, err)
+	}
+
+	info := vin.Decode(car.VIN)
+	if s.vinPrefill {
+		if car.Make == "" {
+			car.Make = info.Make
+		}
+		if car.Year == 0 && len(info.ModelYears) > 0 {
+			car.Year = info.ModelYears[0]
+		}
+	}
+
+	if info.Make != "" && car.Make != "" && !strings.EqualFold(car.Make, info.Make) {
+		return fmt.Errorf("%w: manufacturer code %s belongs to %s, not %s", domain.ErrInvalidVIN, info.WMI, info.Make, car.Make)
+	}
+	if len(info.ModelYears) > 0 && car.Year != 0 && !slices.Contains(info.ModelYears, car.Year) {
+		return fmt.Errorf("%w: model year code %c stands for %d or %d, not %d",
+			domain.ErrInvalidVIN, car.VIN[9], info.ModelYears[0], info.ModelYears[1], car.Year)
 	}
-	if input.Price <= 0 {
-		return nil, errors.New("price must be positive")
+	return nil
+}
+
+// checkIdentity resolves make and model against the catalog and checks the
+// VIN. The VIN is checked after resolving so that an alias such as "VW" is
+// compared with the manufacturer under its catalog name; a make that the VIN
+// fills in is resolved afterwards.
+func (s *carService) checkIdentity(ctx context.Context, car *domain.Car) error {
+	prefill := car.Make == ""
+	model, err := s.resolveCatalog(ctx, car)
+	if err != nil {
+		return err
+	}
+	if err := s.checkVIN(car); err != nil {
+		return err
+	}
+	if prefill && car.Make != "" {
+		if model, err = s.resolveCatalog(ctx, car); err != nil {
+			return err
+		}
+	}
+
+	if model != nil && car.Year != 0 && !model.BuiltIn(car.Year) {
+		return fmt.Errorf("%w: %s %s was built %s, not in %d",
+			domain.ErrModelYearMismatch, car.Make, model.Name, productionYears(*model), car.Year)
+	}
+	return nil
+}
+
+// resolveCatalog replaces the make and model of car with their catalog
+// names. It returns the catalog model, or nil when it is unknown or there is
+// no catalog.
+func (s *carService) resolveCatalog(ctx context.Context, car *domain.Car) (*domain.Model, error) {
+	car.Make = strings.TrimSpace(car.Make)
+	car.Model = strings.TrimSpace(car.Model)
+	if s.catalog == nil || car.Make == "" {
+		return nil, nil
+	}
+
+	mk, err := s.catalog.FindMake(ctx, car.Make)
+	if errors.Is(err, domain.ErrMakeNotFound) {
+		if s.strictCatalog {
+			return nil, fmt.Errorf("%w: unknown make %q", domain.ErrUnknownMakeModel, car.Make)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up make: %w", err)
+	}
+	car.Make = mk.Name
+
+	model, err := s.catalog.FindModel(ctx, mk.ID, car.Model)
+	if errors.Is(err, domain.ErrModelNotFound) {
+		if s.strictCatalog {
+			return nil, fmt.Errorf("%w: unknown model %q of %s", domain.ErrUnknownMakeModel, car.Model, mk.Name)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up model: %w", err)
+	}
+	car.Model = model.Name
+
+	return model, nil
+}
+
+func productionYears(m domain.Model) string {
+	switch {
+	case m.YearTo == 0:
+		return fmt.Sprintf("from %d", m.YearFrom)
+	case m.YearFrom == 0:
+		return fmt.Sprintf("until %d", m.YearTo)
+	}
+	return fmt.Sprintf("from %d to %d", m.YearFrom, m.YearTo)
+}
+
+func (s *carService) Create(ctx context.Context, input domain.Car) (*domain.Car, error) {
+	if err := s.authorize(ctx, auth.ActionCreateCars); err != nil {
+		return nil, err
+	}
+
+	normalizeCurrency(&input.Price, s.defaultCurrency)
+	normalizeAttributes(&input)
+	if err := s.checkIdentity(ctx, &input); err != nil {
+		return nil, err
+	}
+	if err := validateCar(input); err != nil {
+		return nil, err
 	}
 
 	car, err := s.repo.Create(ctx, input)
@@ -47,10 +362,50 @@ func (s *carService) Create(ctx context.Context, input domain.Car) (*domain.Car,
 		return nil, fmt.Errorf("failed to create car: %w", err)
 	}
 
+	metrics.CarsCreated(1)
+	s.logger.InfoContext(ctx, "car created", slog.String("car_id", car.ID))
+
 	return car, nil
 }
 
+func (s *carService) CreateBatch(ctx context.Context, inputs []domain.Car) ([]domain.Car, error) {
+	if err := s.authorize(ctx, auth.ActionCreateCars); err != nil {
+		return nil, err
+	}
+
+	if len(inputs) == 0 {
+		return nil, errors.New("at least one car is required")
+	}
+	if len(inputs) > maxBatchSize {
+		return nil, fmt.Errorf("at most %d cars can be created at once", maxBatchSize)
+	}
+	for i := range inputs {
+		normalizeCurrency(&inputs[i].Price, s.defaultCurrency)
+		normalizeAttributes(&inputs[i])
+		if err := s.checkIdentity(ctx, &inputs[i]); err != nil {
+			return nil, fmt.Errorf("car %d: %w", i, err)
+		}
+		if err := validateCar(inputs[i]); err != nil {
+			return nil, fmt.Errorf("car %d: %w", i, err)
+		}
+	}
+
+	cars, err := s.repo.CreateBatch(ctx, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cars: %w", err)
+	}
+
+	metrics.CarsCreated(len(cars))
+	s.logger.InfoContext(ctx, "cars created", slog.Int("count", len(cars)))
+
+	return cars, nil
+}
+
 func (s *carService) GetByID(ctx context.Context, id string) (*domain.Car, error) {
+	if err := s.authorize(ctx, auth.ActionReadCars); err != nil {
+		return nil, err
+	}
+
 	if id == "" {
 		return nil, errors.New("id is required")
 	}
@@ -63,18 +418,46 @@ func (s *carService) GetByID(ctx context.Context, id string) (*domain.Car, error
 	return car, nil
 }
 
-func (s *carService) GetAll(ctx context.Context, limit, offset int) ([]domain.Car, error) {
-	if limit <= 0 {
-		limit = 10
+func (s *carService) GetByVIN(ctx context.Context, v string) (*domain.Car, error) {
+	if err := s.authorize(ctx, auth.ActionReadCars); err != nil {
+		return nil, err
 	}
-	if limit > 100 {
-		limit = 100
+
+	v = vin.Normalize(v)
+	if err := vin.Validate(v); err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidVIN, err)
 	}
-	if offset < 0 {
-		offset = 0
+
+	car, err := s.repo.GetByVIN(ctx, v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get car: %w", err)
 	}
 
-	cars, err := s.repo.GetAll(ctx, limit, offset)
+	return car, nil
+}
+
+func (s *carService) GetAll(ctx context.Context, filter domain.CarFilter) ([]domain.Car, error) {
+	if err := s.authorize(ctx, auth.ActionReadCars); err != nil {
+		return nil, err
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if err := s.prepareFilter(&filter); err != nil {
+		return nil, err
+	}
+	if filter.DealerID != 0 {
+		return s.getAllAtDealer(ctx, filter)
+	}
+
+	cars, err := s.repo.GetAll(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cars: %w", err)
 	}
@@ -82,66 +465,500 @@ func (s *carService) GetAll(ctx context.Context, limit, offset int) ([]domain.Ca
 	return cars, nil
 }
 
+// getAllAtDealer tells a dealer without cars from one that does not exist.
+func (s *carService) getAllAtDealer(ctx context.Context, filter domain.CarFilter) ([]domain.Car, error) {
+	var cars []domain.Car
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		if _, err := repos.Dealers.GetByID(ctx, filter.DealerID); err != nil {
+			return fmt.Errorf("failed to get dealer: %w", err)
+		}
+
+		var err error
+		cars, err = repos.Cars.GetAll(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to get cars: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cars, nil
+}
+
+func (s *carService) Stats(ctx context.Context, filter domain.CarFilter, groupBy domain.StatsGroupBy) (*domain.CarStats, error) {
+	if err := s.authorize(ctx, auth.ActionReadCars); err != nil {
+		return nil, err
+	}
+
+	groupBy = domain.StatsGroupBy(strings.ToLower(string(groupBy)))
+	if !groupBy.Valid() {
+		return nil, fmt.Errorf("%w: cannot group by %q", domain.ErrInvalidInput, groupBy)
+	}
+
+	filter.Sort, filter.Limit, filter.Offset = "", 0, 0
+	if err := s.prepareFilter(&filter); err != nil {
+		return nil, err
+	}
+
+	if filter.DealerID == 0 {
+		stats, err := s.repo.Stats(ctx, filter, groupBy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get car stats: %w", err)
+		}
+		return stats, nil
+	}
+
+	var stats *domain.CarStats
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		if _, err := repos.Dealers.GetByID(ctx, filter.DealerID); err != nil {
+			return fmt.Errorf("failed to get dealer: %w", err)
+		}
+
+		var err error
+		stats, err = repos.Cars.Stats(ctx, filter, groupBy)
+		if err != nil {
+			return fmt.Errorf("failed to get car stats: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// prepareFilter validates filter and decides how prices are compared: within
+// the requested currency, or across currencies with the conversion table.
+func (s *carService) prepareFilter(filter *domain.CarFilter) error {
+	switch filter.SortField() {
+	case domain.CarSortID, domain.CarSortPrice, domain.CarSortYear, domain.CarSortCreatedAt, domain.CarSortMileage:
+	default:
+		return fmt.Errorf("%w: cannot sort by %q", domain.ErrInvalidInput, filter.SortField())
+	}
+
+	if filter.YearMin != 0 && filter.YearMax != 0 && filter.YearMin > filter.YearMax {
+		return fmt.Errorf("%w: year_min is greater than year_max", domain.ErrInvalidInput)
+	}
+	if filter.PriceMin != 0 && filter.PriceMax != 0 && filter.PriceMin > filter.PriceMax {
+		return fmt.Errorf("%w: price_min is greater than price_max", domain.ErrInvalidInput)
+	}
+	if filter.DealerID < 0 {
+		return fmt.Errorf("%w: dealer_id must be positive", domain.ErrInvalidInput)
+	}
+	filter.Status = domain.CarStatus(strings.ToLower(string(filter.Status)))
+	if filter.Status != "" && !filter.Status.Valid() {
+		return fmt.Errorf("%w: unknown status %q", domain.ErrInvalidInput, filter.Status)
+	}
+	if filter.MileageMin != 0 && filter.MileageMax != 0 && filter.MileageMin > filter.MileageMax {
+		return fmt.Errorf("%w: mileage_min is greater than mileage_max", domain.ErrInvalidInput)
+	}
+
+	attrs := domain.Car{
+		Condition:    filter.Condition,
+		FuelType:     filter.FuelType,
+		Transmission: filter.Transmission,
+		BodyType:     filter.BodyType,
+	}
+	normalizeAttributes(&attrs)
+	filter.Condition, filter.FuelType, filter.Transmission, filter.BodyType = attrs.Condition, attrs.FuelType, attrs.Transmission, attrs.BodyType
+	if err := validateAttributes(attrs); err != nil {
+		return err
+	}
+
+	filter.Conversion = nil
+	if filter.Currency != "" {
+		filter.Currency = strings.ToUpper(filter.Currency)
+		if !domain.ValidCurrency(filter.Currency) {
+			return fmt.Errorf("%w: %q", domain.ErrInvalidCurrency, filter.Currency)
+		}
+	} else if filter.ComparesPrices() {
+		if s.conversion == nil {
+			return fmt.Errorf("%w: set currency to filter or sort by price", domain.ErrCurrencyMismatch)
+		}
+		filter.Conversion = s.conversion
+	}
+	return nil
+}
+
+// GetPriceHistory returns the price changes of a car, oldest first. The car
+// and its history are read in one transaction so that a concurrent delete
+// cannot turn a missing car into an empty history.
+func (s *carService) GetPriceHistory(ctx context.Context, id string) ([]domain.PriceChange, error) {
+	if err := s.authorize(ctx, auth.ActionReadCars); err != nil {
+		return nil, err
+	}
+
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+
+	var changes []domain.PriceChange
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		if _, err := repos.Cars.GetByID(ctx, id); err != nil {
+			return fmt.Errorf("failed to get car: %w", err)
+		}
+
+		var err error
+		changes, err = repos.Prices.List(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get price history: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
 func (s *carService) Update(ctx context.Context, id string, input domain.UpdateCarInput) (*domain.Car, error) {
+	if err := s.authorize(ctx, auth.ActionUpdateCars); err != nil {
+		return nil, err
+	}
+
 	if id == "" {
 		return nil, errors.New("id is required")
 	}
 
-	existing, err := s.repo.GetByID(ctx, id)
+	var updated *domain.Car
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		existing, err := repos.Cars.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return fmt.Errorf("car not found: %w", err)
+		}
+		if existing == nil {
+			return domain.ErrCarNotFound
+		}
+		if existing.Status == domain.StatusSold {
+			return domain.ErrCarSold
+		}
+
+		oldPrice := existing.Price
+		if err := applyUpdate(existing, input); err != nil {
+			return err
+		}
+		// Cars stored before the catalog existed are only matched against
+		// it when their make, model or year changes.
+		check := s.checkVIN
+		if input.Make != nil || input.Model != nil || input.Year != nil {
+			check = func(car *domain.Car) error { return s.checkIdentity(ctx, car) }
+		}
+		if err := check(existing); err != nil {
+			return err
+		}
+
+		// The change is recorded first so that the updated car already
+		// reports its original price.
+		if existing.Price != oldPrice {
+			if err := repos.Prices.Record(ctx, id, oldPrice, existing.Price); err != nil {
+				return fmt.Errorf("failed to record price change: %w", err)
+			}
+		}
+
+		updated, err = repos.Cars.Update(ctx, id, *existing)
+		if err != nil {
+			return fmt.Errorf("failed to update car: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("car not found: %w", err)
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "car updated", slog.String("car_id", id))
+	return updated, nil
+}
+
+func (s *carService) Transfer(ctx context.Context, id string, dealerID int64) (*domain.Car, error) {
+	if err := s.authorize(ctx, auth.ActionTransferCars); err != nil {
+		return nil, err
+	}
+
+	if id == "" {
+		return nil, errors.New("id is required")
 	}
-	if existing == nil {
-		return nil, domain.ErrCarNotFound
+	if dealerID <= 0 {
+		return nil, fmt.Errorf("%w: dealer_id must be positive", domain.ErrInvalidInput)
 	}
 
+	var (
+		transferred *domain.Car
+		from        int64
+	)
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		car, err := repos.Cars.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return fmt.Errorf("car not found: %w", err)
+		}
+		if car.Status == domain.StatusSold {
+			return domain.ErrCarSold
+		}
+		if car.DealerID == dealerID {
+			return fmt.Errorf("%w: %d", domain.ErrSameDealer, dealerID)
+		}
+		if _, err := repos.Dealers.GetByID(ctx, dealerID); err != nil {
+			return fmt.Errorf("failed to get dealer: %w", err)
+		}
+
+		from = car.DealerID
+		car.DealerID = dealerID
+		transferred, err = repos.Cars.Update(ctx, id, *car)
+		if err != nil {
+			return fmt.Errorf("failed to transfer car: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "car transferred",
+		slog.String("car_id", id),
+		slog.Int64("from_dealer_id", from),
+		slog.Int64("to_dealer_id", dealerID))
+	return transferred, nil
+}
+
+// Transition applies t to the status of a car. The car is locked while the
+// transition is checked, so two clients cannot both reserve the same car.
+func (s *carService) Transition(ctx context.Context, id string, t domain.Transition, reason string) (*domain.Car, error) {
+	if err := s.authorize(ctx, auth.ActionUpdateCars); err != nil {
+		return nil, err
+	}
+
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+	if t == domain.TransitionReserve {
+		return nil, fmt.Errorf("%w: cars are reserved by creating a reservation", domain.ErrInvalidInput)
+	}
+	reason = strings.TrimSpace(reason)
+	if len(reason) > maxReasonLength {
+		return nil, fmt.Errorf("%w: reason must be at most %d characters", domain.ErrInvalidInput, maxReasonLength)
+	}
+
+	var (
+		changed *domain.Car
+		from    domain.CarStatus
+	)
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		car, err := repos.Cars.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return fmt.Errorf("car not found: %w", err)
+		}
+		if car, err = expireLapsedHold(ctx, repos, car); err != nil {
+			return err
+		}
+
+		from = car.Status
+		changed, err = applyTransition(ctx, repos, car, t, reason)
+		if err != nil {
+			return err
+		}
+		// A car that stops being reserved takes its reservation with it.
+		if from == domain.StatusReserved {
+			ended := domain.ReservationCancelled
+			if changed.Status == domain.StatusSold {
+				ended = domain.ReservationCompleted
+			}
+			return endActiveReservation(ctx, repos.Reservations, id, ended)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "car status changed",
+		slog.String("car_id", id),
+		slog.String("from", string(from)),
+		slog.String("to", string(changed.Status)))
+	return changed, nil
+}
+
+// applyTransition moves car through t, records the change with reason and
+// stores the car. It must run in the transaction that locked car.
+func applyTransition(ctx context.Context, repos repository.Repositories, car *domain.Car, t domain.Transition, reason string) (*domain.Car, error) {
+	to, err := car.Status.Apply(t)
+	if err != nil {
+		return nil, err
+	}
+	change := domain.StatusChange{From: car.Status, To: to, Reason: reason}
+	if err := repos.Statuses.Record(ctx, car.ID, change); err != nil {
+		return nil, fmt.Errorf("failed to record status change: %w", err)
+	}
+
+	car.Status = to
+	changed, err := repos.Cars.Update(ctx, car.ID, *car)
+	if err != nil {
+		return nil, fmt.Errorf("failed to change status: %w", err)
+	}
+	return changed, nil
+}
+
+// GetStatusHistory returns the status changes of a car, oldest first, read in
+// one transaction like GetPriceHistory.
+func (s *carService) GetStatusHistory(ctx context.Context, id string) ([]domain.StatusChange, error) {
+	if err := s.authorize(ctx, auth.ActionReadCars); err != nil {
+		return nil, err
+	}
+
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+
+	var changes []domain.StatusChange
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		if _, err := repos.Cars.GetByID(ctx, id); err != nil {
+			return fmt.Errorf("failed to get car: %w", err)
+		}
+
+		var err error
+		changes, err = repos.Statuses.List(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get status history: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func applyUpdate(existing *domain.Car, input domain.UpdateCarInput) error {
 	if input.Make != nil {
 		if *input.Make == "" {
-			return nil, errors.New("make cannot be empty")
+			return errors.New("make cannot be empty")
 		}
 		if len(*input.Make) > 255 {
-			return nil, errors.New("make must be less than 255 characters")
+			return errors.New("make must be less than 255 characters")
 		}
 		existing.Make = *input.Make
 	}
 
 	if input.Model != nil {
 		if *input.Model == "" {
-			return nil, errors.New("model cannot be empty")
+			return errors.New("model cannot be empty")
 		}
 		existing.Model = *input.Model
 	}
 
 	if input.Year != nil {
 		if *input.Year < 1900 {
-			return nil, errors.New("year must be >= 1900")
+			return errors.New("year must be >= 1900")
 		}
 		existing.Year = *input.Year
 	}
 
 	if input.Price != nil {
-		if *input.Price <= 0 {
-			return nil, errors.New("price must be positive")
+		price := *input.Price
+		// A bare amount keeps the car's currency.
+		normalizeCurrency(&price, existing.Price.Currency)
+		if err := validatePrice(price); err != nil {
+			return err
 		}
-		existing.Price = *input.Price
+		existing.Price = price
 	}
 
-	updated, err := s.repo.Update(ctx, id, *existing)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update car: %w", err)
+	if input.VIN != nil {
+		existing.VIN = *input.VIN
 	}
 
-	return updated, nil
+	if input.Mileage != nil {
+		existing.Mileage = *input.Mileage
+	}
+	if input.Condition != nil {
+		existing.Condition = *input.Condition
+	}
+	if input.FuelType != nil {
+		existing.FuelType = *input.FuelType
+	}
+	if input.Transmission != nil {
+		existing.Transmission = *input.Transmission
+	}
+	if input.BodyType != nil {
+		existing.BodyType = *input.BodyType
+	}
+	if input.Doors != nil {
+		existing.Doors = *input.Doors
+	}
+	if input.EngineDisplacement != nil {
+		existing.EngineDisplacement = *input.EngineDisplacement
+	}
+	if input.Color != nil {
+		existing.Color = *input.Color
+	}
+	normalizeAttributes(existing)
+
+	// The combination is checked, not just the fields sent, so that for
+	// example a car cannot be switched to electric while keeping its engine
+	// displacement.
+	return validateAttributes(*existing)
 }
 
 func (s *carService) Delete(ctx context.Context, id string) error {
+	if err := s.authorize(ctx, auth.ActionDeleteCars); err != nil {
+		return err
+	}
+
 	if id == "" {
 		return errors.New("id is required")
 	}
 
-	if err := s.repo.Delete(ctx, id); err != nil {
-		return fmt.Errorf("failed to delete car: %w", err)
+	if err := s.delete(ctx, id); err != nil {
+		return err
+	}
+
+	metrics.CarDeleted()
+	s.logger.InfoContext(ctx, "car deleted", slog.String("car_id", id))
+
+	return nil
+}
+
+// delete deletes a car unless it is sold, since deleting it would also take
+// its history. Deleting cascades to its attachments; with attachment content
+// configured, the content no other attachment refers to is deleted after the
+// commit. The car is locked first so that it cannot be sold, and no attachment
+// can be added, between checking and deleting.
+func (s *carService) delete(ctx context.Context, id string) error {
+	var checksums []string
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		car, err := repos.Cars.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete car: %w", err)
+		}
+		if car.Status == domain.StatusSold {
+			return domain.ErrCarSold
+		}
+
+		if s.blobs != nil {
+			attachments, err := repos.Attachments.List(ctx, id)
+			if err != nil {
+				return fmt.Errorf("failed to list attachments: %w", err)
+			}
+			checksums = make([]string, len(attachments))
+			for i, a := range attachments {
+				checksums[i] = a.Checksum
+			}
+		}
+
+		if err := repos.Cars.Delete(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete car: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	if s.blobs != nil {
+		releaseContent(ctx, s.uow, s.blobs, s.logger, checksums)
+	}
 	return nil
 }
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kefir4iick/crud/internal/auth"
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/repository"
+)
+
+type CatalogService interface {
+	CreateMake(ctx context.Context, input domain.Make) (*domain.Make, error)
+	GetMake(ctx context.Context, id string) (*domain.Make, error)
+	ListMakes(ctx context.Context) ([]domain.Make, error)
+	UpdateMake(ctx context.Context, id string, input domain.Make) (*domain.Make, error)
+	DeleteMake(ctx context.Context, id string) error
+
+	CreateModel(ctx context.Context, makeID string, input domain.Model) (*domain.Model, error)
+	GetModel(ctx context.Context, makeID, id string) (*domain.Model, error)
+	ListModels(ctx context.Context, makeID string) ([]domain.Model, error)
+	UpdateModel(ctx context.Context, makeID, id string, input domain.Model) (*domain.Model, error)
+	DeleteModel(ctx context.Context, makeID, id string) error
+}
+
+type catalogService struct {
+	repo   repository.CatalogRepository
+	authz  Authorizer
+	logger *slog.Logger
+}
+
+// NewCatalogService returns the service behind the catalog endpoints. A nil
+// authz allows every call.
+func NewCatalogService(repo repository.CatalogRepository, authz Authorizer, logger *slog.Logger) CatalogService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &catalogService{repo: repo, authz: authz, logger: logger}
+}
+
+func (s *catalogService) authorize(ctx context.Context, action auth.Action) error {
+	if s.authz == nil {
+		return nil
+	}
+	return s.authz.Authorize(ctx, action)
+}
+
+var catalogIDPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// prepareID defaults id to the slug of name and validates it.
+func prepareID(id *string, name string) error {
+	if *id == "" {
+		*id = domain.Slug(name)
+	}
+	if len(*id) > 64 || !catalogIDPattern.MatchString(*id) {
+		return fmt.Errorf("%w: id must be up to 64 lower case letters, digits and dashes", domain.ErrInvalidInput)
+	}
+	return nil
+}
+
+// prepareNames trims name and aliases, drops aliases whose key repeats the
+// name or an earlier alias, and sorts the rest.
+func prepareNames(name *string, aliases *[]string) error {
+	*name = strings.TrimSpace(*name)
+	if *name == "" {
+		return fmt.Errorf("%w: name is required", domain.ErrInvalidInput)
+	}
+	if len(*name) > 255 {
+		return fmt.Errorf("%w: name must be less than 255 characters", domain.ErrInvalidInput)
+	}
+	if domain.NameKey(*name) == "" {
+		return fmt.Errorf("%w: name must contain a letter or digit", domain.ErrInvalidInput)
+	}
+
+	seen := map[string]bool{domain.NameKey(*name): true}
+	kept := []string{}
+	for _, alias := range *aliases {
+		alias = strings.TrimSpace(alias)
+		if len(alias) > 255 {
+			return fmt.Errorf("%w: aliases must be less than 255 characters", domain.ErrInvalidInput)
+		}
+		key := domain.NameKey(alias)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, alias)
+	}
+	sort.Strings(kept)
+	*aliases = kept
+	return nil
+}
+
+func validateModelYears(m domain.Model) error {
+	if m.YearFrom != 0 && m.YearFrom < 1900 || m.YearTo != 0 && m.YearTo < 1900 {
+		return fmt.Errorf("%w: model years must be >= 1900", domain.ErrInvalidInput)
+	}
+	if m.YearFrom != 0 && m.YearTo != 0 && m.YearFrom > m.YearTo {
+		return fmt.Errorf("%w: year_from is greater than year_to", domain.ErrInvalidInput)
+	}
+	return nil
+}
+
+func (s *catalogService) CreateMake(ctx context.Context, input domain.Make) (*domain.Make, error) {
+	if err := s.authorize(ctx, auth.ActionWriteCatalog); err != nil {
+		return nil, err
+	}
+
+	if err := prepareNames(&input.Name, &input.Aliases); err != nil {
+		return nil, err
+	}
+	if err := prepareID(&input.ID, input.Name); err != nil {
+		return nil, err
+	}
+
+	m, err := s.repo.CreateMake(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create make: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "make created", slog.String("make_id", m.ID))
+	return m, nil
+}
+
+func (s *catalogService) GetMake(ctx context.Context, id string) (*domain.Make, error) {
+	if err := s.authorize(ctx, auth.ActionReadCatalog); err != nil {
+		return nil, err
+	}
+
+	m, err := s.repo.GetMake(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get make: %w", err)
+	}
+	return m, nil
+}
+
+func (s *catalogService) ListMakes(ctx context.Context) ([]domain.Make, error) {
+	if err := s.authorize(ctx, auth.ActionReadCatalog); err != nil {
+		return nil, err
+	}
+
+	makes, err := s.repo.ListMakes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list makes: %w", err)
+	}
+	return makes, nil
+}
+
+func (s *catalogService) UpdateMake(ctx context.Context, id string, input domain.Make) (*domain.Make, error) {
+	if err := s.authorize(ctx, auth.ActionWriteCatalog); err != nil {
+		return nil, err
+	}
+
+	if err := prepareNames(&input.Name, &input.Aliases); err != nil {
+		return nil, err
+	}
+
+	m, err := s.repo.UpdateMake(ctx, id, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update make: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "make updated", slog.String("make_id", id))
+	return m, nil
+}
+
+func (s *catalogService) DeleteMake(ctx context.Context, id string) error {
+	if err := s.authorize(ctx, auth.ActionWriteCatalog); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteMake(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete make: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "make deleted", slog.String("make_id", id))
+	return nil
+}
+
+func (s *catalogService) CreateModel(ctx context.Context, makeID string, input domain.Model) (*domain.Model, error) {
+	if err := s.authorize(ctx, auth.ActionWriteCatalog); err != nil {
+		return nil, err
+	}
+
+	input.MakeID = makeID
+	if err := prepareNames(&input.Name, &input.Aliases); err != nil {
+		return nil, err
+	}
+	if err := prepareID(&input.ID, input.Name); err != nil {
+		return nil, err
+	}
+	if err := validateModelYears(input); err != nil {
+		return nil, err
+	}
+
+	m, err := s.repo.CreateModel(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create model: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "model created", slog.String("make_id", makeID), slog.String("model_id", m.ID))
+	return m, nil
+}
+
+func (s *catalogService) GetModel(ctx context.Context, makeID, id string) (*domain.Model, error) {
+	if err := s.authorize(ctx, auth.ActionReadCatalog); err != nil {
+		return nil, err
+	}
+
+	m, err := s.repo.GetModel(ctx, makeID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model: %w", err)
+	}
+	return m, nil
+}
+
+// ListModels reports ErrMakeNotFound for an unknown make instead of an empty
+// list.
+func (s *catalogService) ListModels(ctx context.Context, makeID string) ([]domain.Model, error) {
+	if err := s.authorize(ctx, auth.ActionReadCatalog); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetMake(ctx, makeID); err != nil {
+		return nil, fmt.Errorf("failed to get make: %w", err)
+	}
+
+	models, err := s.repo.ListModels(ctx, makeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	return models, nil
+}
+
+func (s *catalogService) UpdateModel(ctx context.Context, makeID, id string, input domain.Model) (*domain.Model, error) {
+	if err := s.authorize(ctx, auth.ActionWriteCatalog); err != nil {
+		return nil, err
+	}
+
+	if err := prepareNames(&input.Name, &input.Aliases); err != nil {
+		return nil, err
+	}
+	if err := validateModelYears(input); err != nil {
+		return nil, err
+	}
+
+	m, err := s.repo.UpdateModel(ctx, makeID, id, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update model: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "model updated", slog.String("make_id", makeID), slog.String("model_id", id))
+	return m, nil
+}
+
+func (s *catalogService) DeleteModel(ctx context.Context, makeID, id string) error {
+	if err := s.authorize(ctx, auth.ActionWriteCatalog); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteModel(ctx, makeID, id); err != nil {
+		return fmt.Errorf("failed to delete model: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "model deleted", slog.String("make_id", makeID), slog.String("model_id", id))
+	return nil
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kefir4iick/crud/internal/auth"
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/repository"
+)
+
+type DealerService interface {
+	Create(ctx context.Context, input domain.Dealer) (*domain.Dealer, error)
+	GetByID(ctx context.Context, id int64) (*domain.Dealer, error)
+	GetAll(ctx context.Context) ([]domain.Dealer, error)
+	Update(ctx context.Context, id int64, input domain.Dealer) (*domain.Dealer, error)
+	// Delete refuses to delete a dealer that still holds cars; they have to
+	// be transferred first.
+	Delete(ctx context.Context, id int64) error
+}
+
+type dealerService struct {
+	repo   repository.DealerRepository
+	authz  Authorizer
+	logger *slog.Logger
+}
+
+// NewDealerService returns the service behind the dealer endpoints. A nil
+// authz allows every call.
+func NewDealerService(repo repository.DealerRepository, authz Authorizer, logger *slog.Logger) DealerService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &dealerService{repo: repo, authz: authz, logger: logger}
+}
+
+func (s *dealerService) authorize(ctx context.Context, action auth.Action) error {
+	if s.authz == nil {
+		return nil
+	}
+	return s.authz.Authorize(ctx, action)
+}
+
+// prepareDealer trims the text fields of d and validates it.
+func prepareDealer(d *domain.Dealer) error {
+	d.Name = strings.TrimSpace(d.Name)
+	d.Address = strings.TrimSpace(d.Address)
+	if d.Name == "" {
+		return fmt.Errorf("%w: name is required", domain.ErrInvalidInput)
+	}
+	if len(d.Name) > 255 {
+		return fmt.Errorf("%w: name must be less than 255 characters", domain.ErrInvalidInput)
+	}
+	if len(d.Address) > 1000 {
+		return fmt.Errorf("%w: address must be less than 1000 characters", domain.ErrInvalidInput)
+	}
+	if d.Location != nil && !d.Location.Valid() {
+		return fmt.Errorf("%w: latitude must be between -90 and 90 and longitude between -180 and 180", domain.ErrInvalidInput)
+	}
+	return nil
+}
+
+func validDealerID(id int64) error {
+	if id <= 0 {
+		return fmt.Errorf("%w: dealer id must be positive", domain.ErrInvalidInput)
+	}
+	return nil
+}
+
+func (s *dealerService) Create(ctx context.Context, input domain.Dealer) (*domain.Dealer, error) {
+	if err := s.authorize(ctx, auth.ActionWriteDealers); err != nil {
+		return nil, err
+	}
+
+	if err := prepareDealer(&input); err != nil {
+		return nil, err
+	}
+
+	dealer, err := s.repo.Create(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dealer: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "dealer created", slog.Int64("dealer_id", dealer.ID))
+	return dealer, nil
+}
+
+func (s *dealerService) GetByID(ctx context.Context, id int64) (*domain.Dealer, error) {
+	if err := s.authorize(ctx, auth.ActionReadDealers); err != nil {
+		return nil, err
+	}
+
+	if err := validDealerID(id); err != nil {
+		return nil, err
+	}
+
+	dealer, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dealer: %w", err)
+	}
+	return dealer, nil
+}
+
+func (s *dealerService) GetAll(ctx context.Context) ([]domain.Dealer, error) {
+	if err := s.authorize(ctx, auth.ActionReadDealers); err != nil {
+		return nil, err
+	}
+
+	dealers, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dealers: %w", err)
+	}
+	return dealers, nil
+}
+
+func (s *dealerService) Update(ctx context.Context, id int64, input domain.Dealer) (*domain.Dealer, error) {
+	if err := s.authorize(ctx, auth.ActionWriteDealers); err != nil {
+		return nil, err
+	}
+
+	if err := validDealerID(id); err != nil {
+		return nil, err
+	}
+	if err := prepareDealer(&input); err != nil {
+		return nil, err
+	}
+
+	dealer, err := s.repo.Update(ctx, id, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update dealer: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "dealer updated", slog.Int64("dealer_id", id))
+	return dealer, nil
+}
+
+func (s *dealerService) Delete(ctx context.Context, id int64) error {
+	if err := s.authorize(ctx, auth.ActionWriteDealers); err != nil {
+		return err
+	}
+
+	if err := validDealerID(id); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete dealer: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "dealer deleted", slog.Int64("dealer_id", id))
+	return nil
+}
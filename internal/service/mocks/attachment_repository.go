@@ -0,0 +1,49 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type AttachmentRepository struct {
+	mock.Mock
+}
+
+func (m *AttachmentRepository) Create(ctx context.Context, a domain.Attachment) (*domain.Attachment, error) {
+	args := m.Called(ctx, a)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Attachment), args.Error(1)
+}
+
+func (m *AttachmentRepository) Get(ctx context.Context, carID string, id int64) (*domain.Attachment, error) {
+	args := m.Called(ctx, carID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Attachment), args.Error(1)
+}
+
+func (m *AttachmentRepository) List(ctx context.Context, carID string) ([]domain.Attachment, error) {
+	args := m.Called(ctx, carID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Attachment), args.Error(1)
+}
+
+func (m *AttachmentRepository) Delete(ctx context.Context, carID string, id int64) (*domain.Attachment, error) {
+	args := m.Called(ctx, carID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Attachment), args.Error(1)
+}
+
+func (m *AttachmentRepository) LockContent(ctx context.Context, checksum string) (int, error) {
+	args := m.Called(ctx, checksum)
+	return args.Int(0), args.Error(1)
+}
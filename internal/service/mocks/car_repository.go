@@ -15,6 +15,14 @@ func (m *CarRepository) Create(ctx context.Context, car domain.Car) (*domain.Car
 	return args.Get(0).(*domain.Car), args.Error(1)
 }
 
+func (m *CarRepository) CreateBatch(ctx context.Context, cars []domain.Car) ([]domain.Car, error) {
+	args := m.Called(ctx, cars)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Car), args.Error(1)
+}
+
 func (m *CarRepository) GetByID(ctx context.Context, id string) (*domain.Car, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
@@ -23,11 +31,32 @@ func (m *CarRepository) GetByID(ctx context.Context, id string) (*domain.Car, er
 	return args.Get(0).(*domain.Car), args.Error(1)
 }
 
-func (m *CarRepository) GetAll(ctx context.Context, limit, offset int) ([]domain.Car, error) {
-	args := m.Called(ctx, limit, offset)
+func (m *CarRepository) GetByVIN(ctx context.Context, vin string) (*domain.Car, error) {
+	args := m.Called(ctx, vin)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Car), args.Error(1)
+}
+
+func (m *CarRepository) GetByIDForUpdate(ctx context.Context, id string) (*domain.Car, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Car), args.Error(1)
+}
+
+func (m *CarRepository) GetAll(ctx context.Context, filter domain.CarFilter) ([]domain.Car, error) {
+	args := m.Called(ctx, filter)
 	return args.Get(0).([]domain.Car), args.Error(1)
 }
 
+func (m *CarRepository) Stats(ctx context.Context, filter domain.CarFilter, groupBy domain.StatsGroupBy) (*domain.CarStats, error) {
+	args := m.Called(ctx, filter, groupBy)
+	return args.Get(0).(*domain.CarStats), args.Error(1)
+}
+
 func (m *CarRepository) Update(ctx context.Context, id string, car domain.Car) (*domain.Car, error) {
 	args := m.Called(ctx, id, car)
 	return args.Get(0).(*domain.Car), args.Error(1)
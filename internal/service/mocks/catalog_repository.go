@@ -0,0 +1,82 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type CatalogRepository struct {
+	mock.Mock
+}
+
+func (m *CatalogRepository) make(args mock.Arguments) (*domain.Make, error) {
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Make), args.Error(1)
+}
+
+func (m *CatalogRepository) model(args mock.Arguments) (*domain.Model, error) {
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Model), args.Error(1)
+}
+
+func (m *CatalogRepository) CreateMake(ctx context.Context, mk domain.Make) (*domain.Make, error) {
+	return m.make(m.Called(ctx, mk))
+}
+
+func (m *CatalogRepository) GetMake(ctx context.Context, id string) (*domain.Make, error) {
+	return m.make(m.Called(ctx, id))
+}
+
+func (m *CatalogRepository) ListMakes(ctx context.Context) ([]domain.Make, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Make), args.Error(1)
+}
+
+func (m *CatalogRepository) UpdateMake(ctx context.Context, id string, mk domain.Make) (*domain.Make, error) {
+	return m.make(m.Called(ctx, id, mk))
+}
+
+func (m *CatalogRepository) DeleteMake(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *CatalogRepository) FindMake(ctx context.Context, name string) (*domain.Make, error) {
+	return m.make(m.Called(ctx, name))
+}
+
+func (m *CatalogRepository) CreateModel(ctx context.Context, md domain.Model) (*domain.Model, error) {
+	return m.model(m.Called(ctx, md))
+}
+
+func (m *CatalogRepository) GetModel(ctx context.Context, makeID, id string) (*domain.Model, error) {
+	return m.model(m.Called(ctx, makeID, id))
+}
+
+func (m *CatalogRepository) ListModels(ctx context.Context, makeID string) ([]domain.Model, error) {
+	args := m.Called(ctx, makeID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Model), args.Error(1)
+}
+
+func (m *CatalogRepository) UpdateModel(ctx context.Context, makeID, id string, md domain.Model) (*domain.Model, error) {
+	return m.model(m.Called(ctx, makeID, id, md))
+}
+
+func (m *CatalogRepository) DeleteModel(ctx context.Context, makeID, id string) error {
+	return m.Called(ctx, makeID, id).Error(0)
+}
+
+func (m *CatalogRepository) FindModel(ctx context.Context, makeID, name string) (*domain.Model, error) {
+	return m.model(m.Called(ctx, makeID, name))
+}
@@ -0,0 +1,49 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type DealerRepository struct {
+	mock.Mock
+}
+
+func (m *DealerRepository) Create(ctx context.Context, dealer domain.Dealer) (*domain.Dealer, error) {
+	args := m.Called(ctx, dealer)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Dealer), args.Error(1)
+}
+
+func (m *DealerRepository) GetByID(ctx context.Context, id int64) (*domain.Dealer, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Dealer), args.Error(1)
+}
+
+func (m *DealerRepository) GetAll(ctx context.Context) ([]domain.Dealer, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Dealer), args.Error(1)
+}
+
+func (m *DealerRepository) Update(ctx context.Context, id int64, dealer domain.Dealer) (*domain.Dealer, error) {
+	args := m.Called(ctx, id, dealer)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Dealer), args.Error(1)
+}
+
+func (m *DealerRepository) Delete(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type PriceHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *PriceHistoryRepository) Record(ctx context.Context, carID string, oldPrice, newPrice domain.Money) error {
+	args := m.Called(ctx, carID, oldPrice, newPrice)
+	return args.Error(0)
+}
+
+func (m *PriceHistoryRepository) List(ctx context.Context, carID string) ([]domain.PriceChange, error) {
+	args := m.Called(ctx, carID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.PriceChange), args.Error(1)
+}
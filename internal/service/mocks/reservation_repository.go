@@ -0,0 +1,64 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type ReservationRepository struct {
+	mock.Mock
+}
+
+func (m *ReservationRepository) Create(ctx context.Context, r domain.Reservation) (*domain.Reservation, error) {
+	args := m.Called(ctx, r)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Reservation), args.Error(1)
+}
+
+func (m *ReservationRepository) Get(ctx context.Context, carID string, id int64) (*domain.Reservation, error) {
+	args := m.Called(ctx, carID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Reservation), args.Error(1)
+}
+
+func (m *ReservationRepository) GetActive(ctx context.Context, carID string) (*domain.Reservation, error) {
+	args := m.Called(ctx, carID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Reservation), args.Error(1)
+}
+
+func (m *ReservationRepository) List(ctx context.Context, carID string) ([]domain.Reservation, error) {
+	args := m.Called(ctx, carID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Reservation), args.Error(1)
+}
+
+func (m *ReservationRepository) Update(ctx context.Context, r domain.Reservation) (*domain.Reservation, error) {
+	args := m.Called(ctx, r)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Reservation), args.Error(1)
+}
+
+type ExpiredReservationRepository struct {
+	mock.Mock
+}
+
+func (m *ExpiredReservationRepository) ListExpired(ctx context.Context, after int64, limit int) ([]domain.ExpiredReservation, error) {
+	args := m.Called(ctx, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.ExpiredReservation), args.Error(1)
+}
@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type StatusHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *StatusHistoryRepository) Record(ctx context.Context, carID string, change domain.StatusChange) error {
+	args := m.Called(ctx, carID, change)
+	return args.Error(0)
+}
+
+func (m *StatusHistoryRepository) List(ctx context.Context, carID string) ([]domain.StatusChange, error) {
+	args := m.Called(ctx, carID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.StatusChange), args.Error(1)
+}
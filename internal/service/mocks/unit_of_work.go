@@ -0,0 +1,38 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/kefir4iick/crud/internal/repository"
+)
+
+// UnitOfWork hands the callback the mocked repositories directly; there is no
+// transaction to commit or roll back.
+type UnitOfWork struct {
+	Cars         *CarRepository
+	Prices       *PriceHistoryRepository
+	Attachments  *AttachmentRepository
+	Dealers      *DealerRepository
+	Statuses     *StatusHistoryRepository
+	Reservations *ReservationRepository
+}
+
+func (u *UnitOfWork) WithTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	repos := repository.Repositories{Cars: u.Cars}
+	if u.Prices != nil {
+		repos.Prices = u.Prices
+	}
+	if u.Attachments != nil {
+		repos.Attachments = u.Attachments
+	}
+	if u.Dealers != nil {
+		repos.Dealers = u.Dealers
+	}
+	if u.Statuses != nil {
+		repos.Statuses = u.Statuses
+	}
+	if u.Reservations != nil {
+		repos.Reservations = u.Reservations
+	}
+	return fn(repos)
+}
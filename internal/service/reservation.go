@@ -0,0 +1,437 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/kefir4iick/crud/internal/auth"
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/repository"
+	"github.com/kefir4iick/crud/internal/tenant"
+)
+
+type ReservationService interface {
+	// Create reserves a car for input.Holder until input.ExpiresAt, or for
+	// DefaultReservationPeriod when it is zero.
+	Create(ctx context.Context, carID string, input domain.Reservation) (*domain.Reservation, error)
+	List(ctx context.Context, carID string) ([]domain.Reservation, error)
+	// Extend moves the expiry of an active reservation to a later time.
+	Extend(ctx context.Context, carID string, id int64, expiresAt time.Time) (*domain.Reservation, error)
+	// Cancel ends an active reservation and puts the car back on sale.
+	Cancel(ctx context.Context, carID string, id int64, reason string) (*domain.Reservation, error)
+	// ExpireDue ends the reservations past their expiry, across tenants,
+	// and returns how many it ended. It is meant for
+	// RunReservationSweeper and is not authorized.
+	ExpireDue(ctx context.Context) (int, error)
+}
+
+const (
+	// DefaultReservationPeriod is how long a car is held when the client
+	// does not say.
+	DefaultReservationPeriod = 48 * time.Hour
+	// MaxReservationPeriod bounds how far ahead a hold may expire, so a
+	// forgotten one still ends.
+	MaxReservationPeriod = 30 * 24 * time.Hour
+)
+
+// sweepBatchSize bounds the reservations ExpireDue lists at a time.
+const sweepBatchSize = 100
+
+type reservationService struct {
+	uow     repository.UnitOfWork
+	expired repository.ExpiredReservationRepository
+	authz   Authorizer
+	logger  *slog.Logger
+}
+
+// NewReservationService returns the service behind the reservation endpoints
+// and the sweeper. A nil authz allows every call.
+func NewReservationService(uow repository.UnitOfWork, expired repository.ExpiredReservationRepository, authz Authorizer, logger *slog.Logger) ReservationService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &reservationService{uow: uow, expired: expired, authz: authz, logger: logger}
+}
+
+func (s *reservationService) authorize(ctx context.Context, action auth.Action) error {
+	if s.authz == nil {
+		return nil
+	}
+	return s.authz.Authorize(ctx, action)
+}
+
+// validExpiry checks that a reservation made or extended now may expire at
+// expiresAt.
+func validExpiry(expiresAt time.Time) error {
+	now := time.Now()
+	if !expiresAt.After(now) {
+		return fmt.Errorf("%w: expires_at must be in the future", domain.ErrInvalidInput)
+	}
+	if expiresAt.After(now.Add(MaxReservationPeriod)) {
+		return fmt.Errorf("%w: expires_at must be within %s", domain.ErrInvalidInput, MaxReservationPeriod)
+	}
+	return nil
+}
+
+// prepareReservation trims the text fields of r, fills in the expiry and
+// validates it.
+func prepareReservation(r *domain.Reservation) error {
+	r.Holder = strings.TrimSpace(r.Holder)
+	r.Note = strings.TrimSpace(r.Note)
+	if r.Holder == "" {
+		return fmt.Errorf("%w: holder is required", domain.ErrInvalidInput)
+	}
+	if len(r.Holder) > 255 {
+		return fmt.Errorf("%w: holder must be less than 255 characters", domain.ErrInvalidInput)
+	}
+	if len(r.Note) > 1000 {
+		return fmt.Errorf("%w: note must be less than 1000 characters", domain.ErrInvalidInput)
+	}
+	if r.ExpiresAt.IsZero() {
+		r.ExpiresAt = time.Now().Add(DefaultReservationPeriod)
+	}
+	return validExpiry(r.ExpiresAt)
+}
+
+// Create stores the reservation before reserving the car, so that a car
+// that is not available rolls the reservation back, and one that already
+// has an active reservation is caught by the storage however requests race.
+func (s *reservationService) Create(ctx context.Context, carID string, input domain.Reservation) (*domain.Reservation, error) {
+	if err := s.authorize(ctx, auth.ActionReserveCars); err != nil {
+		return nil, err
+	}
+
+	if carID == "" {
+		return nil, errors.New("id is required")
+	}
+	if err := prepareReservation(&input); err != nil {
+		return nil, err
+	}
+	input.ID, input.CarID, input.Status = 0, carID, domain.ReservationActive
+
+	var reservation *domain.Reservation
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		car, err := repos.Cars.GetByIDForUpdate(ctx, carID)
+		if err != nil {
+			return fmt.Errorf("car not found: %w", err)
+		}
+		if car, err = expireLapsedHold(ctx, repos, car); err != nil {
+			return err
+		}
+
+		reservation, err = repos.Reservations.Create(ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to create reservation: %w", err)
+		}
+
+		_, err = applyTransition(ctx, repos, car, domain.TransitionReserve, fmt.Sprintf("reservation %d", reservation.ID))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "car reserved",
+		slog.String("car_id", carID),
+		slog.Int64("reservation_id", reservation.ID),
+		slog.Time("expires_at", reservation.ExpiresAt))
+	return reservation, nil
+}
+
+func (s *reservationService) List(ctx context.Context, carID string) ([]domain.Reservation, error) {
+	if err := s.authorize(ctx, auth.ActionReadCars); err != nil {
+		return nil, err
+	}
+
+	if carID == "" {
+		return nil, errors.New("id is required")
+	}
+
+	var reservations []domain.Reservation
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		if _, err := repos.Cars.GetByID(ctx, carID); err != nil {
+			return fmt.Errorf("failed to get car: %w", err)
+		}
+
+		var err error
+		reservations, err = repos.Reservations.List(ctx, carID)
+		if err != nil {
+			return fmt.Errorf("failed to list reservations: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// getActive returns the reservation id of a car locked by the caller, or
+// ErrReservationNotActive once it has ended or lapsed. A lapsed one may not
+// have been swept yet, but it no longer holds the car.
+func getActive(ctx context.Context, repo repository.ReservationRepository, carID string, id int64) (*domain.Reservation, error) {
+	reservation, err := repo.Get(ctx, carID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reservation: %w", err)
+	}
+	if reservation.Status != domain.ReservationActive || !reservation.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: reservation %d", domain.ErrReservationNotActive, id)
+	}
+	return reservation, nil
+}
+
+// Extend locks the car first, like every other change to a reservation, so
+// that it cannot race the sweeper or a transition of the car.
+func (s *reservationService) Extend(ctx context.Context, carID string, id int64, expiresAt time.Time) (*domain.Reservation, error) {
+	if err := s.authorize(ctx, auth.ActionReserveCars); err != nil {
+		return nil, err
+	}
+
+	if carID == "" {
+		return nil, errors.New("id is required")
+	}
+	if err := validExpiry(expiresAt); err != nil {
+		return nil, err
+	}
+
+	var extended *domain.Reservation
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		if _, err := repos.Cars.GetByIDForUpdate(ctx, carID); err != nil {
+			return fmt.Errorf("car not found: %w", err)
+		}
+
+		reservation, err := getActive(ctx, repos.Reservations, carID, id)
+		if err != nil {
+			return err
+		}
+		if !expiresAt.After(reservation.ExpiresAt) {
+			return fmt.Errorf("%w: expires_at must be later than the current expiry", domain.ErrInvalidInput)
+		}
+
+		reservation.ExpiresAt = expiresAt
+		extended, err = repos.Reservations.Update(ctx, *reservation)
+		if err != nil {
+			return fmt.Errorf("failed to extend reservation: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "reservation extended",
+		slog.String("car_id", carID),
+		slog.Int64("reservation_id", id),
+		slog.Time("expires_at", expiresAt))
+	return extended, nil
+}
+
+func (s *reservationService) Cancel(ctx context.Context, carID string, id int64, reason string) (*domain.Reservation, error) {
+	if err := s.authorize(ctx, auth.ActionReserveCars); err != nil {
+		return nil, err
+	}
+
+	if carID == "" {
+		return nil, errors.New("id is required")
+	}
+	reason = strings.TrimSpace(reason)
+	if len(reason) > maxReasonLength {
+		return nil, fmt.Errorf("%w: reason must be at most %d characters", domain.ErrInvalidInput, maxReasonLength)
+	}
+	if reason == "" {
+		reason = fmt.Sprintf("reservation %d cancelled", id)
+	}
+
+	var cancelled *domain.Reservation
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		car, err := repos.Cars.GetByIDForUpdate(ctx, carID)
+		if err != nil {
+			return fmt.Errorf("car not found: %w", err)
+		}
+
+		reservation, err := getActive(ctx, repos.Reservations, carID, id)
+		if err != nil {
+			return err
+		}
+		cancelled, err = endReservation(ctx, repos, car, *reservation, domain.ReservationCancelled, reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "reservation cancelled",
+		slog.String("car_id", carID),
+		slog.Int64("reservation_id", id))
+	return cancelled, nil
+}
+
+// endReservation gives reservation its final status and releases its car.
+// It must run in the transaction that locked car.
+func endReservation(ctx context.Context, repos repository.Repositories, car *domain.Car, reservation domain.Reservation, status domain.ReservationStatus, reason string) (*domain.Reservation, error) {
+	reservation.Status = status
+	ended, err := repos.Reservations.Update(ctx, reservation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to end reservation: %w", err)
+	}
+
+	if car.Status == domain.StatusReserved {
+		if _, err := applyTransition(ctx, repos, car, domain.TransitionRelease, reason); err != nil {
+			return nil, err
+		}
+	}
+	return ended, nil
+}
+
+// endActiveReservation gives the active reservation of a car, if any, its
+// final status when the car itself moves on.
+func endActiveReservation(ctx context.Context, repo repository.ReservationRepository, carID string, status domain.ReservationStatus) error {
+	reservation, err := repo.GetActive(ctx, carID)
+	if errors.Is(err, domain.ErrReservationNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get reservation: %w", err)
+	}
+
+	reservation.Status = status
+	if _, err := repo.Update(ctx, *reservation); err != nil {
+		return fmt.Errorf("failed to end reservation: %w", err)
+	}
+	return nil
+}
+
+// expireLapsedHold ends the reservation of a car whose hold has lapsed but
+// that the sweeper has not reached yet, and puts the car back on sale, so
+// that writes see the car as reads already do. It must run in the
+// transaction that locked car.
+func expireLapsedHold(ctx context.Context, repos repository.Repositories, car *domain.Car) (*domain.Car, error) {
+	if car.Status != domain.StatusReserved || car.ReservedUntil == nil || car.ReservedUntil.After(time.Now()) {
+		return car, nil
+	}
+
+	reservation, err := repos.Reservations.GetActive(ctx, car.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reservation: %w", err)
+	}
+	reservation.Status = domain.ReservationExpired
+	if _, err := repos.Reservations.Update(ctx, *reservation); err != nil {
+		return nil, fmt.Errorf("failed to end reservation: %w", err)
+	}
+	return applyTransition(ctx, repos, car, domain.TransitionRelease, fmt.Sprintf("reservation %d expired", reservation.ID))
+}
+
+// ExpireDue ends each reservation in a transaction of its own, scoped to its
+// tenant, so one failure does not hold up the others. It pages through the
+// due reservations by ID, so reservations that keep failing are passed over
+// rather than listed first by every sweep.
+func (s *reservationService) ExpireDue(ctx context.Context) (int, error) {
+	n := 0
+	var after int64
+	for {
+		due, err := s.expired.ListExpired(ctx, after, sweepBatchSize)
+		if err != nil {
+			return n, err
+		}
+
+		n += s.expireBatch(ctx, due)
+		if len(due) < sweepBatchSize {
+			return n, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		after = due[len(due)-1].ID
+	}
+}
+
+// expireBatch ends the reservations in due and returns how many it ended.
+func (s *reservationService) expireBatch(ctx context.Context, due []domain.ExpiredReservation) int {
+	n := 0
+	for _, e := range due {
+		ctx := tenant.WithID(ctx, e.TenantID)
+		ended, err := s.expire(ctx, e)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to expire reservation",
+				slog.String("car_id", e.CarID),
+				slog.Int64("reservation_id", e.ID),
+				slog.Any("error", err))
+			continue
+		}
+		if ended {
+			n++
+		}
+	}
+	return n
+}
+
+// expire ends one reservation unless it was cancelled, extended or deleted
+// with its car since it was listed.
+func (s *reservationService) expire(ctx context.Context, e domain.ExpiredReservation) (bool, error) {
+	ended := false
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		ended = false
+		car, err := repos.Cars.GetByIDForUpdate(ctx, e.CarID)
+		if errors.Is(err, domain.ErrCarNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock car: %w", err)
+		}
+
+		reservation, err := repos.Reservations.Get(ctx, e.CarID, e.ID)
+		if errors.Is(err, domain.ErrReservationNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get reservation: %w", err)
+		}
+		if reservation.Status != domain.ReservationActive || reservation.ExpiresAt.After(time.Now()) {
+			return nil
+		}
+
+		if _, err := endReservation(ctx, repos, car, *reservation, domain.ReservationExpired, fmt.Sprintf("reservation %d expired", e.ID)); err != nil {
+			return err
+		}
+		ended = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if ended {
+		s.logger.InfoContext(ctx, "reservation expired",
+			slog.String("car_id", e.CarID),
+			slog.Int64("reservation_id", e.ID))
+	}
+	return ended, nil
+}
+
+// RunReservationSweeper expires reservations every interval until ctx is
+// cancelled.
+func RunReservationSweeper(ctx context.Context, s ReservationService, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.ExpireDue(ctx)
+			if err != nil {
+				logger.ErrorContext(ctx, "failed to expire reservations", slog.Any("error", err))
+				continue
+			}
+			if n > 0 {
+				logger.InfoContext(ctx, "expired reservations", slog.Int("count", n))
+			}
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/kefir4iick/crud/internal/service")
+
+type tracingCarService struct {
+	next CarService
+}
+
+// WithTracing wraps svc so that every method call gets its own span.
+func WithTracing(svc CarService) CarService {
+	return &tracingCarService{next: svc}
+}
+
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t *tracingCarService) Create(ctx context.Context, input domain.Car) (car *domain.Car, err error) {
+	ctx, span := startSpan(ctx, "CarService.Create")
+	defer func() { endSpan(span, err) }()
+	return t.next.Create(ctx, input)
+}
+
+func (t *tracingCarService) CreateBatch(ctx context.Context, inputs []domain.Car) (cars []domain.Car, err error) {
+	ctx, span := startSpan(ctx, "CarService.CreateBatch", attribute.Int("batch.size", len(inputs)))
+	defer func() { endSpan(span, err) }()
+	return t.next.CreateBatch(ctx, inputs)
+}
+
+func (t *tracingCarService) GetByID(ctx context.Context, id string) (car *domain.Car, err error) {
+	ctx, span := startSpan(ctx, "CarService.GetByID", attribute.String("car.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.GetByID(ctx, id)
+}
+
+func (t *tracingCarService) GetByVIN(ctx context.Context, vin string) (car *domain.Car, err error) {
+	ctx, span := startSpan(ctx, "CarService.GetByVIN", attribute.String("car.vin", vin))
+	defer func() { endSpan(span, err) }()
+	return t.next.GetByVIN(ctx, vin)
+}
+
+func (t *tracingCarService) GetAll(ctx context.Context, filter domain.CarFilter) (cars []domain.Car, err error) {
+	ctx, span := startSpan(ctx, "CarService.GetAll",
+		attribute.Int("limit", filter.Limit),
+		attribute.Int("offset", filter.Offset),
+		attribute.String("sort", filter.Sort),
+	)
+	defer func() { endSpan(span, err) }()
+	return t.next.GetAll(ctx, filter)
+}
+
+func (t *tracingCarService) Stats(ctx context.Context, filter domain.CarFilter, groupBy domain.StatsGroupBy) (stats *domain.CarStats, err error) {
+	ctx, span := startSpan(ctx, "CarService.Stats", attribute.String("group_by", string(groupBy)))
+	defer func() { endSpan(span, err) }()
+	return t.next.Stats(ctx, filter, groupBy)
+}
+
+func (t *tracingCarService) GetPriceHistory(ctx context.Context, id string) (changes []domain.PriceChange, err error) {
+	ctx, span := startSpan(ctx, "CarService.GetPriceHistory", attribute.String("car.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.GetPriceHistory(ctx, id)
+}
+
+func (t *tracingCarService) Update(ctx context.Context, id string, input domain.UpdateCarInput) (car *domain.Car, err error) {
+	ctx, span := startSpan(ctx, "CarService.Update", attribute.String("car.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.Update(ctx, id, input)
+}
+
+func (t *tracingCarService) Transfer(ctx context.Context, id string, dealerID int64) (car *domain.Car, err error) {
+	ctx, span := startSpan(ctx, "CarService.Transfer", attribute.String("car.id", id), attribute.Int64("dealer.id", dealerID))
+	defer func() { endSpan(span, err) }()
+	return t.next.Transfer(ctx, id, dealerID)
+}
+
+func (t *tracingCarService) Transition(ctx context.Context, id string, tr domain.Transition, reason string) (car *domain.Car, err error) {
+	ctx, span := startSpan(ctx, "CarService.Transition", attribute.String("car.id", id), attribute.String("transition", string(tr)))
+	defer func() { endSpan(span, err) }()
+	return t.next.Transition(ctx, id, tr, reason)
+}
+
+func (t *tracingCarService) GetStatusHistory(ctx context.Context, id string) (changes []domain.StatusChange, err error) {
+	ctx, span := startSpan(ctx, "CarService.GetStatusHistory", attribute.String("car.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.GetStatusHistory(ctx, id)
+}
+
+func (t *tracingCarService) Delete(ctx context.Context, id string) (err error) {
+	ctx, span := startSpan(ctx, "CarService.Delete", attribute.String("car.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.Delete(ctx, id)
+}
+
+type tracingCatalogService struct {
+	next CatalogService
+}
+
+// WithCatalogTracing wraps svc so that every method call gets its own span.
+func WithCatalogTracing(svc CatalogService) CatalogService {
+	return &tracingCatalogService{next: svc}
+}
+
+func (t *tracingCatalogService) CreateMake(ctx context.Context, input domain.Make) (m *domain.Make, err error) {
+	ctx, span := startSpan(ctx, "CatalogService.CreateMake")
+	defer func() { endSpan(span, err) }()
+	return t.next.CreateMake(ctx, input)
+}
+
+func (t *tracingCatalogService) GetMake(ctx context.Context, id string) (m *domain.Make, err error) {
+	ctx, span := startSpan(ctx, "CatalogService.GetMake", attribute.String("make.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.GetMake(ctx, id)
+}
+
+func (t *tracingCatalogService) ListMakes(ctx context.Context) (makes []domain.Make, err error) {
+	ctx, span := startSpan(ctx, "CatalogService.ListMakes")
+	defer func() { endSpan(span, err) }()
+	return t.next.ListMakes(ctx)
+}
+
+func (t *tracingCatalogService) UpdateMake(ctx context.Context, id string, input domain.Make) (m *domain.Make, err error) {
+	ctx, span := startSpan(ctx, "CatalogService.UpdateMake", attribute.String("make.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.UpdateMake(ctx, id, input)
+}
+
+func (t *tracingCatalogService) DeleteMake(ctx context.Context, id string) (err error) {
+	ctx, span := startSpan(ctx, "CatalogService.DeleteMake", attribute.String("make.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.DeleteMake(ctx, id)
+}
+
+func (t *tracingCatalogService) CreateModel(ctx context.Context, makeID string, input domain.Model) (m *domain.Model, err error) {
+	ctx, span := startSpan(ctx, "CatalogService.CreateModel", attribute.String("make.id", makeID))
+	defer func() { endSpan(span, err) }()
+	return t.next.CreateModel(ctx, makeID, input)
+}
+
+func (t *tracingCatalogService) GetModel(ctx context.Context, makeID, id string) (m *domain.Model, err error) {
+	ctx, span := startSpan(ctx, "CatalogService.GetModel", attribute.String("make.id", makeID), attribute.String("model.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.GetModel(ctx, makeID, id)
+}
+
+func (t *tracingCatalogService) ListModels(ctx context.Context, makeID string) (models []domain.Model, err error) {
+	ctx, span := startSpan(ctx, "CatalogService.ListModels", attribute.String("make.id", makeID))
+	defer func() { endSpan(span, err) }()
+	return t.next.ListModels(ctx, makeID)
+}
+
+func (t *tracingCatalogService) UpdateModel(ctx context.Context, makeID, id string, input domain.Model) (m *domain.Model, err error) {
+	ctx, span := startSpan(ctx, "CatalogService.UpdateModel", attribute.String("make.id", makeID), attribute.String("model.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.UpdateModel(ctx, makeID, id, input)
+}
+
+func (t *tracingCatalogService) DeleteModel(ctx context.Context, makeID, id string) (err error) {
+	ctx, span := startSpan(ctx, "CatalogService.DeleteModel", attribute.String("make.id", makeID), attribute.String("model.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.DeleteModel(ctx, makeID, id)
+}
+
+type tracingDealerService struct {
+	next DealerService
+}
+
+// WithDealerTracing wraps svc so that every method call gets its own span.
+func WithDealerTracing(svc DealerService) DealerService {
+	return &tracingDealerService{next: svc}
+}
+
+func (t *tracingDealerService) Create(ctx context.Context, input domain.Dealer) (dealer *domain.Dealer, err error) {
+	ctx, span := startSpan(ctx, "DealerService.Create")
+	defer func() { endSpan(span, err) }()
+	return t.next.Create(ctx, input)
+}
+
+func (t *tracingDealerService) GetByID(ctx context.Context, id int64) (dealer *domain.Dealer, err error) {
+	ctx, span := startSpan(ctx, "DealerService.GetByID", attribute.Int64("dealer.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.GetByID(ctx, id)
+}
+
+func (t *tracingDealerService) GetAll(ctx context.Context) (dealers []domain.Dealer, err error) {
+	ctx, span := startSpan(ctx, "DealerService.GetAll")
+	defer func() { endSpan(span, err) }()
+	return t.next.GetAll(ctx)
+}
+
+func (t *tracingDealerService) Update(ctx context.Context, id int64, input domain.Dealer) (dealer *domain.Dealer, err error) {
+	ctx, span := startSpan(ctx, "DealerService.Update", attribute.Int64("dealer.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.Update(ctx, id, input)
+}
+
+func (t *tracingDealerService) Delete(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "DealerService.Delete", attribute.Int64("dealer.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.Delete(ctx, id)
+}
+
+type tracingAttachmentService struct {
+	next AttachmentService
+}
+
+// WithAttachmentTracing wraps svc so that every method call gets its own
+// span.
+func WithAttachmentTracing(svc AttachmentService) AttachmentService {
+	return &tracingAttachmentService{next: svc}
+}
+
+func (t *tracingAttachmentService) Upload(ctx context.Context, carID, filename string, content io.Reader) (a *domain.Attachment, err error) {
+	ctx, span := startSpan(ctx, "AttachmentService.Upload", attribute.String("car.id", carID))
+	defer func() { endSpan(span, err) }()
+	return t.next.Upload(ctx, carID, filename, content)
+}
+
+func (t *tracingAttachmentService) List(ctx context.Context, carID string) (attachments []domain.Attachment, err error) {
+	ctx, span := startSpan(ctx, "AttachmentService.List", attribute.String("car.id", carID))
+	defer func() { endSpan(span, err) }()
+	return t.next.List(ctx, carID)
+}
+
+func (t *tracingAttachmentService) Open(ctx context.Context, carID string, id int64) (a *domain.Attachment, content io.ReadCloser, err error) {
+	ctx, span := startSpan(ctx, "AttachmentService.Open", attribute.String("car.id", carID), attribute.Int64("attachment.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.Open(ctx, carID, id)
+}
+
+func (t *tracingAttachmentService) Delete(ctx context.Context, carID string, id int64) (err error) {
+	ctx, span := startSpan(ctx, "AttachmentService.Delete", attribute.String("car.id", carID), attribute.Int64("attachment.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.Delete(ctx, carID, id)
+}
+
+type tracingReservationService struct {
+	next ReservationService
+}
+
+// WithReservationTracing wraps svc so that every method call gets its own
+// span.
+func WithReservationTracing(svc ReservationService) ReservationService {
+	return &tracingReservationService{next: svc}
+}
+
+func (t *tracingReservationService) Create(ctx context.Context, carID string, input domain.Reservation) (r *domain.Reservation, err error) {
+	ctx, span := startSpan(ctx, "ReservationService.Create", attribute.String("car.id", carID))
+	defer func() { endSpan(span, err) }()
+	return t.next.Create(ctx, carID, input)
+}
+
+func (t *tracingReservationService) List(ctx context.Context, carID string) (reservations []domain.Reservation, err error) {
+	ctx, span := startSpan(ctx, "ReservationService.List", attribute.String("car.id", carID))
+	defer func() { endSpan(span, err) }()
+	return t.next.List(ctx, carID)
+}
+
+func (t *tracingReservationService) Extend(ctx context.Context, carID string, id int64, expiresAt time.Time) (r *domain.Reservation, err error) {
+	ctx, span := startSpan(ctx, "ReservationService.Extend", attribute.String("car.id", carID), attribute.Int64("reservation.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.Extend(ctx, carID, id, expiresAt)
+}
+
+func (t *tracingReservationService) Cancel(ctx context.Context, carID string, id int64, reason string) (r *domain.Reservation, err error) {
+	ctx, span := startSpan(ctx, "ReservationService.Cancel", attribute.String("car.id", carID), attribute.Int64("reservation.id", id))
+	defer func() { endSpan(span, err) }()
+	return t.next.Cancel(ctx, carID, id, reason)
+}
+
+func (t *tracingReservationService) ExpireDue(ctx context.Context) (n int, err error) {
+	ctx, span := startSpan(ctx, "ReservationService.ExpireDue")
+	defer func() {
+		span.SetAttributes(attribute.Int("reservations.expired", n))
+		endSpan(span, err)
+	}()
+	return t.next.ExpireDue(ctx)
+}
@@ -0,0 +1,237 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path"
+	"testing"
+
+	"github.com/kefir4iick/crud/internal/blob"
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/repository"
+	"github.com/kefir4iick/crud/internal/service"
+	"github.com/kefir4iick/crud/internal/service/mocks"
+	"github.com/kefir4iick/crud/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is enough of a PNG file for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func blobKey(data []byte) string {
+	sum := checksum(data)
+	return path.Join("acme", sum[:2], sum)
+}
+
+func readBlob(t *testing.T, blobs blob.Store, key string) []byte {
+	t.Helper()
+	rc, err := blobs.Open(context.Background(), key)
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return data
+}
+
+func newAttachmentService(t *testing.T) (service.AttachmentService, *mocks.CarRepository, *mocks.AttachmentRepository, blob.Store) {
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	cars := new(mocks.CarRepository)
+	cars.On("GetByID", mock.Anything, "1").Return(&domain.Car{ID: "1"}, nil)
+	cars.On("GetByID", mock.Anything, "999").Return(nil, domain.ErrCarNotFound)
+	cars.On("GetByIDForUpdate", mock.Anything, "1").Return(&domain.Car{ID: "1", Status: domain.StatusAvailable}, nil)
+	cars.On("GetByIDForUpdate", mock.Anything, "2").Return(&domain.Car{ID: "2", Status: domain.StatusSold}, nil)
+	cars.On("GetByIDForUpdate", mock.Anything, "999").Return(nil, domain.ErrCarNotFound)
+	attachments := new(mocks.AttachmentRepository)
+
+	uow := &mocks.UnitOfWork{Cars: cars, Attachments: attachments}
+	return service.NewAttachmentService(uow, blobs, 64, nil, nil), cars, attachments, blobs
+}
+
+func TestUploadAttachment(t *testing.T) {
+	ctx := tenant.WithID(context.Background(), "acme")
+	photo := append(pngHeader, "photo"...)
+	sum := checksum(photo)
+
+	t.Run("New content is stored", func(t *testing.T) {
+		s, _, attachments, blobs := newAttachmentService(t)
+		want := domain.Attachment{CarID: "1", Filename: "front.png", ContentType: "image/png", Size: int64(len(photo)), Checksum: sum}
+		attachments.On("LockContent", ctx, sum).Return(0, nil)
+		attachments.On("Create", ctx, want).Return(&domain.Attachment{ID: 7}, nil)
+
+		got, err := s.Upload(ctx, "1", `C:\photos\front.png`, bytes.NewReader(photo))
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), got.ID)
+		assert.Equal(t, photo, readBlob(t, blobs, blobKey(photo)))
+	})
+
+	t.Run("Known content is not stored again", func(t *testing.T) {
+		s, _, attachments, blobs := newAttachmentService(t)
+		attachments.On("LockContent", ctx, sum).Return(2, nil)
+		attachments.On("Create", ctx, mock.Anything).Return(&domain.Attachment{ID: 8}, nil)
+
+		_, err := s.Upload(ctx, "1", "", bytes.NewReader(photo))
+		require.NoError(t, err)
+		attachments.AssertCalled(t, "Create", ctx, mock.MatchedBy(func(a domain.Attachment) bool {
+			return a.Filename == "attachment"
+		}))
+		_, err = blobs.Open(ctx, blobKey(photo))
+		assert.ErrorIs(t, err, blob.ErrNotFound)
+	})
+
+	t.Run("Failed create releases the content", func(t *testing.T) {
+		s, _, attachments, blobs := newAttachmentService(t)
+		attachments.On("LockContent", mock.Anything, sum).Return(0, nil)
+		attachments.On("Create", ctx, mock.Anything).Return(nil, domain.ErrCheckViolation)
+
+		_, err := s.Upload(ctx, "1", "front.png", bytes.NewReader(photo))
+		assert.ErrorIs(t, err, domain.ErrCheckViolation)
+		_, err = blobs.Open(ctx, blobKey(photo))
+		assert.ErrorIs(t, err, blob.ErrNotFound)
+	})
+
+	tests := []struct {
+		name    string
+		carID   string
+		content []byte
+		wantErr error
+	}{
+		{name: "Too large", carID: "1", content: append(pngHeader, bytes.Repeat([]byte("x"), 64)...), wantErr: domain.ErrAttachmentTooLarge},
+		{name: "Empty", carID: "1", content: nil, wantErr: domain.ErrInvalidInput},
+		{name: "Unsupported type", carID: "1", content: []byte("<html><script>alert(1)</script>"), wantErr: domain.ErrUnsupportedMediaType},
+		{name: "Unknown car", carID: "999", content: photo, wantErr: domain.ErrCarNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, attachments, _ := newAttachmentService(t)
+
+			_, err := s.Upload(ctx, tt.carID, "file", bytes.NewReader(tt.content))
+			assert.ErrorIs(t, err, tt.wantErr)
+			attachments.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestDeleteAttachment(t *testing.T) {
+	ctx := tenant.WithID(context.Background(), "acme")
+	photo := append(pngHeader, "photo"...)
+	sum := checksum(photo)
+
+	s, _, attachments, blobs := newAttachmentService(t)
+	require.NoError(t, blobs.Put(ctx, blobKey(photo), bytes.NewReader(photo)))
+	attachments.On("Delete", ctx, "1", int64(7)).Return(&domain.Attachment{ID: 7, Checksum: sum}, nil).Once()
+	attachments.On("Delete", ctx, "1", int64(8)).Return(&domain.Attachment{ID: 8, Checksum: sum}, nil).Once()
+	attachments.On("Delete", ctx, "1", int64(9)).Return(nil, domain.ErrAttachmentNotFound)
+	attachments.On("LockContent", mock.Anything, sum).Return(1, nil).Once()
+	attachments.On("LockContent", mock.Anything, sum).Return(0, nil).Once()
+
+	require.NoError(t, s.Delete(ctx, "1", 7))
+	assert.Equal(t, photo, readBlob(t, blobs, blobKey(photo)), "another attachment still refers to the content")
+
+	require.NoError(t, s.Delete(ctx, "1", 8))
+	_, err := blobs.Open(ctx, blobKey(photo))
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+
+	assert.ErrorIs(t, s.Delete(ctx, "1", 9), domain.ErrAttachmentNotFound)
+}
+
+// failingCommit runs transactions against the mocks, and fails the commit of
+// the first one.
+type failingCommit struct {
+	mocks.UnitOfWork
+	failed bool
+}
+
+func (u *failingCommit) WithTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	if err := u.UnitOfWork.WithTx(ctx, fn); err != nil {
+		return err
+	}
+	if !u.failed {
+		u.failed = true
+		return errors.New("commit failed")
+	}
+	return nil
+}
+
+func TestDeleteAttachment_FailedCommit(t *testing.T) {
+	ctx := tenant.WithID(context.Background(), "acme")
+	photo := append(pngHeader, "photo"...)
+
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, blobs.Put(ctx, blobKey(photo), bytes.NewReader(photo)))
+	cars := new(mocks.CarRepository)
+	cars.On("GetByIDForUpdate", ctx, "1").Return(&domain.Car{ID: "1"}, nil)
+	attachments := new(mocks.AttachmentRepository)
+	attachments.On("Delete", ctx, "1", int64(7)).Return(&domain.Attachment{ID: 7, Checksum: checksum(photo)}, nil)
+
+	uow := &failingCommit{UnitOfWork: mocks.UnitOfWork{Cars: cars, Attachments: attachments}}
+	s := service.NewAttachmentService(uow, blobs, 64, nil, nil)
+	assert.ErrorContains(t, s.Delete(ctx, "1", 7), "commit failed")
+	assert.Equal(t, photo, readBlob(t, blobs, blobKey(photo)), "the attachment survived, so must its content")
+	attachments.AssertNotCalled(t, "LockContent", mock.Anything, mock.Anything)
+}
+
+func TestOpenAttachment(t *testing.T) {
+	ctx := tenant.WithID(context.Background(), "acme")
+	photo := append(pngHeader, "photo"...)
+	attachment := &domain.Attachment{ID: 7, CarID: "1", Checksum: checksum(photo)}
+
+	s, _, attachments, blobs := newAttachmentService(t)
+	attachments.On("Get", ctx, "1", int64(7)).Return(attachment, nil)
+
+	_, _, err := s.Open(ctx, "1", 7)
+	assert.ErrorIs(t, err, domain.ErrAttachmentNotFound, "the content is gone")
+
+	require.NoError(t, blobs.Put(ctx, blobKey(photo), bytes.NewReader(photo)))
+	got, content, err := s.Open(ctx, "1", 7)
+	require.NoError(t, err)
+	defer content.Close()
+	assert.Equal(t, attachment, got)
+	data, err := io.ReadAll(content)
+	require.NoError(t, err)
+	assert.Equal(t, photo, data)
+}
+
+func TestDeleteCar_Attachments(t *testing.T) {
+	ctx := tenant.WithID(context.Background(), "acme")
+	front, rear := append(pngHeader, "front"...), append(pngHeader, "rear"...)
+
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	for _, data := range [][]byte{front, rear} {
+		require.NoError(t, blobs.Put(ctx, blobKey(data), bytes.NewReader(data)))
+	}
+
+	repo := new(mocks.CarRepository)
+	attachments := new(mocks.AttachmentRepository)
+	repo.On("GetByIDForUpdate", ctx, "1").Return(&domain.Car{ID: "1"}, nil)
+	repo.On("Delete", ctx, "1").Return(nil)
+	attachments.On("List", ctx, "1").Return([]domain.Attachment{
+		{ID: 1, Checksum: checksum(front)},
+		{ID: 2, Checksum: checksum(rear)},
+		{ID: 3, Checksum: checksum(front)},
+	}, nil)
+	attachments.On("LockContent", mock.Anything, checksum(front)).Return(0, nil).Once()
+	attachments.On("LockContent", mock.Anything, checksum(rear)).Return(1, nil).Once()
+
+	s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo, Attachments: attachments}, service.WithAttachmentContent(blobs))
+	require.NoError(t, s.Delete(ctx, "1"))
+
+	_, err = blobs.Open(ctx, blobKey(front))
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+	assert.Equal(t, rear, readBlob(t, blobs, blobKey(rear)), "a car of the same tenant still uses it")
+	attachments.AssertExpectations(t)
+}
@@ -2,8 +2,11 @@ package service_test
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
+	"github.com/kefir4iick/crud/internal/auth"
 	"github.com/kefir4iick/crud/internal/domain"
 	"github.com/kefir4iick/crud/internal/service"
 	"github.com/kefir4iick/crud/internal/service/mocks"
@@ -19,37 +22,37 @@ func TestCreateCar_Validation(t *testing.T) {
 	}{
 		{
 			name:    "Empty make",
-			input:   domain.Car{Make: "", Model: "Model", Year: 2020, Price: 10000},
+			input:   domain.Car{Make: "", Model: "Model", Year: 2020, Price: usd(10000)},
 			wantErr: "make is required",
 		},
 		{
 			name:    "Make too long",
-			input:   domain.Car{Make: string(make([]byte, 256)), Model: "Model", Year: 2020, Price: 10000},
+			input:   domain.Car{Make: string(make([]byte, 256)), Model: "Model", Year: 2020, Price: usd(10000)},
 			wantErr: "make must be less than 255 characters",
 		},
 		{
 			name:    "Empty model",
-			input:   domain.Car{Make: "Make", Model: "", Year: 2020, Price: 10000},
+			input:   domain.Car{Make: "Make", Model: "", Year: 2020, Price: usd(10000)},
 			wantErr: "model is required",
 		},
 		{
 			name:    "Year too old",
-			input:   domain.Car{Make: "Make", Model: "Model", Year: 1899, Price: 10000},
+			input:   domain.Car{Make: "Make", Model: "Model", Year: 1899, Price: usd(10000)},
 			wantErr: "year must be >= 1900",
 		},
 		{
 			name:    "Negative price",
-			input:   domain.Car{Make: "Make", Model: "Model", Year: 2020, Price: -1},
+			input:   domain.Car{Make: "Make", Model: "Model", Year: 2020, Price: usd(-1)},
 			wantErr: "price must be positive",
 		},
 		{
 			name:    "Zero price",
-			input:   domain.Car{Make: "Make", Model: "Model", Year: 2020, Price: 0},
+			input:   domain.Car{Make: "Make", Model: "Model", Year: 2020, Price: usd(0)},
 			wantErr: "price must be positive",
 		},
 		{
 			name:    "Valid input",
-			input:   domain.Car{Make: "Toyota", Model: "Camry", Year: 2020, Price: 25000},
+			input:   domain.Car{Make: "Toyota", Model: "Camry", Year: 2020, Price: usd(25000)},
 			wantErr: "",
 		},
 	}
@@ -57,7 +60,7 @@ func TestCreateCar_Validation(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := new(mocks.CarRepository)
-			s := service.NewCarService(repo)
+			s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo})
 
 			if tt.wantErr == "" {
 				repo.On("Create", mock.Anything, tt.input).Return(&tt.input, nil)
@@ -76,6 +79,67 @@ func TestCreateCar_Validation(t *testing.T) {
 	}
 }
 
+func TestCreateCar_VIN(t *testing.T) {
+	accord := domain.Car{Make: "Honda", Model: "Accord", Year: 2003, Price: usd(5000), VIN: "1HGCM82633A004352"}
+
+	tests := []struct {
+		name    string
+		opts    []service.Option
+		input   domain.Car
+		want    domain.Car
+		wantErr error
+	}{
+		{name: "Valid", input: accord, want: accord},
+		{
+			name:  "Lower case is normalized",
+			input: domain.Car{Make: "Honda", Model: "Accord", Year: 2003, Price: usd(5000), VIN: " 1hgcm82633a004352 "},
+			want:  accord,
+		},
+		{
+			name:    "Bad check digit",
+			input:   domain.Car{Make: "Honda", Model: "Accord", Year: 2003, Price: usd(5000), VIN: "1HGCM82643A004352"},
+			wantErr: domain.ErrInvalidVIN,
+		},
+		{
+			name:    "Make does not match",
+			input:   domain.Car{Make: "Toyota", Model: "Accord", Year: 2003, Price: usd(5000), VIN: accord.VIN},
+			wantErr: domain.ErrInvalidVIN,
+		},
+		{
+			name:    "Year does not match",
+			input:   domain.Car{Make: "Honda", Model: "Accord", Year: 2004, Price: usd(5000), VIN: accord.VIN},
+			wantErr: domain.ErrInvalidVIN,
+		},
+		{
+			name:  "Prefill",
+			opts:  []service.Option{service.WithVINPrefill()},
+			input: domain.Car{Model: "Accord", Price: usd(5000), VIN: accord.VIN},
+			want:  accord,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mocks.CarRepository)
+			s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo}, tt.opts...)
+
+			if tt.wantErr == nil {
+				repo.On("Create", mock.Anything, tt.want).Return(&tt.want, nil)
+			}
+
+			_, err := s.Create(context.Background(), tt.input)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				repo.AssertCalled(t, "Create", mock.Anything, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetCarByID(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -88,8 +152,8 @@ func TestGetCarByID(t *testing.T) {
 		{
 			name:    "Success",
 			id:      "1",
-			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: 25000},
-			wantCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: 25000},
+			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: usd(25000)},
+			wantCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: usd(25000)},
 		},
 		{
 			name:    "Empty ID",
@@ -112,7 +176,7 @@ func TestGetCarByID(t *testing.T) {
 				repo.On("GetByID", mock.Anything, tt.id).Return(tt.mockCar, tt.mockErr)
 			}
 
-			s := service.NewCarService(repo)
+			s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo})
 			car, err := s.GetByID(context.Background(), tt.id)
 
 			if tt.wantErr != "" {
@@ -145,7 +209,7 @@ func TestUpdateCar_Validation(t *testing.T) {
 			input: domain.UpdateCarInput{
 				Make: stringPtr(string(make([]byte, 256))),
 			},
-			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: 25000},
+			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: usd(25000)},
 			wantErr: "make must be less than 255 characters",
 		},
 		{
@@ -154,7 +218,7 @@ func TestUpdateCar_Validation(t *testing.T) {
 			input: domain.UpdateCarInput{
 				Make: stringPtr(""),
 			},
-			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: 25000},
+			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: usd(25000)},
 			wantErr: "make cannot be empty",
 		},
 		{
@@ -163,7 +227,7 @@ func TestUpdateCar_Validation(t *testing.T) {
 			input: domain.UpdateCarInput{
 				Model: stringPtr(""),
 			},
-			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: 25000},
+			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: usd(25000)},
 			wantErr: "model cannot be empty",
 		},
 		{
@@ -172,40 +236,40 @@ func TestUpdateCar_Validation(t *testing.T) {
 			input: domain.UpdateCarInput{
 				Year: intPtr(1899),
 			},
-			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: 25000},
+			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: usd(25000)},
 			wantErr: "year must be >= 1900",
 		},
 		{
 			name: "Negative price",
 			id:   "1",
 			input: domain.UpdateCarInput{
-				Price: intPtr(-1),
+				Price: usdPtr(-1),
 			},
-			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: 25000},
+			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: usd(25000)},
 			wantErr: "price must be positive",
 		},
 		{
 			name: "Zero price",
 			id:   "1",
 			input: domain.UpdateCarInput{
-				Price: intPtr(0),
+				Price: usdPtr(0),
 			},
-			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: 25000},
+			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: usd(25000)},
 			wantErr: "price must be positive",
 		},
 		{
 			name: "Valid partial update",
 			id:   "1",
 			input: domain.UpdateCarInput{
-				Price: intPtr(30000),
+				Price: usdPtr(30000),
 			},
-			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: 25000},
+			mockCar: &domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: usd(25000)},
 		},
 		{
 			name: "Car not found",
 			id:   "999",
 			input: domain.UpdateCarInput{
-				Price: intPtr(30000),
+				Price: usdPtr(30000),
 			},
 			mockCar: nil,
 			mockErr: domain.ErrCarNotFound,
@@ -215,7 +279,7 @@ func TestUpdateCar_Validation(t *testing.T) {
 			name: "Empty ID",
 			id:   "",
 			input: domain.UpdateCarInput{
-				Price: intPtr(30000),
+				Price: usdPtr(30000),
 			},
 			wantErr: "id is required",
 		},
@@ -227,9 +291,9 @@ func TestUpdateCar_Validation(t *testing.T) {
 			
 			if tt.id != "" {
 				if tt.mockErr != nil {
-					repo.On("GetByID", mock.Anything, tt.id).Return(nil, tt.mockErr)
+					repo.On("GetByIDForUpdate", mock.Anything, tt.id).Return(nil, tt.mockErr)
 				} else if tt.mockCar != nil {
-					repo.On("GetByID", mock.Anything, tt.id).Return(tt.mockCar, nil)
+					repo.On("GetByIDForUpdate", mock.Anything, tt.id).Return(tt.mockCar, nil)
 				}
 			}
 			
@@ -250,25 +314,369 @@ func TestUpdateCar_Validation(t *testing.T) {
 				repo.On("Update", mock.Anything, tt.id, updatedCar).Return(&updatedCar, nil)
 			}
 
-			s := service.NewCarService(repo)
+			prices := new(mocks.PriceHistoryRepository)
+			prices.On("Record", mock.Anything, tt.id, mock.Anything, mock.Anything).Return(nil)
+
+			s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo, Prices: prices})
 			_, err := s.Update(context.Background(), tt.id, tt.input)
 
 			if tt.wantErr != "" {
 				assert.ErrorContains(t, err, tt.wantErr)
 				if tt.id != "" && tt.wantErr != "id is required" {
-					repo.AssertCalled(t, "GetByID", mock.Anything, tt.id)
+					repo.AssertCalled(t, "GetByIDForUpdate", mock.Anything, tt.id)
 				}
 				repo.AssertNotCalled(t, "Update")
+				prices.AssertNotCalled(t, "Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 			} else {
 				assert.NoError(t, err)
 				if tt.id != "" {
-					repo.AssertCalled(t, "GetByID", mock.Anything, tt.id)
+					repo.AssertCalled(t, "GetByIDForUpdate", mock.Anything, tt.id)
 					repo.AssertCalled(t, "Update", mock.Anything, tt.id, mock.Anything)
+					prices.AssertCalled(t, "Record", mock.Anything, tt.id, usd(25000), *tt.input.Price)
 				}
 			}
 		})
 	}
 }
 
+func TestAuthorization(t *testing.T) {
+	viewer := &auth.Principal{Subject: "v", Roles: []string{"viewer"}}
+	editor := &auth.Principal{Subject: "e", Roles: []string{"editor"}}
+	admin := &auth.Principal{Subject: "a", Roles: []string{"admin"}}
+	deleter := &auth.Principal{Subject: "d", Scopes: []string{"cars:delete"}}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		call      func(s service.CarService, ctx context.Context) error
+		wantErr   error
+	}{
+		{
+			name:      "Anonymous read",
+			principal: nil,
+			call:      getCar,
+			wantErr:   domain.ErrUnauthenticated,
+		},
+		{
+			name:      "Viewer read",
+			principal: viewer,
+			call:      getCar,
+		},
+		{
+			name:      "Viewer delete",
+			principal: viewer,
+			call:      deleteCar,
+			wantErr:   domain.ErrForbidden,
+		},
+		{
+			name:      "Editor delete",
+			principal: editor,
+			call:      deleteCar,
+			wantErr:   domain.ErrForbidden,
+		},
+		{
+			name:      "Admin delete",
+			principal: admin,
+			call:      deleteCar,
+		},
+		{
+			name:      "Scope grants delete",
+			principal: deleter,
+			call:      deleteCar,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mocks.CarRepository)
+			repo.On("GetByID", mock.Anything, "1").Return(&domain.Car{ID: "1"}, nil)
+			repo.On("GetByIDForUpdate", mock.Anything, "1").Return(&domain.Car{ID: "1"}, nil)
+			repo.On("Delete", mock.Anything, "1").Return(nil)
+
+			s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo}, service.WithAuthorizer(auth.DefaultPolicy()))
+
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.WithPrincipal(ctx, tt.principal)
+			}
+
+			err := tt.call(s, ctx)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				repo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+				repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func getCar(s service.CarService, ctx context.Context) error {
+	_, err := s.GetByID(ctx, "1")
+	return err
+}
+
+func deleteCar(s service.CarService, ctx context.Context) error {
+	return s.Delete(ctx, "1")
+}
+
 func stringPtr(s string) *string { return &s }
 func intPtr(i int) *int         { return &i }
+
+func usd(amount int64) domain.Money {
+	return domain.Money{Amount: amount, Currency: "USD"}
+}
+
+func usdPtr(amount int64) *domain.Money {
+	m := usd(amount)
+	return &m
+}
+
+func TestGetAll_PriceComparison(t *testing.T) {
+	ctx := context.Background()
+	table := &domain.ConversionTable{Base: "USD", Rates: map[string]float64{"EUR": 1.08}}
+
+	repo := new(mocks.CarRepository)
+	s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo})
+
+	_, err := s.GetAll(ctx, domain.CarFilter{Sort: "-price"})
+	assert.ErrorIs(t, err, domain.ErrCurrencyMismatch)
+
+	_, err = s.GetAll(ctx, domain.CarFilter{Sort: "colour"})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	_, err = s.GetAll(ctx, domain.CarFilter{Currency: "XYZ", PriceMin: 100})
+	assert.ErrorIs(t, err, domain.ErrInvalidCurrency)
+
+	repo.On("GetAll", ctx, domain.CarFilter{Currency: "EUR", PriceMin: 100, Limit: 10}).Return([]domain.Car{}, nil).Once()
+	_, err = s.GetAll(ctx, domain.CarFilter{Currency: "eur", PriceMin: 100})
+	assert.NoError(t, err)
+
+	s = service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo}, service.WithConversionTable(table))
+	repo.On("GetAll", ctx, domain.CarFilter{Sort: "-price", Limit: 10, Conversion: table}).Return([]domain.Car{}, nil).Once()
+	_, err = s.GetAll(ctx, domain.CarFilter{Sort: "-price"})
+	assert.NoError(t, err)
+
+	repo.AssertExpectations(t)
+}
+
+func TestCreateCar_LegacyPrice(t *testing.T) {
+	repo := new(mocks.CarRepository)
+	s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo}, service.WithDefaultCurrency("EUR"))
+
+	// The old API priced cars in whole units.
+	want := domain.Car{Make: "Toyota", Model: "Camry", Year: 2020, Price: domain.Money{Amount: 2500000, Currency: "EUR"}}
+	repo.On("Create", mock.Anything, want).Return(&want, nil)
+
+	_, err := s.Create(context.Background(), domain.Car{Make: "Toyota", Model: "Camry", Year: 2020, Price: domain.Money{Amount: 25000}})
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestUpdateCar_PriceHistory(t *testing.T) {
+	ctx := context.Background()
+	existing := domain.Car{ID: "1", Make: "Toyota", Model: "Camry", Year: 2020, Price: usd(25000)}
+
+	t.Run("Unchanged price is not recorded", func(t *testing.T) {
+		repo := new(mocks.CarRepository)
+		prices := new(mocks.PriceHistoryRepository)
+		car := existing
+		repo.On("GetByIDForUpdate", ctx, "1").Return(&car, nil)
+		want := existing
+		want.Model = "Corolla"
+		repo.On("Update", ctx, "1", want).Return(&want, nil)
+
+		s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo, Prices: prices})
+		_, err := s.Update(ctx, "1", domain.UpdateCarInput{Model: stringPtr("Corolla"), Price: usdPtr(25000)})
+		assert.NoError(t, err)
+		prices.AssertNotCalled(t, "Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Bare amount keeps the currency and is in whole units", func(t *testing.T) {
+		repo := new(mocks.CarRepository)
+		prices := new(mocks.PriceHistoryRepository)
+		car := existing
+		car.Price = domain.Money{Amount: 25000, Currency: "EUR"}
+		repo.On("GetByIDForUpdate", ctx, "1").Return(&car, nil)
+		want := car
+		want.Price = domain.Money{Amount: 20000, Currency: "EUR"}
+		repo.On("Update", ctx, "1", want).Return(&want, nil)
+		prices.On("Record", ctx, "1", domain.Money{Amount: 25000, Currency: "EUR"}, want.Price).Return(nil)
+
+		s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo, Prices: prices})
+		_, err := s.Update(ctx, "1", domain.UpdateCarInput{Price: &domain.Money{Amount: 200}})
+		assert.NoError(t, err)
+		prices.AssertExpectations(t)
+	})
+
+	t.Run("Failed record aborts the update", func(t *testing.T) {
+		repo := new(mocks.CarRepository)
+		prices := new(mocks.PriceHistoryRepository)
+		car := existing
+		repo.On("GetByIDForUpdate", ctx, "1").Return(&car, nil)
+		prices.On("Record", ctx, "1", mock.Anything, mock.Anything).Return(errors.New("boom"))
+
+		s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo, Prices: prices})
+		_, err := s.Update(ctx, "1", domain.UpdateCarInput{Price: usdPtr(20000)})
+		assert.ErrorContains(t, err, "failed to record price change")
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetPriceHistory(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(mocks.CarRepository)
+	prices := new(mocks.PriceHistoryRepository)
+	changes := []domain.PriceChange{{OldPrice: usd(25000), NewPrice: usd(23000)}}
+	repo.On("GetByID", ctx, "1").Return(&domain.Car{ID: "1"}, nil)
+	repo.On("GetByID", ctx, "999").Return(nil, domain.ErrCarNotFound)
+	prices.On("List", ctx, "1").Return(changes, nil)
+
+	s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo, Prices: prices})
+
+	got, err := s.GetPriceHistory(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, changes, got)
+
+	_, err = s.GetPriceHistory(ctx, "999")
+	assert.ErrorIs(t, err, domain.ErrCarNotFound)
+	prices.AssertNotCalled(t, "List", ctx, "999")
+}
+
+func TestCreateCar_Attributes(t *testing.T) {
+	base := domain.Car{Make: "Tesla", Model: "Model 3", Year: 2022, Price: usd(40000)}
+	with := func(f func(c *domain.Car)) domain.Car {
+		c := base
+		f(&c)
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		input   domain.Car
+		want    domain.Car
+		wantErr string
+	}{
+		{
+			name: "Valid",
+			input: with(func(c *domain.Car) {
+				c.Mileage, c.Condition, c.FuelType, c.Transmission, c.BodyType, c.Doors = 12000, "Used", "ELECTRIC", "automatic", "sedan", 4
+				c.Color = " Pearl White "
+			}),
+			want: with(func(c *domain.Car) {
+				c.Mileage, c.Condition, c.FuelType, c.Transmission, c.BodyType, c.Doors = 12000, "used", "electric", "automatic", "sedan", 4
+				c.Color = "pearl white"
+			}),
+		},
+		{name: "Unknown condition", input: with(func(c *domain.Car) { c.Condition = "mint" }), wantErr: "unknown condition"},
+		{name: "Unknown fuel", input: with(func(c *domain.Car) { c.FuelType = "coal" }), wantErr: "unknown fuel_type"},
+		{name: "Unknown transmission", input: with(func(c *domain.Car) { c.Transmission = "sequential" }), wantErr: "unknown transmission"},
+		{name: "Unknown body type", input: with(func(c *domain.Car) { c.BodyType = "limo" }), wantErr: "unknown body_type"},
+		{name: "Negative mileage", input: with(func(c *domain.Car) { c.Mileage = -1 }), wantErr: "mileage must not be negative"},
+		{name: "Too many doors", input: with(func(c *domain.Car) { c.Doors = 10 }), wantErr: "doors must be between 1 and 9"},
+		{name: "Long color", input: with(func(c *domain.Car) { c.Color = strings.Repeat("a", 51) }), wantErr: "color must be at most 50 characters"},
+		{
+			name:    "Electric with displacement",
+			input:   with(func(c *domain.Car) { c.FuelType, c.EngineDisplacement = "electric", 1600 }),
+			wantErr: "electric cars have no engine_displacement",
+		},
+		{
+			name:    "New car with high mileage",
+			input:   with(func(c *domain.Car) { c.Condition, c.Mileage = "new", 30000 }),
+			wantErr: "a new car cannot have more than",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mocks.CarRepository)
+			s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo})
+
+			if tt.wantErr == "" {
+				repo.On("Create", mock.Anything, tt.want).Return(&tt.want, nil)
+			}
+
+			_, err := s.Create(context.Background(), tt.input)
+
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				repo.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestUpdateCar_Attributes(t *testing.T) {
+	ctx := context.Background()
+	existing := domain.Car{ID: "1", Make: "VW", Model: "Golf", Year: 2019, Price: usd(15000),
+		FuelType: domain.FuelPetrol, EngineDisplacement: 1400, Doors: 5}
+
+	repo := new(mocks.CarRepository)
+	s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo})
+
+	car := existing
+	repo.On("GetByIDForUpdate", ctx, "1").Return(&car, nil).Once()
+	fuel := domain.FuelElectric
+	_, err := s.Update(ctx, "1", domain.UpdateCarInput{FuelType: &fuel})
+	assert.ErrorContains(t, err, "electric cars have no engine_displacement")
+
+	car = existing
+	repo.On("GetByIDForUpdate", ctx, "1").Return(&car, nil).Once()
+	want := existing
+	want.FuelType, want.EngineDisplacement, want.Doors, want.Mileage, want.Color = domain.FuelElectric, 0, 0, 30000, "red"
+	repo.On("Update", ctx, "1", want).Return(&want, nil)
+	color := "Red"
+	_, err = s.Update(ctx, "1", domain.UpdateCarInput{FuelType: &fuel, EngineDisplacement: intPtr(0), Doors: intPtr(0), Mileage: intPtr(30000), Color: &color})
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestGetAll_AttributeFilters(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mocks.CarRepository)
+	s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo})
+
+	_, err := s.GetAll(ctx, domain.CarFilter{BodyType: "limo"})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	_, err = s.GetAll(ctx, domain.CarFilter{MileageMin: 100, MileageMax: 10})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	repo.On("GetAll", ctx, domain.CarFilter{BodyType: "suv", FuelType: "diesel", Sort: "mileage", Limit: 10}).Return([]domain.Car{}, nil)
+	_, err = s.GetAll(ctx, domain.CarFilter{BodyType: "SUV", FuelType: "Diesel", Sort: "mileage"})
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mocks.CarRepository)
+	dealers := new(mocks.DealerRepository)
+	s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo, Dealers: dealers})
+
+	_, err := s.Stats(ctx, domain.CarFilter{}, "fuel_type")
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	_, err = s.Stats(ctx, domain.CarFilter{PriceMin: 100}, "")
+	assert.ErrorIs(t, err, domain.ErrCurrencyMismatch)
+
+	// Sorting and paging make no difference to the stats, so they are not
+	// validated or passed on.
+	stats := &domain.CarStats{GroupBy: domain.StatsByMake, Groups: []domain.StatsGroup{{Key: "Toyota", Count: 2}}}
+	repo.On("Stats", ctx, domain.CarFilter{Make: "Toyota"}, domain.StatsByMake).Return(stats, nil).Once()
+	got, err := s.Stats(ctx, domain.CarFilter{Make: "Toyota", Sort: "colour", Limit: 5, Offset: 10}, "Make")
+	assert.NoError(t, err)
+	assert.Equal(t, stats, got)
+
+	dealers.On("GetByID", ctx, int64(99)).Return(nil, domain.ErrDealerNotFound)
+	_, err = s.Stats(ctx, domain.CarFilter{DealerID: 99}, "")
+	assert.ErrorIs(t, err, domain.ErrDealerNotFound)
+
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,174 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/service"
+	"github.com/kefir4iick/crud/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newCatalog knows Volkswagen, alias VW, with the Golf built from 1974 and
+// nothing else.
+func newCatalog() *mocks.CatalogRepository {
+	vw := &domain.Make{ID: "volkswagen", Name: "Volkswagen", Aliases: []string{"VW"}}
+	golf := &domain.Model{ID: "golf", MakeID: "volkswagen", Name: "Golf", YearFrom: 1974}
+
+	catalog := new(mocks.CatalogRepository)
+	for _, name := range []string{"Volkswagen", "volkswagen", "VW", "vw"} {
+		catalog.On("FindMake", mock.Anything, name).Return(vw, nil)
+	}
+	catalog.On("FindMake", mock.Anything, mock.Anything).Return(nil, domain.ErrMakeNotFound)
+	catalog.On("FindModel", mock.Anything, "volkswagen", "golf").Return(golf, nil)
+	catalog.On("FindModel", mock.Anything, "volkswagen", "Golf").Return(golf, nil)
+	catalog.On("FindModel", mock.Anything, mock.Anything, mock.Anything).Return(nil, domain.ErrModelNotFound)
+	return catalog
+}
+
+func TestCreateCar_Catalog(t *testing.T) {
+	golf := domain.Car{Make: "Volkswagen", Model: "Golf", Year: 2015, Price: usd(12000)}
+
+	tests := []struct {
+		name    string
+		strict  bool
+		input   domain.Car
+		want    domain.Car
+		wantErr error
+	}{
+		{
+			name:  "Alias and case are normalized",
+			input: domain.Car{Make: " VW ", Model: "golf", Year: 2015, Price: usd(12000)},
+			want:  golf,
+		},
+		{
+			name:    "Year before production",
+			input:   domain.Car{Make: "VW", Model: "Golf", Year: 1970, Price: usd(12000)},
+			wantErr: domain.ErrModelYearMismatch,
+		},
+		{
+			name:  "Unknown make passes in lenient mode",
+			input: domain.Car{Make: "Lada ", Model: "Niva", Year: 2015, Price: usd(12000)},
+			want:  domain.Car{Make: "Lada", Model: "Niva", Year: 2015, Price: usd(12000)},
+		},
+		{
+			name:  "Unknown model keeps the catalog make",
+			input: domain.Car{Make: "vw", Model: "Polo", Year: 2015, Price: usd(12000)},
+			want:  domain.Car{Make: "Volkswagen", Model: "Polo", Year: 2015, Price: usd(12000)},
+		},
+		{
+			name:    "Unknown make is rejected in strict mode",
+			strict:  true,
+			input:   domain.Car{Make: "Lada", Model: "Niva", Year: 2015, Price: usd(12000)},
+			wantErr: domain.ErrUnknownMakeModel,
+		},
+		{
+			name:    "Unknown model is rejected in strict mode",
+			strict:  true,
+			input:   domain.Car{Make: "VW", Model: "Polo", Year: 2015, Price: usd(12000)},
+			wantErr: domain.ErrUnknownMakeModel,
+		},
+		{
+			name:   "Known pair passes in strict mode",
+			strict: true,
+			input:  domain.Car{Make: "volkswagen", Model: "golf", Year: 2015, Price: usd(12000)},
+			want:   golf,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mocks.CarRepository)
+			s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo}, service.WithCatalog(newCatalog(), tt.strict))
+
+			if tt.wantErr == nil {
+				repo.On("Create", mock.Anything, tt.want).Return(&tt.want, nil)
+			}
+
+			_, err := s.Create(context.Background(), tt.input)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				repo.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestUpdateCar_Catalog(t *testing.T) {
+	ctx := context.Background()
+	legacy := domain.Car{ID: "1", Make: "vw", Model: "golf", Year: 1960, Price: usd(5000)}
+
+	repo := new(mocks.CarRepository)
+	prices := new(mocks.PriceHistoryRepository)
+	prices.On("Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo, Prices: prices}, service.WithCatalog(newCatalog(), true))
+
+	// A price change leaves the legacy names alone.
+	car := legacy
+	repo.On("GetByIDForUpdate", ctx, "1").Return(&car, nil).Once()
+	want := legacy
+	want.Price = usd(4000)
+	repo.On("Update", ctx, "1", want).Return(&want, nil).Once()
+	_, err := s.Update(ctx, "1", domain.UpdateCarInput{Price: usdPtr(4000)})
+	assert.NoError(t, err)
+
+	// Changing the year matches the car against the catalog.
+	car = legacy
+	repo.On("GetByIDForUpdate", ctx, "1").Return(&car, nil).Once()
+	_, err = s.Update(ctx, "1", domain.UpdateCarInput{Year: intPtr(1970)})
+	assert.ErrorIs(t, err, domain.ErrModelYearMismatch)
+
+	car = legacy
+	repo.On("GetByIDForUpdate", ctx, "1").Return(&car, nil).Once()
+	want = legacy
+	want.Make, want.Model, want.Year = "Volkswagen", "Golf", 1980
+	repo.On("Update", ctx, "1", want).Return(&want, nil).Once()
+	_, err = s.Update(ctx, "1", domain.UpdateCarInput{Year: intPtr(1980)})
+	assert.NoError(t, err)
+
+	repo.AssertExpectations(t)
+}
+
+func TestCatalogService_CreateMake(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mocks.CatalogRepository)
+	s := service.NewCatalogService(repo, nil, nil)
+
+	want := domain.Make{ID: "mercedes-benz", Name: "Mercedes-Benz", Aliases: []string{"Benz", "Merc"}}
+	repo.On("CreateMake", ctx, want).Return(&want, nil)
+
+	_, err := s.CreateMake(ctx, domain.Make{Name: " Mercedes-Benz ", Aliases: []string{"Merc", "mercedes benz", "", "Benz", "merc"}})
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+
+	_, err = s.CreateMake(ctx, domain.Make{Name: " "})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	_, err = s.CreateMake(ctx, domain.Make{ID: "Bad ID", Name: "Lada"})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
+
+func TestCatalogService_Models(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mocks.CatalogRepository)
+	s := service.NewCatalogService(repo, nil, nil)
+
+	_, err := s.CreateModel(ctx, "volkswagen", domain.Model{Name: "Golf", YearFrom: 2020, YearTo: 2010})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	want := domain.Model{ID: "id-4", MakeID: "volkswagen", Name: "ID.4", Aliases: []string{}, YearFrom: 2020}
+	repo.On("CreateModel", ctx, want).Return(&want, nil)
+	_, err = s.CreateModel(ctx, "volkswagen", domain.Model{Name: "ID.4", YearFrom: 2020})
+	assert.NoError(t, err)
+
+	repo.On("GetMake", ctx, "lada").Return(nil, domain.ErrMakeNotFound)
+	_, err = s.ListModels(ctx, "lada")
+	assert.ErrorIs(t, err, domain.ErrMakeNotFound)
+	repo.AssertNotCalled(t, "ListModels", ctx, "lada")
+}
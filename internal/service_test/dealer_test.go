@@ -0,0 +1,140 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/service"
+	"github.com/kefir4iick/crud/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDealerService_Create(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		input   domain.Dealer
+		want    domain.Dealer
+		wantErr error
+	}{
+		{
+			name:  "Trimmed",
+			input: domain.Dealer{Name: " North Lot ", Address: " 1 Main St\n", Location: &domain.Coordinates{Latitude: 52.5, Longitude: 13.4}},
+			want:  domain.Dealer{Name: "North Lot", Address: "1 Main St", Location: &domain.Coordinates{Latitude: 52.5, Longitude: 13.4}},
+		},
+		{
+			name:    "Name required",
+			input:   domain.Dealer{Name: "  "},
+			wantErr: domain.ErrInvalidInput,
+		},
+		{
+			name:    "Latitude out of range",
+			input:   domain.Dealer{Name: "North Lot", Location: &domain.Coordinates{Latitude: 95, Longitude: 13.4}},
+			wantErr: domain.ErrInvalidInput,
+		},
+		{
+			name:    "Longitude out of range",
+			input:   domain.Dealer{Name: "North Lot", Location: &domain.Coordinates{Latitude: 52.5, Longitude: -181}},
+			wantErr: domain.ErrInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mocks.DealerRepository)
+			if tt.wantErr == nil {
+				created := tt.want
+				created.ID = 1
+				repo.On("Create", ctx, tt.want).Return(&created, nil)
+			}
+
+			s := service.NewDealerService(repo, nil, nil)
+			got, err := s.Create(ctx, tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), got.ID)
+		})
+	}
+}
+
+func TestDealerService_Delete(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mocks.DealerRepository)
+	repo.On("Delete", ctx, int64(1)).Return(domain.ErrDealerHasInventory)
+
+	s := service.NewDealerService(repo, nil, nil)
+	assert.ErrorIs(t, s.Delete(ctx, 1), domain.ErrDealerHasInventory)
+	assert.ErrorIs(t, s.Delete(ctx, 0), domain.ErrInvalidInput)
+	repo.AssertNumberOfCalls(t, "Delete", 1)
+}
+
+func TestTransferCar(t *testing.T) {
+	ctx := context.Background()
+
+	newService := func() (service.CarService, *mocks.CarRepository) {
+		repo := new(mocks.CarRepository)
+		dealers := new(mocks.DealerRepository)
+		repo.On("GetByIDForUpdate", ctx, "1").Return(&domain.Car{ID: "1", Make: "Toyota", DealerID: 1}, nil)
+		repo.On("GetByIDForUpdate", ctx, "999").Return(nil, domain.ErrCarNotFound)
+		dealers.On("GetByID", ctx, int64(1)).Return(&domain.Dealer{ID: 1}, nil)
+		dealers.On("GetByID", ctx, int64(2)).Return(&domain.Dealer{ID: 2}, nil)
+		dealers.On("GetByID", ctx, mock.Anything).Return(nil, domain.ErrDealerNotFound)
+		repo.On("Update", ctx, "1", mock.Anything).Return(&domain.Car{ID: "1", Make: "Toyota", DealerID: 2}, nil)
+		return service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo, Dealers: dealers}), repo
+	}
+
+	t.Run("Moves the car", func(t *testing.T) {
+		s, repo := newService()
+		car, err := s.Transfer(ctx, "1", 2)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), car.DealerID)
+		repo.AssertCalled(t, "Update", ctx, "1", domain.Car{ID: "1", Make: "Toyota", DealerID: 2})
+	})
+
+	tests := []struct {
+		name     string
+		id       string
+		dealerID int64
+		wantErr  error
+	}{
+		{name: "Same dealer", id: "1", dealerID: 1, wantErr: domain.ErrSameDealer},
+		{name: "Unknown dealer", id: "1", dealerID: 3, wantErr: domain.ErrDealerNotFound},
+		{name: "Invalid dealer", id: "1", dealerID: 0, wantErr: domain.ErrInvalidInput},
+		{name: "Unknown car", id: "999", dealerID: 2, wantErr: domain.ErrCarNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo := newService()
+			_, err := s.Transfer(ctx, tt.id, tt.dealerID)
+			assert.ErrorIs(t, err, tt.wantErr)
+			repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGetAll_ByDealer(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mocks.CarRepository)
+	dealers := new(mocks.DealerRepository)
+	dealers.On("GetByID", ctx, int64(1)).Return(&domain.Dealer{ID: 1}, nil)
+	dealers.On("GetByID", ctx, int64(2)).Return(nil, domain.ErrDealerNotFound)
+	repo.On("GetAll", ctx, mock.MatchedBy(func(f domain.CarFilter) bool { return f.DealerID == 1 })).
+		Return([]domain.Car{{ID: "1", DealerID: 1}}, nil)
+
+	s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo, Dealers: dealers})
+
+	cars, err := s.GetAll(ctx, domain.CarFilter{DealerID: 1})
+	require.NoError(t, err)
+	assert.Len(t, cars, 1)
+
+	_, err = s.GetAll(ctx, domain.CarFilter{DealerID: 2})
+	assert.ErrorIs(t, err, domain.ErrDealerNotFound, "an unknown dealer is not an empty one")
+}
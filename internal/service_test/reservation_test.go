@@ -0,0 +1,307 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/service"
+	"github.com/kefir4iick/crud/internal/service/mocks"
+	"github.com/kefir4iick/crud/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newReservationService returns a service over one car, "1", in status.
+func newReservationService(ctx context.Context, status domain.CarStatus) (service.ReservationService, *mocks.CarRepository, *mocks.ReservationRepository, *mocks.StatusHistoryRepository) {
+	repo := new(mocks.CarRepository)
+	reservations := new(mocks.ReservationRepository)
+	statuses := new(mocks.StatusHistoryRepository)
+	repo.On("GetByIDForUpdate", ctx, "1").Return(&domain.Car{ID: "1", Make: "Toyota", Status: status}, nil)
+	repo.On("GetByIDForUpdate", ctx, "999").Return(nil, domain.ErrCarNotFound)
+	repo.On("Update", ctx, "1", mock.Anything).Return(&domain.Car{ID: "1", Make: "Toyota"}, nil)
+	statuses.On("Record", ctx, "1", mock.Anything).Return(nil)
+	uow := &mocks.UnitOfWork{Cars: repo, Statuses: statuses, Reservations: reservations}
+	return service.NewReservationService(uow, nil, nil, nil), repo, reservations, statuses
+}
+
+func TestCreateReservation(t *testing.T) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	t.Run("Reserves the car", func(t *testing.T) {
+		s, repo, reservations, statuses := newReservationService(ctx, domain.StatusAvailable)
+		want := domain.Reservation{CarID: "1", Holder: "Jane Doe", Note: "test drive first", Status: domain.ReservationActive, ExpiresAt: expiresAt}
+		created := want
+		created.ID = 7
+		reservations.On("Create", ctx, want).Return(&created, nil)
+
+		got, err := s.Create(ctx, "1", domain.Reservation{Holder: " Jane Doe ", Note: "test drive first\n", ExpiresAt: expiresAt})
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), got.ID)
+		statuses.AssertCalled(t, "Record", ctx, "1",
+			domain.StatusChange{From: domain.StatusAvailable, To: domain.StatusReserved, Reason: "reservation 7"})
+		repo.AssertCalled(t, "Update", ctx, "1", domain.Car{ID: "1", Make: "Toyota", Status: domain.StatusReserved})
+	})
+
+	t.Run("Default expiry", func(t *testing.T) {
+		s, _, reservations, _ := newReservationService(ctx, domain.StatusAvailable)
+		reservations.On("Create", ctx, mock.Anything).Return(&domain.Reservation{ID: 7}, nil)
+
+		_, err := s.Create(ctx, "1", domain.Reservation{Holder: "Jane Doe"})
+		require.NoError(t, err)
+		input := reservations.Calls[0].Arguments.Get(1).(domain.Reservation)
+		assert.WithinDuration(t, time.Now().Add(service.DefaultReservationPeriod), input.ExpiresAt, time.Minute)
+	})
+
+	t.Run("Car not available", func(t *testing.T) {
+		s, repo, reservations, _ := newReservationService(ctx, domain.StatusWithdrawn)
+		reservations.On("Create", ctx, mock.Anything).Return(&domain.Reservation{ID: 7}, nil)
+
+		_, err := s.Create(ctx, "1", domain.Reservation{Holder: "Jane Doe", ExpiresAt: expiresAt})
+		assert.ErrorIs(t, err, domain.ErrIllegalTransition)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already reserved", func(t *testing.T) {
+		s, repo, reservations, _ := newReservationService(ctx, domain.StatusAvailable)
+		reservations.On("Create", ctx, mock.Anything).Return(nil, domain.ErrCarReserved)
+
+		_, err := s.Create(ctx, "1", domain.Reservation{Holder: "Jane Doe", ExpiresAt: expiresAt})
+		assert.ErrorIs(t, err, domain.ErrCarReserved)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	tests := []struct {
+		name    string
+		carID   string
+		input   domain.Reservation
+		wantErr error
+	}{
+		{name: "Holder required", carID: "1", input: domain.Reservation{Holder: "  ", ExpiresAt: expiresAt}, wantErr: domain.ErrInvalidInput},
+		{name: "Expiry in the past", carID: "1", input: domain.Reservation{Holder: "Jane Doe", ExpiresAt: time.Now().Add(-time.Minute)}, wantErr: domain.ErrInvalidInput},
+		{name: "Expiry too far ahead", carID: "1", input: domain.Reservation{Holder: "Jane Doe", ExpiresAt: time.Now().Add(service.MaxReservationPeriod + time.Hour)}, wantErr: domain.ErrInvalidInput},
+		{name: "Unknown car", carID: "999", input: domain.Reservation{Holder: "Jane Doe", ExpiresAt: expiresAt}, wantErr: domain.ErrCarNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, reservations, _ := newReservationService(ctx, domain.StatusAvailable)
+			_, err := s.Create(ctx, tt.carID, tt.input)
+			assert.ErrorIs(t, err, tt.wantErr)
+			reservations.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestExtendReservation(t *testing.T) {
+	ctx := context.Background()
+	active := domain.Reservation{ID: 7, CarID: "1", Holder: "Jane Doe", Status: domain.ReservationActive, ExpiresAt: time.Now().Add(time.Hour)}
+	later := time.Now().Add(24 * time.Hour)
+
+	t.Run("Moves the expiry", func(t *testing.T) {
+		s, _, reservations, _ := newReservationService(ctx, domain.StatusReserved)
+		reservations.On("Get", ctx, "1", int64(7)).Return(&active, nil)
+		extended := active
+		extended.ExpiresAt = later
+		reservations.On("Update", ctx, extended).Return(&extended, nil)
+
+		got, err := s.Extend(ctx, "1", 7, later)
+		require.NoError(t, err)
+		assert.Equal(t, later, got.ExpiresAt)
+	})
+
+	lapsed := active
+	lapsed.ExpiresAt = time.Now().Add(-time.Minute)
+	cancelled := active
+	cancelled.Status = domain.ReservationCancelled
+
+	tests := []struct {
+		name        string
+		reservation *domain.Reservation
+		expiresAt   time.Time
+		wantErr     error
+	}{
+		{name: "Earlier expiry", reservation: &active, expiresAt: active.ExpiresAt.Add(-time.Minute), wantErr: domain.ErrInvalidInput},
+		{name: "Lapsed", reservation: &lapsed, expiresAt: later, wantErr: domain.ErrReservationNotActive},
+		{name: "Cancelled", reservation: &cancelled, expiresAt: later, wantErr: domain.ErrReservationNotActive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, reservations, _ := newReservationService(ctx, domain.StatusReserved)
+			reservations.On("Get", ctx, "1", int64(7)).Return(tt.reservation, nil)
+
+			_, err := s.Extend(ctx, "1", 7, tt.expiresAt)
+			assert.ErrorIs(t, err, tt.wantErr)
+			reservations.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCancelReservation(t *testing.T) {
+	ctx := context.Background()
+	active := domain.Reservation{ID: 7, CarID: "1", Holder: "Jane Doe", Status: domain.ReservationActive, ExpiresAt: time.Now().Add(time.Hour)}
+
+	s, repo, reservations, statuses := newReservationService(ctx, domain.StatusReserved)
+	reservations.On("Get", ctx, "1", int64(7)).Return(&active, nil)
+	cancelled := active
+	cancelled.Status = domain.ReservationCancelled
+	reservations.On("Update", ctx, cancelled).Return(&cancelled, nil)
+
+	got, err := s.Cancel(ctx, "1", 7, "")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ReservationCancelled, got.Status)
+	statuses.AssertCalled(t, "Record", ctx, "1",
+		domain.StatusChange{From: domain.StatusReserved, To: domain.StatusAvailable, Reason: "reservation 7 cancelled"})
+	repo.AssertCalled(t, "Update", ctx, "1", domain.Car{ID: "1", Make: "Toyota", Status: domain.StatusAvailable})
+}
+
+func TestTransition_EndsReservation(t *testing.T) {
+	ctx := context.Background()
+	active := domain.Reservation{ID: 7, CarID: "1", Status: domain.ReservationActive}
+
+	tests := []struct {
+		name       string
+		transition domain.Transition
+		to         domain.CarStatus
+		want       domain.ReservationStatus
+	}{
+		{name: "Sold", transition: domain.TransitionSell, to: domain.StatusSold, want: domain.ReservationCompleted},
+		{name: "Released", transition: domain.TransitionRelease, to: domain.StatusAvailable, want: domain.ReservationCancelled},
+		{name: "Withdrawn", transition: domain.TransitionWithdraw, to: domain.StatusWithdrawn, want: domain.ReservationCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mocks.CarRepository)
+			statuses := new(mocks.StatusHistoryRepository)
+			reservations := new(mocks.ReservationRepository)
+			repo.On("GetByIDForUpdate", ctx, "1").Return(&domain.Car{ID: "1", Status: domain.StatusReserved}, nil)
+			repo.On("Update", ctx, "1", domain.Car{ID: "1", Status: tt.to}).Return(&domain.Car{ID: "1", Status: tt.to}, nil)
+			statuses.On("Record", ctx, "1", mock.Anything).Return(nil)
+			reservations.On("GetActive", ctx, "1").Return(&active, nil)
+			ended := active
+			ended.Status = tt.want
+			reservations.On("Update", ctx, ended).Return(&ended, nil)
+
+			s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo, Statuses: statuses, Reservations: reservations})
+			_, err := s.Transition(ctx, "1", tt.transition, "")
+			require.NoError(t, err)
+			reservations.AssertCalled(t, "Update", ctx, ended)
+		})
+	}
+}
+
+func TestExpireDue(t *testing.T) {
+	ctx := context.Background()
+	t1 := tenant.WithID(ctx, "t1")
+	t2 := tenant.WithID(ctx, "t2")
+
+	repo := new(mocks.CarRepository)
+	statuses := new(mocks.StatusHistoryRepository)
+	reservations := new(mocks.ReservationRepository)
+	expired := new(mocks.ExpiredReservationRepository)
+	expired.On("ListExpired", ctx, int64(0), mock.Anything).Return([]domain.ExpiredReservation{
+		{TenantID: "t1", CarID: "1", ID: 7},
+		{TenantID: "t1", CarID: "2", ID: 8},
+		{TenantID: "t2", CarID: "3", ID: 9},
+	}, nil)
+
+	// Reservation 7 has expired, 8 was extended after it was listed and the
+	// car of 9 cannot be locked.
+	lapsed := domain.Reservation{ID: 7, CarID: "1", Status: domain.ReservationActive, ExpiresAt: time.Now().Add(-time.Minute)}
+	extended := domain.Reservation{ID: 8, CarID: "2", Status: domain.ReservationActive, ExpiresAt: time.Now().Add(time.Hour)}
+	repo.On("GetByIDForUpdate", t1, "1").Return(&domain.Car{ID: "1", Status: domain.StatusReserved}, nil)
+	repo.On("GetByIDForUpdate", t1, "2").Return(&domain.Car{ID: "2", Status: domain.StatusReserved}, nil)
+	repo.On("GetByIDForUpdate", t2, "3").Return(nil, errors.New("connection reset"))
+	repo.On("Update", t1, "1", mock.Anything).Return(&domain.Car{ID: "1", Status: domain.StatusAvailable}, nil)
+	statuses.On("Record", t1, "1", mock.Anything).Return(nil)
+	reservations.On("Get", t1, "1", int64(7)).Return(&lapsed, nil)
+	reservations.On("Get", t1, "2", int64(8)).Return(&extended, nil)
+	ended := lapsed
+	ended.Status = domain.ReservationExpired
+	reservations.On("Update", t1, ended).Return(&ended, nil)
+
+	uow := &mocks.UnitOfWork{Cars: repo, Statuses: statuses, Reservations: reservations}
+	s := service.NewReservationService(uow, expired, nil, nil)
+
+	n, err := s.ExpireDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	statuses.AssertCalled(t, "Record", t1, "1",
+		domain.StatusChange{From: domain.StatusReserved, To: domain.StatusAvailable, Reason: "reservation 7 expired"})
+	reservations.AssertNumberOfCalls(t, "Update", 1)
+	repo.AssertNotCalled(t, "Update", t1, "2", mock.Anything)
+}
+
+func TestExpireDue_PagesPastFailures(t *testing.T) {
+	ctx := context.Background()
+	t1 := tenant.WithID(ctx, "t1")
+
+	// A full batch of reservations whose cars cannot be locked must not keep
+	// the sweeper from the reservations listed after them.
+	var failing []domain.ExpiredReservation
+	for i := 1; i <= 100; i++ {
+		failing = append(failing, domain.ExpiredReservation{TenantID: "t1", CarID: "broken", ID: int64(i)})
+	}
+	expired := new(mocks.ExpiredReservationRepository)
+	expired.On("ListExpired", ctx, int64(0), mock.Anything).Return(failing, nil)
+	expired.On("ListExpired", ctx, int64(100), mock.Anything).Return([]domain.ExpiredReservation{
+		{TenantID: "t1", CarID: "1", ID: 101},
+	}, nil)
+
+	repo := new(mocks.CarRepository)
+	statuses := new(mocks.StatusHistoryRepository)
+	reservations := new(mocks.ReservationRepository)
+	lapsed := domain.Reservation{ID: 101, CarID: "1", Status: domain.ReservationActive, ExpiresAt: time.Now().Add(-time.Minute)}
+	repo.On("GetByIDForUpdate", t1, "broken").Return(nil, errors.New("connection reset"))
+	repo.On("GetByIDForUpdate", t1, "1").Return(&domain.Car{ID: "1", Status: domain.StatusReserved}, nil)
+	repo.On("Update", t1, "1", mock.Anything).Return(&domain.Car{ID: "1", Status: domain.StatusAvailable}, nil)
+	statuses.On("Record", t1, "1", mock.Anything).Return(nil)
+	reservations.On("Get", t1, "1", int64(101)).Return(&lapsed, nil)
+	reservations.On("Update", t1, mock.Anything).Return(&lapsed, nil)
+
+	uow := &mocks.UnitOfWork{Cars: repo, Statuses: statuses, Reservations: reservations}
+	s := service.NewReservationService(uow, expired, nil, nil)
+
+	n, err := s.ExpireDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	expired.AssertNumberOfCalls(t, "ListExpired", 2)
+}
+
+func TestCreateReservation_LapsedHold(t *testing.T) {
+	ctx := context.Background()
+
+	// The sweeper has not yet ended reservation 7, which lapsed a minute ago.
+	until := time.Now().Add(-time.Minute)
+	lapsed := domain.Reservation{ID: 7, CarID: "1", Holder: "John Roe", Status: domain.ReservationActive, ExpiresAt: until}
+	ended := lapsed
+	ended.Status = domain.ReservationExpired
+
+	repo := new(mocks.CarRepository)
+	statuses := new(mocks.StatusHistoryRepository)
+	reservations := new(mocks.ReservationRepository)
+	repo.On("GetByIDForUpdate", ctx, "1").Return(&domain.Car{ID: "1", Status: domain.StatusReserved, ReservedUntil: &until}, nil)
+	repo.On("Update", ctx, "1", domain.Car{ID: "1", Status: domain.StatusAvailable, ReservedUntil: &until}).
+		Return(&domain.Car{ID: "1", Status: domain.StatusAvailable}, nil)
+	repo.On("Update", ctx, "1", domain.Car{ID: "1", Status: domain.StatusReserved}).
+		Return(&domain.Car{ID: "1", Status: domain.StatusReserved}, nil)
+	statuses.On("Record", ctx, "1", mock.Anything).Return(nil)
+	reservations.On("GetActive", ctx, "1").Return(&lapsed, nil)
+	reservations.On("Update", ctx, ended).Return(&ended, nil)
+	reservations.On("Create", ctx, mock.Anything).Return(&domain.Reservation{ID: 8, CarID: "1", Holder: "Jane Doe", Status: domain.ReservationActive}, nil)
+
+	uow := &mocks.UnitOfWork{Cars: repo, Statuses: statuses, Reservations: reservations}
+	s := service.NewReservationService(uow, new(mocks.ExpiredReservationRepository), nil, nil)
+
+	reservation, err := s.Create(ctx, "1", domain.Reservation{Holder: "Jane Doe"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), reservation.ID)
+	reservations.AssertCalled(t, "Update", ctx, ended)
+	statuses.AssertCalled(t, "Record", ctx, "1",
+		domain.StatusChange{From: domain.StatusReserved, To: domain.StatusAvailable, Reason: "reservation 7 expired"})
+	statuses.AssertCalled(t, "Record", ctx, "1",
+		domain.StatusChange{From: domain.StatusAvailable, To: domain.StatusReserved, Reason: "reservation 8"})
+}
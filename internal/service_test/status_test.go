@@ -0,0 +1,141 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kefir4iick/crud/internal/domain"
+	"github.com/kefir4iick/crud/internal/service"
+	"github.com/kefir4iick/crud/internal/service/mocks"
+	"github.com/kefir4iick/crud/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransition(t *testing.T) {
+	ctx := context.Background()
+
+	newService := func(status domain.CarStatus) (service.CarService, *mocks.CarRepository, *mocks.StatusHistoryRepository) {
+		repo := new(mocks.CarRepository)
+		statuses := new(mocks.StatusHistoryRepository)
+		repo.On("GetByIDForUpdate", ctx, "1").Return(&domain.Car{ID: "1", Make: "Toyota", Status: status}, nil)
+		repo.On("GetByIDForUpdate", ctx, "999").Return(nil, domain.ErrCarNotFound)
+		repo.On("Update", ctx, "1", mock.Anything).Return(&domain.Car{ID: "1", Make: "Toyota", Status: domain.StatusWithdrawn}, nil)
+		statuses.On("Record", ctx, "1", mock.Anything).Return(nil)
+		return service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo, Statuses: statuses}), repo, statuses
+	}
+
+	t.Run("Withdraws the car", func(t *testing.T) {
+		s, repo, statuses := newService(domain.StatusAvailable)
+		car, err := s.Transition(ctx, "1", domain.TransitionWithdraw, "  needs repairs \n")
+		require.NoError(t, err)
+		assert.Equal(t, domain.StatusWithdrawn, car.Status)
+		statuses.AssertCalled(t, "Record", ctx, "1",
+			domain.StatusChange{From: domain.StatusAvailable, To: domain.StatusWithdrawn, Reason: "needs repairs"})
+		repo.AssertCalled(t, "Update", ctx, "1", domain.Car{ID: "1", Make: "Toyota", Status: domain.StatusWithdrawn})
+	})
+
+	tests := []struct {
+		name       string
+		id         string
+		status     domain.CarStatus
+		transition domain.Transition
+		reason     string
+		wantErr    error
+	}{
+		{name: "Reserve without a reservation", id: "1", status: domain.StatusAvailable, transition: domain.TransitionReserve, wantErr: domain.ErrInvalidInput},
+		{name: "Release available", id: "1", status: domain.StatusAvailable, transition: domain.TransitionRelease, wantErr: domain.ErrIllegalTransition},
+		{name: "Withdraw sold", id: "1", status: domain.StatusSold, transition: domain.TransitionWithdraw, wantErr: domain.ErrIllegalTransition},
+		{name: "Unknown transition", id: "1", status: domain.StatusAvailable, transition: "scrap", wantErr: domain.ErrInvalidInput},
+		{name: "Reason too long", id: "1", status: domain.StatusAvailable, transition: domain.TransitionSell, reason: strings.Repeat("x", 501), wantErr: domain.ErrInvalidInput},
+		{name: "Unknown car", id: "999", transition: domain.TransitionSell, wantErr: domain.ErrCarNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo, statuses := newService(tt.status)
+			_, err := s.Transition(ctx, tt.id, tt.transition, tt.reason)
+			assert.ErrorIs(t, err, tt.wantErr)
+			statuses.AssertNotCalled(t, "Record", mock.Anything, mock.Anything, mock.Anything)
+			repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestSoldCarIsImmutable(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mocks.CarRepository)
+	repo.On("GetByIDForUpdate", ctx, "1").Return(&domain.Car{ID: "1", Make: "Toyota", Status: domain.StatusSold}, nil)
+	s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo, Dealers: new(mocks.DealerRepository)})
+
+	mileage := 1000
+	_, err := s.Update(ctx, "1", domain.UpdateCarInput{Mileage: &mileage})
+	assert.ErrorIs(t, err, domain.ErrCarSold)
+
+	_, err = s.Transfer(ctx, "1", 2)
+	assert.ErrorIs(t, err, domain.ErrCarSold)
+
+	assert.ErrorIs(t, s.Delete(ctx, "1"), domain.ErrCarSold, "deleting would take its history")
+
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestSoldCarAttachmentsAreImmutable(t *testing.T) {
+	ctx := tenant.WithID(context.Background(), "acme")
+	s, _, attachments, _ := newAttachmentService(t)
+
+	_, err := s.Upload(ctx, "2", "photo.png", bytes.NewReader(append(pngHeader, "photo"...)))
+	assert.ErrorIs(t, err, domain.ErrCarSold)
+
+	assert.ErrorIs(t, s.Delete(ctx, "2", 7), domain.ErrCarSold)
+
+	attachments.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	attachments.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreate_Status(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mocks.CarRepository)
+	s := service.NewCarService(repo, nil)
+
+	_, err := s.Create(ctx, domain.Car{Make: "Toyota", Model: "Camry", Year: 2020, Price: domain.Money{Amount: 100, Currency: "USD"}, Status: domain.StatusSold})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput, "new cars cannot skip the state machine")
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestGetAll_ByStatus(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mocks.CarRepository)
+	repo.On("GetAll", ctx, mock.MatchedBy(func(f domain.CarFilter) bool { return f.Status == domain.StatusReserved })).
+		Return([]domain.Car{{ID: "1", Status: domain.StatusReserved}}, nil)
+	s := service.NewCarService(repo, nil)
+
+	cars, err := s.GetAll(ctx, domain.CarFilter{Status: "Reserved"})
+	require.NoError(t, err)
+	assert.Len(t, cars, 1)
+
+	_, err = s.GetAll(ctx, domain.CarFilter{Status: "lost"})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
+
+func TestGetStatusHistory(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mocks.CarRepository)
+	statuses := new(mocks.StatusHistoryRepository)
+	history := []domain.StatusChange{{From: domain.StatusAvailable, To: domain.StatusReserved, Reason: "deposit paid"}}
+	repo.On("GetByID", ctx, "1").Return(&domain.Car{ID: "1"}, nil)
+	repo.On("GetByID", ctx, "999").Return(nil, domain.ErrCarNotFound)
+	statuses.On("List", ctx, "1").Return(history, nil)
+	s := service.NewCarService(repo, &mocks.UnitOfWork{Cars: repo, Statuses: statuses})
+
+	got, err := s.GetStatusHistory(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, history, got)
+
+	_, err = s.GetStatusHistory(ctx, "999")
+	assert.ErrorIs(t, err, domain.ErrCarNotFound)
+	statuses.AssertNumberOfCalls(t, "List", 1)
+}
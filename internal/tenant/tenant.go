@@ -0,0 +1,29 @@
+// Package tenant carries the dealership a request acts for. Repositories read
+// it from the context to scope every query.
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+const maxIDLength = 64
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type ctxKey struct{}
+
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxKey{}).(string)
+	return id, ok && id != ""
+}
+
+// ValidID reports whether id can be used as a tenant ID: 1-64 letters,
+// digits, dashes or underscores.
+func ValidID(id string) bool {
+	return len(id) <= maxIDLength && idPattern.MatchString(id)
+}
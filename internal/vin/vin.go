@@ -0,0 +1,119 @@
+// Package vin validates and decodes vehicle identification numbers
+// (ISO 3779). Decoding is offline: the manufacturer comes from a bundled WMI
+// table and the model year from the tenth character.
+package vin
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const Length = 17
+
+var (
+	ErrLength    = errors.New("must be 17 characters long")
+	ErrCharacter = errors.New("contains a character that is not allowed")
+	ErrCheck     = errors.New("check digit does not match")
+)
+
+// Normalize upper-cases vin and strips surrounding space.
+func Normalize(vin string) string {
+	return strings.ToUpper(strings.TrimSpace(vin))
+}
+
+// Validate checks the length, the alphabet (digits and capital letters except
+// I, O and Q) and the check digit in position 9.
+func Validate(vin string) error {
+	if len(vin) != Length {
+		return ErrLength
+	}
+
+	sum := 0
+	for i := 0; i < Length; i++ {
+		v, ok := transliterate(vin[i])
+		if !ok {
+			return fmt.Errorf("%w: %q at position %d", ErrCharacter, vin[i], i+1)
+		}
+		sum += v * weights[i]
+	}
+
+	want := byte('0' + sum%11)
+	if sum%11 == 10 {
+		want = 'X'
+	}
+	if vin[8] != want {
+		return fmt.Errorf("%w: got %q, want %q", ErrCheck, vin[8], want)
+	}
+	return nil
+}
+
+var weights = [Length]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+func transliterate(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'A' && c <= 'H':
+		return int(c-'A') + 1, true
+	case c >= 'J' && c <= 'N':
+		return int(c-'J') + 1, true
+	case c == 'P':
+		return 7, true
+	case c == 'R':
+		return 9, true
+	case c >= 'S' && c <= 'Z':
+		return int(c-'S') + 2, true
+	}
+	return 0, false
+}
+
+// Info is what can be read from a valid VIN without an external service.
+type Info struct {
+	WMI string
+	// Make is empty when the WMI is not in the bundled table.
+	Make string
+	// ModelYears lists the years the model-year character can stand for,
+	// most likely first. The code repeats every 30 years; for passenger cars
+	// a letter in position 7 means the later cycle.
+	ModelYears []int
+}
+
+// Decode reads the manufacturer and model year from vin, which must be valid.
+func Decode(vin string) Info {
+	info := Info{WMI: vin[:3], Make: wmiMakes[vin[:3]]}
+
+	if i := strings.IndexByte(yearCodes, vin[9]); i >= 0 {
+		older, newer := 1980+i, 2010+i
+		if vin[6] >= 'A' && vin[6] <= 'Z' {
+			info.ModelYears = []int{newer, older}
+		} else {
+			info.ModelYears = []int{older, newer}
+		}
+	}
+	return info
+}
+
+const yearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+//go:embed wmi.csv
+var wmiTable string
+
+var wmiMakes = parseWMITable(wmiTable)
+
+func parseWMITable(table string) map[string]string {
+	makes := make(map[string]string)
+	for _, line := range strings.Split(table, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		wmi, carMake, ok := strings.Cut(line, ",")
+		if !ok {
+			panic("vin: malformed WMI table line: " + line)
+		}
+		makes[wmi] = carMake
+	}
+	return makes
+}
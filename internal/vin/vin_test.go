@@ -0,0 +1,46 @@
+package vin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		vin string
+		err error
+	}{
+		{vin: "1HGCM82633A004352"},
+		{vin: "1M8GDM9AXKP042788"},
+		{vin: "11111111111111111"},
+		{vin: "1HGCM82643A004352", err: ErrCheck},
+		{vin: "1HGCM82633A00435", err: ErrLength},
+		{vin: "1HGCM8263IA004352", err: ErrCharacter},
+		{vin: "1hgcm82633a004352", err: ErrCharacter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vin, func(t *testing.T) {
+			err := Validate(tt.vin)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	info := Decode("1HGCM82633A004352")
+	assert.Equal(t, "1HG", info.WMI)
+	assert.Equal(t, "Honda", info.Make)
+	assert.Equal(t, []int{2003, 2033}, info.ModelYears)
+
+	info = Decode("5YJ3E1EA5LF000316")
+	assert.Equal(t, "Tesla", info.Make)
+	assert.Equal(t, []int{2020, 1990}, info.ModelYears)
+
+	assert.Empty(t, Decode("11111111111111111").Make)
+}